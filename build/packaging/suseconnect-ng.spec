@@ -119,6 +119,7 @@ go build -v -ldflags "-s -w" -mod=vendor -buildmode=pie -o bin/zypper-migration
 go build -v -ldflags "-s -w" -mod=vendor -buildmode=pie -o bin/zypper-search-packages %{project}/cmd/zypper-search-packages
 go build -v -ldflags "-s -w" -mod=vendor -buildmode=pie -o bin/suse-uptime-tracker %{project}/cmd/suse-uptime-tracker
 go build -v -ldflags "-s -w" -mod=vendor -buildmode=pie -o bin/suseconnect-mcp %{project}/cmd/suseconnect-mcp
+go build -v -ldflags "-s -w" -mod=vendor -buildmode=pie -o bin/issue-offline-certificate %{project}/cmd/issue-offline-certificate
 
 # the library
 mkdir -p %_builddir/go/lib
@@ -130,6 +131,7 @@ install -D -m 0755 bin/suseconnect %{buildroot}/%{_bindir}/suseconnect
 ln -s %{_bindir}/suseconnect %{buildroot}/%{_bindir}/SUSEConnect
 install -D -m 0755 bin/suse-uptime-tracker %{buildroot}/%{_bindir}/suse-uptime-tracker
 install -D -m 0755 bin/suseconnect-mcp %{buildroot}/%{_bindir}/suseconnect-mcp
+install -D -m 0755 bin/issue-offline-certificate %{buildroot}/%{_bindir}/issue-offline-certificate
 
 install -d -m 0755 %{buildroot}/%{_sbindir}
 ln -s %{_bindir}/suseconnect %{buildroot}/%{_sbindir}/SUSEConnect
@@ -149,19 +151,24 @@ install -D -m 644 docs/SUSEConnect.8 %{buildroot}/%{_mandir}/man8/SUSEConnect.8
 install -D -m 644 docs/zypper-migration.8 %{buildroot}/%{_mandir}/man8/zypper-migration.8
 install -D -m 644 docs/zypper-search-packages.8 %{buildroot}/%{_mandir}/man8/zypper-search-packages.8
 
-# Install the SUSEConnect --keepalive timer and service.
+# Install the SUSEConnect --keepalive timer and services.
 install -D -m 644 build/packaging/suseconnect-keepalive.timer %{buildroot}/%{_unitdir}/suseconnect-keepalive.timer
 install -D -m 644 build/packaging/suseconnect-keepalive.service %{buildroot}/%{_unitdir}/suseconnect-keepalive.service
+install -D -m 644 build/packaging/suseconnect-keepalived.service %{buildroot}/%{_unitdir}/suseconnect-keepalived.service
 install -D -m 644 build/packaging/suse-uptime-tracker.timer %{buildroot}/%{_unitdir}/suse-uptime-tracker.timer
 install -D -m 644 build/packaging/suse-uptime-tracker.service %{buildroot}/%{_unitdir}/suse-uptime-tracker.service
+install -D -m 644 build/packaging/suseconnect-metrics.timer %{buildroot}/%{_unitdir}/suseconnect-metrics.timer
+install -D -m 644 build/packaging/suseconnect-metrics.service %{buildroot}/%{_unitdir}/suseconnect-metrics.service
 ln -sf service %{buildroot}/%{_sbindir}/rcsuseconnect-keepalive
+ln -sf service %{buildroot}/%{_sbindir}/rcsuseconnect-keepalived
 ln -sf service %{buildroot}/%{_sbindir}/rcsuse-uptime-tracker
+ln -sf service %{buildroot}/%{_sbindir}/rcsuseconnect-metrics
 
 # we currently do not ship the source for any go module
 rm -rf %{buildroot}/usr/share/go
 
 %pre
-%service_add_pre suseconnect-keepalive.service suseconnect-keepalive.timer suse-uptime-tracker.service suse-uptime-tracker.timer
+%service_add_pre suseconnect-keepalive.service suseconnect-keepalive.timer suseconnect-keepalived.service suse-uptime-tracker.service suse-uptime-tracker.timer suseconnect-metrics.service suseconnect-metrics.timer
 
 # in pre blocks the old version is still installed. This way we can detect
 # if --keepalive was already present before
@@ -216,13 +223,13 @@ rm -f /run/suseconnect/*
     sed -i '/RandomizedDelaySec*/d' %{_unitdir}/suseconnect-keepalive.timer
     sed -i "s/OnCalendar=daily/OnCalendar=*-*-* $TIMER_HOUR:$TIMER_MINUTE:00/" %{_unitdir}/suseconnect-keepalive.timer
 %endif
-%service_add_post suseconnect-keepalive.service suseconnect-keepalive.timer suse-uptime-tracker.service suse-uptime-tracker.timer
+%service_add_post suseconnect-keepalive.service suseconnect-keepalive.timer suseconnect-keepalived.service suse-uptime-tracker.service suse-uptime-tracker.timer suseconnect-metrics.service suseconnect-metrics.timer
 
 %preun
-%service_del_preun suseconnect-keepalive.service suseconnect-keepalive.timer suse-uptime-tracker.service suse-uptime-tracker.timer
+%service_del_preun suseconnect-keepalive.service suseconnect-keepalive.timer suseconnect-keepalived.service suse-uptime-tracker.service suse-uptime-tracker.timer suseconnect-metrics.service suseconnect-metrics.timer
 
 %postun
-%service_del_postun suseconnect-keepalive.service suseconnect-keepalive.timer suse-uptime-tracker.service suse-uptime-tracker.timer
+%service_del_postun suseconnect-keepalive.service suseconnect-keepalive.timer suseconnect-keepalived.service suse-uptime-tracker.service suse-uptime-tracker.timer suseconnect-metrics.service suseconnect-metrics.timer
 
 %posttrans
 if [ -e /run/suseconnect-keepalive.timer.is-enabled ]; then
@@ -239,17 +246,23 @@ fi
 %doc README.md
 %{_bindir}/suseconnect
 %{_bindir}/suse-uptime-tracker
+%{_bindir}/issue-offline-certificate
 %{_bindir}/SUSEConnect
 %{_sbindir}/SUSEConnect
 %{_sbindir}/rcsuseconnect-keepalive
+%{_sbindir}/rcsuseconnect-keepalived
 %{_sbindir}/rcsuse-uptime-tracker
+%{_sbindir}/rcsuseconnect-metrics
 /usr/lib/zypper/commands
 %{_mandir}/man8/*
 %{_mandir}/man5/*
 %{_unitdir}/suseconnect-keepalive.service
 %{_unitdir}/suseconnect-keepalive.timer
+%{_unitdir}/suseconnect-keepalived.service
 %{_unitdir}/suse-uptime-tracker.service
 %{_unitdir}/suse-uptime-tracker.timer
+%{_unitdir}/suseconnect-metrics.service
+%{_unitdir}/suseconnect-metrics.timer
 
 %files -n libsuseconnect
 %license LICENSE
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+func main() {
+	fmt.Fprintln(os.Stderr, "issue-offline-certificate: Issue an offline registration certificate for an offline registration request")
+
+	if len(os.Args) != 5 {
+		fmt.Fprintln(os.Stderr, "./issue-offline-certificate <request-file> <regcode-file> <subscription-json-file> <private-key-pem-file>")
+		fmt.Fprintln(os.Stderr, "Use - as regcode file to read the regcode from stdin.")
+		os.Exit(1)
+	}
+
+	requestPath := os.Args[1]
+	regcodePath := os.Args[2]
+	subscriptionPath := os.Args[3]
+	keyPath := os.Args[4]
+
+	// the regcode is not passed as argument to keep it out of ps and the
+	// shell history
+	regcode, regcodeErr := readRegcode(regcodePath)
+	if regcodeErr != nil {
+		fmt.Fprintf(os.Stderr, "Reading %s failed: %s\n", regcodePath, regcodeErr)
+		os.Exit(1)
+	}
+
+	hdl, openErr := os.Open(requestPath)
+	if openErr != nil {
+		fmt.Fprintf(os.Stderr, "Reading %s failed: %s\n", requestPath, openErr)
+		os.Exit(1)
+	}
+	defer hdl.Close()
+
+	request, readErr := registration.OfflineRequestFrom(bufio.NewReader(hdl), true)
+	if readErr != nil {
+		fmt.Fprintf(os.Stderr, "request error: %s\n", readErr)
+		os.Exit(1)
+	}
+
+	data, readErr := os.ReadFile(subscriptionPath)
+	if readErr != nil {
+		fmt.Fprintf(os.Stderr, "Reading %s failed: %s\n", subscriptionPath, readErr)
+		os.Exit(1)
+	}
+	subscription := registration.SubscriptionInfo{}
+	if err := json.Unmarshal(data, &subscription); err != nil {
+		fmt.Fprintf(os.Stderr, "subscription error: %s\n", err)
+		os.Exit(1)
+	}
+
+	key, readErr := os.ReadFile(keyPath)
+	if readErr != nil {
+		fmt.Fprintf(os.Stderr, "Reading %s failed: %s\n", keyPath, readErr)
+		os.Exit(1)
+	}
+	signer, signerErr := registration.NewOfflineSignerFromPEM(key)
+	if signerErr != nil {
+		fmt.Fprintf(os.Stderr, "key error: %s\n", signerErr)
+		os.Exit(1)
+	}
+
+	cert, signErr := signer.Sign(request, regcode, subscription)
+	if signErr != nil {
+		fmt.Fprintf(os.Stderr, "sign error: %s\n", signErr)
+		os.Exit(1)
+	}
+
+	blob, encodeErr := cert.Base64Encoded()
+	if encodeErr != nil {
+		fmt.Fprintf(os.Stderr, "encode error: %s\n", encodeErr)
+		os.Exit(1)
+	}
+	io.Copy(os.Stdout, blob)
+	fmt.Println()
+
+	fmt.Fprintf(os.Stderr, "product: %s/%s/%s\n", request.Product.Identifier, request.Product.Version, request.Product.Arch)
+	fmt.Fprintf(os.Stderr, "subscription: %s\n", subscription.Name)
+	fmt.Fprintf(os.Stderr, "expires: %s\n", subscription.ExpiresAt)
+	fmt.Fprintf(os.Stderr, "key id: %s\n", signer.KeyID())
+}
+
+func readRegcode(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	regcode := strings.TrimSpace(string(data))
+	if regcode == "" {
+		return "", fmt.Errorf("no regcode given")
+	}
+	return regcode, nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	wtmpFilePath = "/var/log/wtmp"
+
+	// Layout of the utmp records of glibc, see utmp(5).
+	utmpRecordSize = 384
+	utmpTypeOffset = 0
+	utmpUserOffset = 44
+	utmpUserSize   = 32
+	utmpTimeOffset = 340
+	utmpRunLevel   = 1
+	utmpBootTime   = 2
+
+	// Timestamps of `journalctl --list-boots --utc`, without the weekday
+	// and the time zone.
+	journalTimeLayout = "2006-01-02 15:04:05"
+)
+
+var (
+	// test method overwrites
+	localJournalBoots = journalBoots
+	localReadWtmp     = func() ([]byte, error) { return os.ReadFile(wtmpFilePath) }
+)
+
+// uptimePeriod is a period during which the system was running.
+type uptimePeriod struct {
+	start time.Time
+	end   time.Time
+}
+
+// journalBoots returns the boots known by journald. The plain text output is
+// used since `--output=json` needs systemd 251 or newer.
+func journalBoots() ([]byte, error) {
+	return exec.Command("journalctl", "--list-boots", "--utc", "--no-pager", "--quiet").Output()
+}
+
+// journalUptimePeriods parses the output of `journalctl --list-boots --utc`,
+// either "IDX BOOT_ID FIRST—LAST" (systemd < 251) or the table with the same
+// columns of newer versions. Each boot lasts from its first to its last
+// journal entry, the current one (index 0) until now.
+func journalUptimePeriods(data []byte, now time.Time) ([]uptimePeriod, error) {
+	periods := []uptimePeriod{}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(strings.ReplaceAll(line, "—", " "))
+		if len(fields) == 0 {
+			continue
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			// Table header.
+			continue
+		}
+		// Index, boot ID and two "Www YYYY-MM-DD HH:MM:SS UTC" timestamps.
+		if len(fields) != 10 {
+			return nil, fmt.Errorf("invalid journal boot list: %q", line)
+		}
+		start, err := time.Parse(journalTimeLayout, strings.Join(fields[3:5], " "))
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal boot list: %v", err)
+		}
+		end, err := time.Parse(journalTimeLayout, strings.Join(fields[7:9], " "))
+		if err != nil {
+			return nil, fmt.Errorf("invalid journal boot list: %v", err)
+		}
+		if index == 0 {
+			end = now
+		}
+		periods = append(periods, uptimePeriod{start: start, end: end})
+	}
+	if len(periods) == 0 {
+		return nil, errors.New("invalid journal boot list: no boots found")
+	}
+	return periods, nil
+}
+
+// wtmpUptimePeriods parses the boot and shutdown records of a wtmp file.
+// A boot which is not followed by a shutdown (e.g. a crash) ends with the
+// last record before the next boot, the last one until now.
+func wtmpUptimePeriods(data []byte, now time.Time) ([]uptimePeriod, error) {
+	if len(data)%utmpRecordSize != 0 {
+		return nil, errors.New("wtmp file is corrupted: truncated record")
+	}
+
+	periods := []uptimePeriod{}
+	var bootAt, lastSeen time.Time
+	up := false
+	for offset := 0; offset < len(data); offset += utmpRecordSize {
+		record := data[offset : offset+utmpRecordSize]
+		kind := int16(binary.NativeEndian.Uint16(record[utmpTypeOffset:]))
+		user := string(bytes.TrimRight(record[utmpUserOffset:utmpUserOffset+utmpUserSize], "\x00"))
+		sec := int32(binary.NativeEndian.Uint32(record[utmpTimeOffset:]))
+		usec := int32(binary.NativeEndian.Uint32(record[utmpTimeOffset+4:]))
+		at := time.Unix(int64(sec), int64(usec)*1000)
+
+		switch {
+		case kind == utmpBootTime:
+			if up {
+				periods = append(periods, uptimePeriod{start: bootAt, end: lastSeen})
+			}
+			up = true
+			bootAt = at
+		case kind == utmpRunLevel && user == "shutdown":
+			if up {
+				periods = append(periods, uptimePeriod{start: bootAt, end: at})
+			}
+			up = false
+		}
+		lastSeen = at
+	}
+	if up {
+		periods = append(periods, uptimePeriod{start: bootAt, end: now})
+	}
+	return periods, nil
+}
+
+// bootHistory returns the periods during which the system was running
+// according to journald and wtmp. Sources which are not available are
+// skipped.
+func bootHistory(now time.Time) []uptimePeriod {
+	periods := []uptimePeriod{}
+
+	if data, err := localJournalBoots(); err != nil {
+		slog.Debug("Could not list the boots of the journal, skipping it", "error", err)
+	} else if journal, err := journalUptimePeriods(data, now); err != nil {
+		slog.Debug("Could not parse the boots of the journal, skipping it", "error", err)
+	} else {
+		periods = append(periods, journal...)
+	}
+
+	if data, err := localReadWtmp(); err != nil {
+		slog.Debug("Could not read wtmp, skipping it", "path", wtmpFilePath, "error", err)
+	} else if wtmp, err := wtmpUptimePeriods(data, now); err != nil {
+		slog.Debug("Could not parse wtmp, skipping it", "path", wtmpFilePath, "error", err)
+	} else {
+		periods = append(periods, wtmp...)
+	}
+
+	sort.Slice(periods, func(i, j int) bool { return periods[i].start.Before(periods[j].start) })
+	return periods
+}
+
+// backfillUptimeLog marks the hours covered by the given periods between
+// from and to as uptime. Hours which are already marked are kept.
+func backfillUptimeLog(uptimeLogs map[string]string, periods []uptimePeriod, from, to time.Time) map[string]string {
+	for _, period := range periods {
+		start, end := period.start, period.end
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		// NOTE: we are standardizing timezone to UTC
+		for hour := start.UTC().Truncate(time.Hour); hour.Before(end); hour = hour.Add(time.Hour) {
+			day := hour.Format(dateStringFormat)
+			if _, ok := uptimeLogs[day]; !ok {
+				uptimeLogs[day] = initUptimeHours
+			}
+			uptimeHoursMap := []rune(uptimeLogs[day])
+			uptimeHoursMap[hour.Hour()] = '1'
+			uptimeLogs[day] = string(uptimeHoursMap)
+		}
+	}
+	return uptimeLogs
+}
+
+// rebuildUptimeLog regenerates the days between from and to (included) of
+// the uptime log from the boot history.
+func rebuildUptimeLog(uptimeLogs map[string]string, periods []uptimePeriod, from, to time.Time) map[string]string {
+	end := to.AddDate(0, 0, 1)
+	for day := from; day.Before(end); day = day.AddDate(0, 0, 1) {
+		delete(uptimeLogs, day.Format(dateStringFormat))
+	}
+	return backfillUptimeLog(uptimeLogs, periods, from, end)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var historyNow = time.Date(2025, 6, 3, 3, 30, 0, 0, time.UTC)
+
+func date(value string) time.Time {
+	t, _ := time.Parse("2006-01-02 15:04", value)
+	return t
+}
+
+// wtmpFixture returns wtmp records in the byte order of the host, as written
+// by glibc: two boots ended by a shutdown and by a crash, and the current one.
+func wtmpFixture() []byte {
+	records := []struct {
+		kind int16
+		user string
+		at   string
+	}{
+		{utmpBootTime, "reboot", "2025-06-01 08:10"},
+		{utmpRunLevel, "runlevel", "2025-06-01 08:11"},
+		{7, "root", "2025-06-01 09:00"},
+		{8, "", "2025-06-01 09:30"},
+		{utmpRunLevel, "shutdown", "2025-06-01 11:20"},
+		{utmpBootTime, "reboot", "2025-06-02 22:30"},
+		{utmpRunLevel, "runlevel", "2025-06-02 22:31"},
+		{7, "root", "2025-06-02 23:40"},
+		{utmpBootTime, "reboot", "2025-06-03 01:05"},
+		{utmpRunLevel, "runlevel", "2025-06-03 01:06"},
+	}
+	data := make([]byte, 0, len(records)*utmpRecordSize)
+	for _, r := range records {
+		record := make([]byte, utmpRecordSize)
+		binary.NativeEndian.PutUint16(record[utmpTypeOffset:], uint16(r.kind))
+		copy(record[utmpUserOffset:utmpUserOffset+utmpUserSize], r.user)
+		binary.NativeEndian.PutUint32(record[utmpTimeOffset:], uint32(date(r.at).Unix()))
+		data = append(data, record...)
+	}
+	return data
+}
+
+func mockBootHistory(t *testing.T, journal, wtmp []byte) {
+	origJournal, origWtmp := localJournalBoots, localReadWtmp
+	t.Cleanup(func() { localJournalBoots, localReadWtmp = origJournal, origWtmp })
+
+	localJournalBoots = func() ([]byte, error) {
+		if journal == nil {
+			return nil, errors.New("journalctl: command not found")
+		}
+		return journal, nil
+	}
+	localReadWtmp = func() ([]byte, error) {
+		if wtmp == nil {
+			return nil, errors.New("open /var/log/wtmp: no such file or directory")
+		}
+		return wtmp, nil
+	}
+}
+
+func TestJournalUptimePeriods(t *testing.T) {
+	assert := assert.New(t)
+
+	// systemd < 251 and the table of newer versions.
+	for _, fixture := range []string{"journal-boots.txt", "journal-boots-table.txt"} {
+		periods, err := journalUptimePeriods(testutil.Fixture(t, "cmd/suse-uptime-tracker/"+fixture), historyNow)
+		assert.NoError(err)
+		assert.Len(periods, 3)
+		assert.True(periods[0].start.Equal(date("2025-05-31 12:00")))
+		assert.True(periods[0].end.Equal(date("2025-05-31 12:59")))
+		// The current boot lasts until now.
+		assert.True(periods[2].end.Equal(historyNow))
+	}
+
+	_, err := journalUptimePeriods([]byte("-1 8c2d4e6f0a1b3c5d7e9f1a2b3c4d5e6f n/a"), historyNow)
+	assert.Error(err)
+
+	_, err = journalUptimePeriods([]byte("No journal boot entry found"), historyNow)
+	assert.Error(err)
+}
+
+func TestWtmpUptimePeriods(t *testing.T) {
+	assert := assert.New(t)
+
+	periods, err := wtmpUptimePeriods(wtmpFixture(), historyNow)
+	assert.NoError(err)
+	assert.Len(periods, 3)
+
+	// Clean shutdown.
+	assert.True(periods[0].start.Equal(date("2025-06-01 08:10")))
+	assert.True(periods[0].end.Equal(date("2025-06-01 11:20")))
+	// Crash, the last record is a login.
+	assert.True(periods[1].start.Equal(date("2025-06-02 22:30")))
+	assert.True(periods[1].end.Equal(date("2025-06-02 23:40")))
+	// Current boot.
+	assert.True(periods[2].start.Equal(date("2025-06-03 01:05")))
+	assert.True(periods[2].end.Equal(historyNow))
+
+	_, err = wtmpUptimePeriods(make([]byte, utmpRecordSize+1), historyNow)
+	assert.Error(err)
+}
+
+func TestBackfillUptimeLog(t *testing.T) {
+	assert := assert.New(t)
+
+	mockBootHistory(t, testutil.Fixture(t, "cmd/suse-uptime-tracker/journal-boots.txt"), wtmpFixture())
+	uptimeLogs := map[string]string{
+		"2025-06-01": "100000000000000000000000",
+	}
+
+	uptimeLogs = backfillUptimeLog(uptimeLogs, bootHistory(historyNow), historyNow.AddDate(0, 0, -daysBeforePurge), historyNow)
+	assert.Equal(map[string]string{
+		"2025-05-31": "000000000000100000000000",
+		"2025-06-01": "100000001111000000000000",
+		"2025-06-02": "000000000000000000000011",
+		"2025-06-03": "011100000000000000000000",
+	}, uptimeLogs)
+}
+
+func TestBackfillUptimeLogWithoutHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	mockBootHistory(t, nil, nil)
+	uptimeLogs := map[string]string{"2025-06-01": "100000000000000000000000"}
+
+	uptimeLogs = backfillUptimeLog(uptimeLogs, bootHistory(historyNow), historyNow.AddDate(0, 0, -daysBeforePurge), historyNow)
+	assert.Equal(map[string]string{"2025-06-01": "100000000000000000000000"}, uptimeLogs)
+}
+
+func TestRebuildUptimeLog(t *testing.T) {
+	assert := assert.New(t)
+
+	mockBootHistory(t, nil, wtmpFixture())
+	uptimeLogs := map[string]string{
+		"2025-05-30": "111111111111111111111111",
+		"2025-06-01": "111111111111111111111111",
+		"2025-06-02": "111111111111111111111111",
+	}
+
+	uptimeLogs = rebuildUptimeLog(uptimeLogs, bootHistory(historyNow), date("2025-06-01 00:00"), date("2025-06-02 00:00"))
+	assert.Equal(map[string]string{
+		// Outside of the rebuilt range.
+		"2025-05-30": "111111111111111111111111",
+		"2025-06-01": "000000001111000000000000",
+		"2025-06-02": "000000000000000000000011",
+	}, uptimeLogs)
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/connect"
+	"github.com/SUSE/connect-ng/internal/uptime"
+)
+
+var (
+	// test method overwrites
+	localNow                 = time.Now
+	localReadSUSEConnectConf = func() (*connect.Options, error) { return connect.ReadFromConfiguration(connect.DefaultConfigPath) }
+)
+
+// reportPeriod is the uptime aggregated over a day, a week or a month.
+type reportPeriod struct {
+	Period string `json:"period"`
+	// Hours during which the system was running.
+	UptimeHours int `json:"uptime_hours"`
+	// Hours of the period within the reported range, up to now.
+	Hours    int     `json:"hours"`
+	Coverage float64 `json:"coverage"`
+}
+
+// reportGap is a range of hours without uptime.
+type reportGap struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Hours int       `json:"hours"`
+}
+
+// uptimeReport is printed by `suse-uptime-tracker report`.
+type uptimeReport struct {
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	By          string         `json:"by"`
+	UptimeHours int            `json:"uptime_hours"`
+	Hours       int            `json:"hours"`
+	Periods     []reportPeriod `json:"periods"`
+	Gaps        []reportGap    `json:"gaps"`
+
+	// What `SUSEConnect --keepalive` submits as online_at, which is the
+	// whole log if uptime tracking is enabled in its configuration.
+	UptimeTrackingEnabled bool     `json:"uptime_tracking_enabled"`
+	OnlineAt              []string `json:"online_at"`
+}
+
+// runReport implements `suse-uptime-tracker report`.
+func runReport(args []string, out io.Writer) error {
+	var (
+		from   string
+		to     string
+		by     string
+		format string
+	)
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Print(uptimeTrackerUsageText)
+	}
+	flags.StringVar(&from, "from", "", "")
+	flags.StringVar(&to, "to", "", "")
+	flags.StringVar(&by, "by", "day", "")
+	flags.StringVar(&format, "format", "table", "")
+	flags.Parse(args)
+
+	log, err := uptime.Read(uptimeCheckLogsFilePath)
+	if err != nil {
+		return err
+	}
+
+	now := localNow().UTC()
+	today := now.Truncate(24 * time.Hour)
+	start, end := today.AddDate(0, 0, -daysBeforePurge), today
+	if from != "" {
+		if start, err = time.Parse(dateStringFormat, from); err != nil {
+			return err
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse(dateStringFormat, to); err != nil {
+			return err
+		}
+	}
+	if end.Before(start) {
+		return errors.New("--to has to be after --from")
+	}
+
+	report, err := buildReport(log, start, end, by, now)
+	if err != nil {
+		return err
+	}
+	report.UptimeTrackingEnabled = uptimeTrackingEnabled()
+	if report.UptimeTrackingEnabled {
+		report.OnlineAt = log.Lines()
+	}
+
+	switch format {
+	case "table":
+		return printReportTable(out, report)
+	case "csv":
+		return printReportCSV(out, report)
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return fmt.Errorf("unknown report format %q, use one of: table, csv or json", format)
+}
+
+// buildReport aggregates the uptime of the days between from and to
+// (included) by day, week or month. Hours after now are left out.
+func buildReport(log uptime.Log, from, to time.Time, by string, now time.Time) (*uptimeReport, error) {
+	var periodOf func(time.Time) string
+	switch by {
+	case "day":
+		periodOf = func(t time.Time) string { return t.Format(dateStringFormat) }
+	case "week":
+		periodOf = func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}
+	case "month":
+		periodOf = func(t time.Time) string { return t.Format("2006-01") }
+	default:
+		return nil, fmt.Errorf("unknown report period %q, use one of: day, week or month", by)
+	}
+
+	report := &uptimeReport{
+		From:     from.Format(dateStringFormat),
+		To:       to.Format(dateStringFormat),
+		By:       by,
+		Periods:  []reportPeriod{},
+		Gaps:     []reportGap{},
+		OnlineAt: []string{},
+	}
+
+	end := to.AddDate(0, 0, 1)
+	var gap *reportGap
+	for hour := from; hour.Before(end) && !hour.After(now); hour = hour.Add(time.Hour) {
+		period := periodOf(hour)
+		if n := len(report.Periods); n == 0 || report.Periods[n-1].Period != period {
+			report.Periods = append(report.Periods, reportPeriod{Period: period})
+		}
+		current := &report.Periods[len(report.Periods)-1]
+		current.Hours++
+		report.Hours++
+
+		hours, ok := log[hour.Format(dateStringFormat)]
+		if ok && hours[hour.Hour()] == '1' {
+			current.UptimeHours++
+			report.UptimeHours++
+			gap = nil
+			continue
+		}
+
+		if gap == nil {
+			report.Gaps = append(report.Gaps, reportGap{From: hour})
+			gap = &report.Gaps[len(report.Gaps)-1]
+		}
+		gap.To = hour.Add(time.Hour)
+		gap.Hours++
+	}
+
+	for i := range report.Periods {
+		report.Periods[i].Coverage = float64(report.Periods[i].UptimeHours) / float64(report.Periods[i].Hours)
+	}
+	return report, nil
+}
+
+// uptimeTrackingEnabled tells whether `SUSEConnect --keepalive` submits the
+// uptime log.
+func uptimeTrackingEnabled() bool {
+	opts, err := localReadSUSEConnectConf()
+	if err != nil {
+		return false
+	}
+	return opts.EnableSystemUptimeTracking
+}
+
+func printReportTable(out io.Writer, report *uptimeReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(report.By)+"\tUPTIME HOURS\tHOURS\tCOVERAGE")
+	for _, p := range report.Periods {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\n", p.Period, p.UptimeHours, p.Hours, 100*p.Coverage)
+	}
+	coverage := 0.0
+	if report.Hours > 0 {
+		coverage = float64(report.UptimeHours) / float64(report.Hours)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%d\t%.1f%%\n", report.UptimeHours, report.Hours, 100*coverage)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nGaps between %s and %s:\n", report.From, report.To)
+	if len(report.Gaps) == 0 {
+		fmt.Fprintln(out, "  none")
+	}
+	layout := "2006-01-02 15:04"
+	for _, gap := range report.Gaps {
+		fmt.Fprintf(out, "  %s - %s UTC (%d hours)\n", gap.From.Format(layout), gap.To.Format(layout), gap.Hours)
+	}
+
+	if !report.UptimeTrackingEnabled {
+		fmt.Fprintf(out, "\nUptime tracking is not enabled in %s, nothing is submitted on keepalive.\n", connect.DefaultConfigPath)
+		return nil
+	}
+	fmt.Fprintln(out, "\nSubmitted as online_at on the next keepalive:")
+	for _, line := range report.OnlineAt {
+		fmt.Fprintln(out, "  "+line)
+	}
+	return nil
+}
+
+// printReportCSV prints the periods only, gaps being the hours without
+// uptime of each period.
+func printReportCSV(out io.Writer, report *uptimeReport) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{report.By, "uptime_hours", "hours", "gap_hours", "coverage"})
+	for _, p := range report.Periods {
+		w.Write([]string{
+			p.Period,
+			fmt.Sprint(p.UptimeHours),
+			fmt.Sprint(p.Hours),
+			fmt.Sprint(p.Hours - p.UptimeHours),
+			fmt.Sprintf("%.4f", p.Coverage),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/connect"
+	"github.com/SUSE/connect-ng/internal/uptime"
+	"github.com/stretchr/testify/assert"
+)
+
+var reportLog = uptime.Log{
+	"2025-06-01": "000000001111000000000000",
+	"2025-06-02": "111111111111111111111111",
+}
+
+var reportNow = time.Date(2025, 6, 3, 1, 30, 0, 0, time.UTC)
+
+func TestBuildReport(t *testing.T) {
+	assert := assert.New(t)
+
+	report, err := buildReport(reportLog, date("2025-06-01 00:00"), date("2025-06-03 00:00"), "day", reportNow)
+	assert.NoError(err)
+	assert.Equal(28, report.UptimeHours)
+	// The hours after now are left out.
+	assert.Equal(50, report.Hours)
+	assert.Equal([]reportPeriod{
+		{Period: "2025-06-01", UptimeHours: 4, Hours: 24, Coverage: 4.0 / 24},
+		{Period: "2025-06-02", UptimeHours: 24, Hours: 24, Coverage: 1},
+		{Period: "2025-06-03", UptimeHours: 0, Hours: 2, Coverage: 0},
+	}, report.Periods)
+	assert.Equal([]reportGap{
+		{From: date("2025-06-01 00:00"), To: date("2025-06-01 08:00"), Hours: 8},
+		{From: date("2025-06-01 12:00"), To: date("2025-06-02 00:00"), Hours: 12},
+		{From: date("2025-06-03 00:00"), To: date("2025-06-03 02:00"), Hours: 2},
+	}, report.Gaps)
+
+	report, err = buildReport(reportLog, date("2025-06-01 00:00"), date("2025-06-03 00:00"), "week", reportNow)
+	assert.NoError(err)
+	assert.Len(report.Periods, 2)
+	assert.Equal(reportPeriod{Period: "2025-W22", UptimeHours: 4, Hours: 24, Coverage: 4.0 / 24}, report.Periods[0])
+	assert.Equal("2025-W23", report.Periods[1].Period)
+
+	report, err = buildReport(reportLog, date("2025-06-01 00:00"), date("2025-06-03 00:00"), "month", reportNow)
+	assert.NoError(err)
+	assert.Equal([]reportPeriod{{Period: "2025-06", UptimeHours: 28, Hours: 50, Coverage: 28.0 / 50}}, report.Periods)
+
+	_, err = buildReport(reportLog, date("2025-06-01 00:00"), date("2025-06-03 00:00"), "year", reportNow)
+	assert.ErrorContains(err, "unknown report period")
+}
+
+func TestPrintReport(t *testing.T) {
+	assert := assert.New(t)
+
+	report, _ := buildReport(reportLog, date("2025-06-01 00:00"), date("2025-06-02 00:00"), "day", reportNow)
+	report.UptimeTrackingEnabled = true
+	report.OnlineAt = reportLog.Lines()
+
+	var table bytes.Buffer
+	assert.NoError(printReportTable(&table, report))
+	assert.Equal(`DAY         UPTIME HOURS  HOURS  COVERAGE
+2025-06-01  4             24     16.7%
+2025-06-02  24            24     100.0%
+TOTAL       28            48     58.3%
+
+Gaps between 2025-06-01 and 2025-06-02:
+  2025-06-01 00:00 - 2025-06-01 08:00 UTC (8 hours)
+  2025-06-01 12:00 - 2025-06-02 00:00 UTC (12 hours)
+
+Submitted as online_at on the next keepalive:
+  2025-06-01:000000001111000000000000
+  2025-06-02:111111111111111111111111
+`, table.String())
+
+	var csv bytes.Buffer
+	assert.NoError(printReportCSV(&csv, report))
+	assert.Equal(`day,uptime_hours,hours,gap_hours,coverage
+2025-06-01,4,24,20,0.1667
+2025-06-02,24,24,0,1.0000
+`, csv.String())
+}
+
+func TestUptimeTrackingEnabled(t *testing.T) {
+	assert := assert.New(t)
+
+	orig := localReadSUSEConnectConf
+	t.Cleanup(func() { localReadSUSEConnectConf = orig })
+
+	path := filepath.Join(t.TempDir(), "SUSEConnect")
+	localReadSUSEConnectConf = func() (*connect.Options, error) { return connect.ReadFromConfiguration(path) }
+
+	assert.NoError(os.WriteFile(path, []byte("---\nurl: https://scc.suse.com\nenable_system_uptime_tracking: true\n"), 0600))
+	assert.True(uptimeTrackingEnabled())
+
+	assert.NoError(os.WriteFile(path, []byte("---\nurl: https://scc.suse.com\n"), 0600))
+	assert.False(uptimeTrackingEnabled())
+
+	localReadSUSEConnectConf = func() (*connect.Options, error) { return nil, errors.New("invalid configuration") }
+	assert.False(uptimeTrackingEnabled())
+}
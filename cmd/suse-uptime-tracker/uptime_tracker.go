@@ -1,15 +1,15 @@
 package main
 
 import (
-	"bufio"
 	_ "embed"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"sort"
 	"strings"
 	"time"
+
+	"github.com/SUSE/connect-ng/internal/uptime"
 )
 
 var (
@@ -21,9 +21,9 @@ var (
 
 const (
 	uptimeCheckLogsFilePath = "/etc/zypp/suse-uptime.log"
-	dateStringFormat        = "2006-01-02"
-	initUptimeHours         = "000000000000000000000000" // initialize the uptime hours bit string with
-	daysBeforePurge         = 90                         // purge all the records after this many days
+	dateStringFormat        = uptime.DateFormat
+	initUptimeHours         = uptime.NoUptime // initialize the uptime hours bit string with
+	daysBeforePurge         = 90              // purge all the records after this many days
 )
 
 func getShortenedVersion() string {
@@ -38,9 +38,19 @@ func exitOnError(err error) {
 	os.Exit(1)
 }
 
-func displayUptimeVersion() {
+// trackerOptions are the options given on the command line.
+type trackerOptions struct {
+	rebuild bool
+	from    time.Time
+	to      time.Time
+}
+
+func parseOptions() trackerOptions {
 	var (
 		version bool
+		rebuild bool
+		from    string
+		to      string
 	)
 
 	flag.Usage = func() {
@@ -48,41 +58,47 @@ func displayUptimeVersion() {
 	}
 
 	flag.BoolVar(&version, "version", false, "")
+	flag.BoolVar(&rebuild, "rebuild", false, "")
+	flag.StringVar(&from, "from", "", "")
+	flag.StringVar(&to, "to", "", "")
 
 	flag.Parse()
 	if version {
 		fmt.Println(getShortenedVersion())
 		os.Exit(0)
 	}
-}
-
-func readUptimeLogFile(uptimeLogsFilePath string) (map[string]string, error) {
-	uptimeLogsFile, err := os.Open(uptimeLogsFilePath)
-	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			// file doesn't exist, so don't error out
-			return nil, nil
-		}
-		return nil, err
+	if (from != "" || to != "") && !rebuild {
+		fmt.Println("--from and --to can only be used with --rebuild")
+		flag.Usage()
+		os.Exit(1)
 	}
-	fileScanner := bufio.NewScanner(uptimeLogsFile)
-	fileScanner.Split(bufio.ScanLines)
-	var logEntries = make(map[string]string)
-
-	var entry []string
-	for fileScanner.Scan() {
-		entryText := fileScanner.Text()
-		entry = strings.Split(entryText, ":")
-		if len(entry) != 2 {
-			return nil, errors.New("Uptime log file is corrupted. Invalid log entry " + entryText)
-		}
-		logEntries[entry[0]] = entry[1]
+	// The hours of the rebuilt days are replaced, so they have to be given
+	// explicitly.
+	if rebuild && (from == "" || to == "") {
+		fmt.Println("--rebuild requires --from and --to")
+		flag.Usage()
+		os.Exit(1)
 	}
-	err = uptimeLogsFile.Close()
-	if err != nil {
-		return nil, err
+
+	opts := trackerOptions{rebuild: rebuild}
+	if !rebuild {
+		return opts
 	}
-	return logEntries, nil
+	var err error
+	opts.from, err = time.Parse(dateStringFormat, from)
+	exitOnError(err)
+	opts.to, err = time.Parse(dateStringFormat, to)
+	exitOnError(err)
+	if opts.to.Before(opts.from) {
+		exitOnError(errors.New("--to has to be after --from"))
+	}
+	return opts
+}
+
+// readUptimeLogFile reads the uptime log, upgrading old formats and skipping
+// malformed entries. It returns nil if the file does not exist.
+func readUptimeLogFile(uptimeLogsFilePath string) (map[string]string, error) {
+	return uptime.Read(uptimeLogsFilePath)
 }
 
 func purgeOldUptimeLog(uptimeLogs map[string]string) (map[string]string, error) {
@@ -117,36 +133,32 @@ func updateUptimeLog(uptimeLogs map[string]string) map[string]string {
 	return uptimeLogs
 }
 
-func writeUptimeLogsFile(uptimeLogsFilePath string, uptimeLogs map[string]string) error {
-	uptimeLogsFile, err := os.OpenFile(uptimeLogsFilePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		exitOnError(runReport(os.Args[2:], os.Stdout))
+		return
 	}
-	defer uptimeLogsFile.Close()
 
-	// sort the keys
-	keys := make([]string, 0, len(uptimeLogs))
-	for day := range uptimeLogs {
-		keys = append(keys, day)
-	}
-	sort.Strings(keys)
+	opts := parseOptions()
+	err := uptime.Update(uptimeCheckLogsFilePath, func(uptimeLogs uptime.Log) (uptime.Log, error) {
+		// Hours in which the tracker did not run (e.g. the timer was
+		// disabled) are filled in from the boot history.
+		now := time.Now().UTC()
+		history := bootHistory(now)
+		if opts.rebuild {
+			uptimeLogs = rebuildUptimeLog(uptimeLogs, history, opts.from, opts.to)
+		} else {
+			uptimeLogs = backfillUptimeLog(uptimeLogs, history, now.AddDate(0, 0, -daysBeforePurge), now)
+		}
 
-	for _, day := range keys {
-		_, err = uptimeLogsFile.WriteString(day + ":" + uptimeLogs[day] + "\n")
+		purged, err := purgeOldUptimeLog(uptimeLogs)
 		if err != nil {
-			return err
+			return nil, err
 		}
-	}
-	return nil
-}
-
-func main() {
-	displayUptimeVersion()
-	uptimeLogs, err := readUptimeLogFile(uptimeCheckLogsFilePath)
-	exitOnError(err)
-	uptimeLogs, err = purgeOldUptimeLog(uptimeLogs)
-	exitOnError(err)
-	uptimeLogs = updateUptimeLog(uptimeLogs)
-	err = writeUptimeLogsFile(uptimeCheckLogsFilePath, uptimeLogs)
+		if !opts.rebuild || !now.Before(opts.from) && now.Before(opts.to.AddDate(0, 0, 1)) {
+			purged = updateUptimeLog(purged)
+		}
+		return purged, nil
+	})
 	exitOnError(err)
 }
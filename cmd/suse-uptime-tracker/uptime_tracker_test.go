@@ -46,9 +46,12 @@ func TestCorruptedUptimeLog(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create temp uptime log file for testing")
 	}
-	_, err = readUptimeLogFile(tempFilePath)
-	if err == nil {
-		t.Fatalf("Expected an error for corrupted uptime logs entry")
+	uptimeLog, err := readUptimeLogFile(tempFilePath)
+	if err != nil {
+		t.Fatalf("Expected corrupted uptime logs entries to be skipped, got %v", err)
+	}
+	if len(uptimeLog) != 1 || uptimeLog["2024-01-18"] != "000000000000001000110000" {
+		t.Fatalf("Expected the valid uptime logs entry to be recovered, got %v", uptimeLog)
 	}
 	defer os.Remove(tempFilePath)
 }
@@ -46,14 +46,16 @@ type Action struct {
 type ToolInput struct{}
 
 type RegisterInput struct {
-	Regcode string `json:"regcode" jsonschema:"The subscription registration code to register the system with"`
-	Email   string `json:"email,omitempty" jsonschema:"Email Address to associate the registration with"`
+	Regcode        string `json:"regcode" jsonschema:"The subscription registration code to register the system with"`
+	Email          string `json:"email,omitempty" jsonschema:"Email Address to associate the registration with"`
+	AcceptLicenses bool   `json:"accept_licenses,omitempty" jsonschema:"Accept the license agreements of the activated products. Registration fails if a license has to be accepted and this is not set."`
 }
 
 type ActivateInput struct {
-	Regcode string `json:"regcode,omitempty" jsonschema:"The subscription registration code to register the system with"`
-	Product string `json:"product" jsonschema:"The product to activate on the system, e.g. 'sle-module-basesystem/15.5/x86_64'. The system needs to be registered first. Available extensions and modules to activate can be found via the ListExtensions tool."`
-	Email   string `json:"email,omitempty" jsonschema:"Email Address to associate the registration with"`
+	Regcode        string `json:"regcode,omitempty" jsonschema:"The subscription registration code to register the system with"`
+	Product        string `json:"product" jsonschema:"The product to activate on the system, e.g. 'sle-module-basesystem/15.5/x86_64'. The system needs to be registered first. Available extensions and modules to activate can be found via the ListExtensions tool."`
+	Email          string `json:"email,omitempty" jsonschema:"Email Address to associate the registration with"`
+	AcceptLicenses bool   `json:"accept_licenses,omitempty" jsonschema:"Accept the license agreement of the product. Activation fails if a license has to be accepted and this is not set."`
 }
 
 type DeactivateInput struct {
@@ -216,9 +218,15 @@ func RegisterSystem(ctx context.Context, req *mcp.CallToolRequest, input Registe
 	}
 	opts.Token = input.Regcode
 	opts.Email = input.Email
+	// The standard streams carry the JSON-RPC messages, never prompt.
+	opts.NonInteractive = true
+	if input.AcceptLicenses {
+		opts.AutoAgreeEULA = true
+	}
 
 	api := connect.NewWrappedAPI(opts)
-	err = connect.Register(api, opts)
+	out, err := connect.Register(api, opts)
+	audit(opts, out)
 	if err != nil {
 		return nil, JSONOutput{Error: "System registration failed"}, err
 	}
@@ -240,6 +248,10 @@ func ActivateProduct(ctx context.Context, req *mcp.CallToolRequest, input Activa
 	}
 	opts.Token = input.Regcode
 	opts.Email = input.Email
+	opts.NonInteractive = true
+	if input.AcceptLicenses {
+		opts.AutoAgreeEULA = true
+	}
 	if p, err := registration.FromTriplet(input.Product); err != nil {
 		return nil, JSONOutput{Error: "Please provide the product identifier in this format: <internal name>/<version>/<architecture>. You can find these values in the ListExtensions tool"}, err
 	} else {
@@ -247,7 +259,8 @@ func ActivateProduct(ctx context.Context, req *mcp.CallToolRequest, input Activa
 	}
 
 	api := connect.NewWrappedAPI(opts)
-	err = connect.Register(api, opts)
+	out, err := connect.Register(api, opts)
+	audit(opts, out)
 	if err != nil {
 		return nil, JSONOutput{Error: "System registration failed"}, err
 	}
@@ -269,7 +282,8 @@ func DeregisterSystem(ctx context.Context, req *mcp.CallToolRequest, input ToolI
 	}
 
 	api := connect.NewWrappedAPI(opts)
-	err = connect.Deregister(api, opts)
+	out, err := connect.Deregister(api, opts)
+	audit(opts, out)
 	if err != nil {
 		return nil, JSONOutput{Error: "System deregistration failed"}, err
 	}
@@ -297,7 +311,8 @@ func DeactivateProduct(ctx context.Context, req *mcp.CallToolRequest, input Deac
 	}
 
 	api := connect.NewWrappedAPI(opts)
-	err = connect.Deregister(api, opts)
+	out, err := connect.Deregister(api, opts)
+	audit(opts, out)
 	if err != nil {
 		return nil, JSONOutput{Error: "Product deactivation failed"}, err
 	}
@@ -305,6 +320,13 @@ func DeactivateProduct(ctx context.Context, req *mcp.CallToolRequest, input Deac
 	return nil, JSONOutput{Response: "Product successfully deactivated"}, nil
 }
 
+// audit records the operation in the audit log of SUSEConnect.
+func audit(opts *connect.Options, out *connect.RegisterOut) {
+	if err := connect.Audit(opts, connect.AuditResultRecord(out)); err != nil {
+		slog.Warn("Could not write the audit log", "error", err)
+	}
+}
+
 func main() {
 	if os.Geteuid() != 0 {
 		fmt.Fprintln(os.Stderr, "Root privileges are required to run the MCP server.")
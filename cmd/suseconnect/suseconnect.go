@@ -2,21 +2,27 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	_ "embed"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/mail"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"github.com/SUSE/connect-ng/internal/connect"
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/internal/zypper"
 	"github.com/SUSE/connect-ng/pkg/connection"
@@ -27,10 +33,19 @@ import (
 var (
 	//go:embed connectUsage.txt
 	connectUsageText string
+
+	// Set with --json: every command prints a single JSON document, including
+	// on failure.
+	jsonOutput bool
+
+	// Exit code of invalid flags and usage errors. With --check-subscriptions
+	// it is UNKNOWN as Nagios plugins are expected to exit with.
+	usageExitCode = 1
 )
 
 const (
 	outdatedRegProxy = "Your Registration Proxy server doesn't support this function."
+	sumaManaged      = "This system is managed by SUSE Manager / Uyuni, do not use SUSEconnect."
 )
 
 // singleStringFlag cannot be set more than once.
@@ -62,13 +77,30 @@ func main() {
 	var (
 		status                bool
 		keepAlive             bool
+		daemon                bool
 		statusText            bool
+		checkSubscriptions    bool
+		warnDays              int
+		verify                bool
+		repair                bool
+		nonInteractive        bool
+		critDays              int
+		writeMetrics          bool
+		metricsDir            string
+		format                string
+		outputFile            string
 		debug                 bool
+		logFormat             string
+		logJournal            bool
 		writeConfig           bool
 		deRegister            bool
+		forceLocal            bool
 		cleanup               bool
 		rollback              bool
 		baseURL               string
+		switchServer          string
+		offlineRequest        string
+		offlineCertificate    string
 		fsRoot                singleStringFlag
 		namespace             string
 		token                 string
@@ -82,21 +114,37 @@ func main() {
 		version               bool
 		jsonFlag              bool
 		info                  bool
+		explain               bool
+		history               bool
 	)
 
 	// display help like the ruby SUSEConnect
 	flag.Usage = func() {
-		fmt.Print(connectUsageText)
+		fmt.Print(i18n.T(connectUsageText))
 	}
 
 	flag.BoolVar(&status, "status", false, "")
 	flag.BoolVar(&status, "s", false, "")
 	flag.BoolVar(&statusText, "status-text", false, "")
+	flag.BoolVar(&checkSubscriptions, "check-subscriptions", false, "")
+	flag.IntVar(&warnDays, "warn-days", connect.DefaultWarnDays, "")
+	flag.IntVar(&critDays, "crit-days", connect.DefaultCritDays, "")
+	flag.BoolVar(&verify, "verify", false, "")
+	flag.BoolVar(&repair, "repair", false, "")
+	flag.BoolVar(&nonInteractive, "non-interactive", false, "")
+	flag.BoolVar(&writeMetrics, "write-metrics", false, "")
+	flag.StringVar(&metricsDir, "metrics-dir", "", "")
+	flag.StringVar(&format, "format", "", "")
+	flag.StringVar(&outputFile, "output-file", "", "")
 	flag.BoolVar(&keepAlive, "keepalive", false, "")
+	flag.BoolVar(&daemon, "daemon", false, "")
 	flag.BoolVar(&debug, "debug", false, "")
+	flag.StringVar(&logFormat, "log-format", "", "")
+	flag.BoolVar(&logJournal, "log-journal", false, "")
 	flag.BoolVar(&writeConfig, "write-config", false, "")
 	flag.BoolVar(&deRegister, "de-register", false, "")
 	flag.BoolVar(&deRegister, "d", false, "")
+	flag.BoolVar(&forceLocal, "force-local", false, "")
 	flag.BoolVar(&cleanup, "cleanup", false, "")
 	flag.BoolVar(&cleanup, "clean", false, "")
 	flag.BoolVar(&listExtensions, "list-extensions", false, "")
@@ -106,6 +154,9 @@ func main() {
 	flag.BoolVar(&autoImportRepoKeys, "gpg-auto-import-keys", false, "")
 	flag.BoolVar(&autoAgreeWithLicenses, "auto-agree-with-licenses", false, "")
 	flag.StringVar(&baseURL, "url", "", "")
+	flag.StringVar(&switchServer, "switch-server", "", "")
+	flag.StringVar(&offlineRequest, "offline-request", "", "")
+	flag.StringVar(&offlineCertificate, "offline-certificate", "", "")
 	flag.Var(&fsRoot, "root", "")
 	flag.StringVar(&namespace, "namespace", "", "")
 	flag.StringVar(&token, "regcode", "", "")
@@ -119,32 +170,120 @@ func main() {
 	flag.BoolVar(&jsonFlag, "json", false, "")
 	flag.BoolVar(&info, "info", false, "")
 	flag.BoolVar(&info, "i", false, "")
+	flag.BoolVar(&explain, "explain", false, "")
+	flag.BoolVar(&history, "history", false, "")
 
-	flag.Parse()
+	if checkSubscriptionsRequested(os.Args[1:]) {
+		usageExitCode = int(connect.CheckUnknown)
+	}
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(usageExitCode)
+	}
+	jsonOutput = jsonFlag
 	if flag.NArg() > 0 {
-		fmt.Fprintf(os.Stderr, "Error: Unexpected argument '%s'\n\n", flag.Arg(0))
-		flag.Usage()
-		os.Exit(1)
+		exitWithUsage(i18n.Tf("Unexpected argument '%s'", flag.Arg(0)))
+	}
+
+	if forceLocal && (!deRegister || product.isSet) {
+		exitWithUsage(i18n.T("--force-local can only be used with --de-register for the whole system"))
+	}
+
+	if !checkSubscriptions {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "warn-days" || f.Name == "crit-days" {
+				exitWithUsage(i18n.Tf("--%s can only be used with --check-subscriptions", f.Name))
+			}
+		})
+	}
+
+	if nonInteractive && !repair {
+		exitWithUsage(i18n.T("--non-interactive can only be used with --repair"))
+	}
+
+	if metricsDir != "" && !writeMetrics {
+		exitWithUsage(i18n.T("--metrics-dir can only be used with --write-metrics"))
+	}
+
+	if daemon && !keepAlive {
+		exitWithUsage(i18n.T("--daemon can only be used with --keepalive"))
+	}
+
+	if explain && !info {
+		exitWithUsage(i18n.T("--explain can only be used with --info"))
+	}
+
+	// The output of these commands goes through the same renderer, which is
+	// configured with --format and --output-file.
+	outputFormat := connect.OutputFormat{Kind: connect.FormatText}
+	if format != "" || outputFile != "" {
+		if !status && !statusText && !listExtensions && !info && !history {
+			exitWithUsage(i18n.T("--format and --output-file can only be used with --status, --status-text, --list-extensions, --info or --history"))
+		}
+	}
+	if format != "" {
+		parsed, err := connect.ParseOutputFormat(format)
+		if err != nil {
+			exitWithUsage(i18n.Tf("--format: %v", err))
+		}
+		if jsonFlag && parsed.Kind != connect.FormatJSON {
+			exitWithUsage(i18n.T("--json cannot be used together with --format"))
+		}
+		outputFormat = parsed
+	} else if status || jsonFlag {
+		outputFormat = connect.OutputFormat{Kind: connect.FormatJSON}
+	}
+
+	// Progress messages (e.g. the zypper calls) are not shown, only warnings
+	// and errors unless --debug is given.
+	logOpts := util.LogOptions{Level: util.LevelWarn, Format: util.LogConsole, Journal: logJournal}
+	if logFormat != "" {
+		parsed, err := util.ParseLogFormat(logFormat)
+		if err != nil {
+			exitWithUsage(i18n.Tf("--log-format: %v", err))
+		}
+		logOpts.Format = parsed
+	}
+	if daemon {
+		// Report each keepalive call in the journal.
+		logOpts.Level = util.LevelInfo
+	}
+	if debug {
+		logOpts.Level = util.LevelDebug
 	}
+	util.SetupLogging(logOpts)
 
 	if version {
-		fmt.Println(connect.GetShortenedVersion())
+		if jsonFlag {
+			printJSON(connect.NewJSONResult(connect.GetShortenedVersion(), map[string]string{
+				"version": connect.GetShortenedVersion(),
+			}))
+		} else {
+			fmt.Println(connect.GetShortenedVersion())
+		}
 		os.Exit(0)
 	}
 	if os.Geteuid() != 0 {
-		fmt.Fprintln(os.Stderr, "Root privileges are required to register products and change software repositories.")
+		message := i18n.T("Root privileges are required to register products and change software repositories.")
+		if jsonFlag {
+			printJSON(connect.NewJSONFailure(connect.ErrorCodePermissionDenied, message, nil))
+		} else {
+			fmt.Fprintln(os.Stderr, message)
+		}
+		if checkSubscriptions {
+			os.Exit(int(connect.CheckUnknown))
+		}
 		os.Exit(1)
 	}
-	if debug {
-		util.EnableDebug()
-	}
-	util.Debug.Println("cmd line:", os.Args)
-	util.Debug.Println("For http debug use: GODEBUG=http2debug=2", strings.Join(os.Args, " "))
+	slog.Debug("Starting", "command", strings.Join(connect.MaskCommandLine(os.Args), " "), "version", connect.GetShortenedVersion())
+	slog.Debug("For http debug use: GODEBUG=http2debug=2 " + strings.Join(connect.MaskCommandLine(os.Args), " "))
 
 	// Ensure --root parameter is actually an absolute path
 	if fsRoot.isSet && !filepath.IsAbs(fsRoot.value) {
-		fmt.Println("SUSEConnect error: the path specified in the --root option must be absolute.")
-		os.Exit(1)
+		exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.T("SUSEConnect error: the path specified in the --root option must be absolute."))
 	}
 
 	// Fetch the options to be passed to the internal/connect library by reading
@@ -153,6 +292,9 @@ func main() {
 	configPath := filepath.Join(fsRoot.value, connect.DefaultConfigPath)
 	opts, err := connect.ReadFromConfiguration(configPath)
 	exitOnError(err, nil, nil)
+	// Keep the options of the configuration file to tell the ones given on
+	// the command line apart when the keepalive daemon reloads it.
+	fromFile := *opts
 
 	// Parsing the given URL. This URL can be given both as an explicit command
 	// line flag, or via an environment variable. If the environment variable is
@@ -165,13 +307,21 @@ func main() {
 	}
 	if baseURL != "" {
 		if err := validateURL(baseURL); err != nil {
-			fmt.Printf("SUSEConnect error: URL \"%s\" not valid: %s\n", baseURL, err)
-			os.Exit(1)
+			exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.Tf("SUSEConnect error: URL \"%s\" not valid: %s", baseURL, err))
 		}
 		opts.ChangeBaseURL(baseURL)
 		writeConfig = true
 	}
 
+	if switchServer != "" {
+		if baseURL != "" {
+			exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.T("SUSEConnect error: --switch-server cannot be used together with --url."))
+		}
+		if err := validateURL(switchServer); err != nil {
+			exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.Tf("SUSEConnect error: URL \"%s\" not valid: %s", switchServer, err))
+		}
+	}
+
 	if fsRoot.isSet {
 		opts.FsRoot = fsRoot.value
 		zypper.SetFilesystemRoot(fsRoot.value)
@@ -185,17 +335,15 @@ func main() {
 		opts.Token = token
 		processedToken, processTokenErr := processToken(token)
 		if processTokenErr != nil {
-			fmt.Printf("SUSEConnect error: %v", processTokenErr)
-			os.Exit(1)
+			exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.Tf("SUSEConnect error: %v", processTokenErr))
 		}
 		opts.Token = processedToken
 	}
 	if product.isSet {
 		if p, err := registration.FromTriplet(product.value); err != nil {
-			fmt.Print("Please provide the product identifier in this format: ")
-			fmt.Print("<internal name>/<version>/<architecture>. You can find ")
-			fmt.Print("these values by calling: 'SUSEConnect --list-extensions'\n")
-			os.Exit(1)
+			exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.T("Please provide the product identifier in this format: "+
+				"<internal name>/<version>/<architecture>. You can find "+
+				"these values by calling: 'SUSEConnect --list-extensions'"))
 		} else {
 			opts.Product = p
 		}
@@ -206,7 +354,12 @@ func main() {
 	if email != "" {
 		_, err := mail.ParseAddress(email)
 		if err != nil {
-			fmt.Printf("SUSEConnect warning: ignoring malformed email address: %s\n", email)
+			warning := i18n.Tf("SUSEConnect warning: ignoring malformed email address: %s", email)
+			if jsonFlag {
+				fmt.Fprintln(os.Stderr, warning)
+			} else {
+				fmt.Println(warning)
+			}
 			email = ""
 		}
 		opts.Email = email
@@ -238,6 +391,7 @@ func main() {
 	if jsonFlag {
 		opts.OutputKind = connect.JSON
 	}
+	slog.SetDefault(slog.Default().With("server", opts.BaseURL))
 
 	api := connect.NewWrappedAPI(opts)
 
@@ -248,97 +402,196 @@ func main() {
 	//
 	// Rollback *must* be allowed because is used as a synchonization mechanism
 	// in the transactional-update toolkit.
-	if deRegister || cleanup {
+	if deRegister || cleanup || switchServer != "" || repair {
 		if err := util.ReadOnlyFilesystem(opts.FsRoot); err != nil {
 			exitOnError(err, api, opts)
 		}
 	}
 
-	if status || statusText {
+	var registered *connect.RegisterOut
+	if checkSubscriptions {
+		// Exit codes follow the Nagios plugin conventions.
+		result := connect.CheckSubscriptions(api, opts, warnDays, critDays)
 		if jsonFlag {
-			exitOnError(errors.New("cannot use the json option with this command"), api, opts)
+			message := fmt.Sprintf("%s - %s", result.State, result.Summary)
+			if result.State == connect.CheckOK {
+				printJSON(connect.NewJSONResult(message, result))
+			} else {
+				printJSON(connect.NewJSONFailure(connect.ErrorCodeSubscriptionCheck, message, result))
+			}
+		} else {
+			fmt.Println(result.Text())
 		}
-		if status {
-			err = connect.PrintProductStatuses(opts, connect.StatusJSON)
+		os.Exit(int(result.State))
+	} else if verify {
+		result, err := connect.Verify(api, opts)
+		exitOnError(err, api, opts)
+		if jsonFlag {
+			if result.Drift {
+				message := i18n.Tf("Drift detected: %d error(s), %d warning(s)", result.Errors, result.Warnings)
+				printJSON(connect.NewJSONFailure(connect.ErrorCodeDriftDetected, message, result))
+			} else {
+				printJSON(connect.NewJSONResult(i18n.T("No drift detected"), result))
+			}
 		} else {
-			err = connect.PrintProductStatuses(opts, connect.StatusText)
+			fmt.Println(result.Text())
+		}
+		if result.Drift {
+			os.Exit(1)
+		}
+	} else if repair {
+		if isSumaManaged() {
+			exitWithFailure(connect.ErrorCodeManagedSystem, i18n.T(sumaManaged))
+		}
+		out, err := connect.Repair(api, opts, nonInteractive)
+		audit(opts, connect.AuditResultRecord(out))
+		exitWithResult(out, err, jsonFlag, api, opts)
+		if !jsonFlag {
+			util.Info.Print(util.Bold(util.GreenText("\n" + out.Message)))
+		}
+	} else if writeMetrics {
+		if metricsDir == "" {
+			metricsDir = opts.MetricsDir
 		}
+		err := connect.WriteMetrics(api, opts, metricsDir)
 		exitOnError(err, api, opts)
+		if jsonFlag {
+			path := filepath.Join(metricsDir, connect.MetricsFileName)
+			printJSON(connect.NewJSONResult(i18n.Tf("Metrics written to %s", path), map[string]string{"path": path}))
+		}
+	} else if status || statusText {
+		output, err := connect.RenderProductStatuses(opts, outputFormat)
+		exitOnError(err, api, opts)
+		if jsonFlag {
+			output = connect.NewJSONResult(i18n.T("Registration status of the installed products"), json.RawMessage(output)).JSON()
+		}
+		exitOnError(connect.WriteOutput(output, outputFile), api, opts)
+	} else if history {
+		output, err := connect.RenderHistory(opts, outputFormat)
+		exitOnError(err, api, opts)
+		if jsonFlag {
+			output = connect.NewJSONResult(i18n.T("Operations recorded in the audit log"), json.RawMessage(output)).JSON()
+		}
+		exitOnError(connect.WriteOutput(output, outputFile), api, opts)
 	} else if keepAlive {
 		if isSumaManaged() {
+			if jsonFlag {
+				printJSON(connect.NewJSONResult(i18n.T("System is managed by SUSE Manager / Uyuni, skipping keepalive"), nil))
+			}
 			os.Exit(0)
 		}
-		if jsonFlag {
-			exitOnError(errors.New("cannot use the json option with the 'keepalive' command"), api, opts)
+		if daemon {
+			exitOnError(runKeepAliveDaemon(configPath, opts, &fromFile), api, opts)
+			os.Exit(0)
 		}
 		api := connect.NewWrappedAPI(opts)
 		err = api.KeepAlive(opts.EnableSystemUptimeTracking)
+		if recordErr := connect.RecordKeepAlive(opts, err); recordErr != nil {
+			slog.Debug("Could not record keepalive", "error", recordErr)
+		}
 		exitOnError(err, api, opts)
-		util.Info.Print(util.Bold(util.GreenText("\nSuccessfully updated system")))
+		if jsonFlag {
+			printJSON(connect.NewJSONResult(i18n.T("Successfully updated system"), nil))
+		} else {
+			util.Info.Print(util.Bold(util.GreenText("\n" + i18n.T("Successfully updated system"))))
+		}
 	} else if listExtensions {
-		output, err := connect.RenderExtensionTree(api, jsonFlag)
+		output, err := connect.RenderExtensions(api, outputFormat)
 		exitOnError(err, api, opts)
-		fmt.Println(output)
+		if jsonFlag {
+			output = connect.NewJSONResult(i18n.T("Extensions and modules available for this system"), json.RawMessage(output)).JSON()
+		}
+		exitOnError(connect.WriteOutput(output, outputFile), api, opts)
 		os.Exit(0)
 	} else if deRegister {
 		// Clear ProfileCache on deregister even if dereg does not succeed.
 		profiles.DeleteProfileCache("*")
-		err := connect.Deregister(api, opts)
-		if jsonFlag && err != nil {
-			out := connect.RegisterOut{Success: false, Message: err.Error()}
-			str, _ := json.Marshal(&out)
-			fmt.Println(string(str))
-			os.Exit(1)
+		var out *connect.RegisterOut
+		if forceLocal {
+			out, err = connect.ForceDeregister(api, opts)
 		} else {
-			exitOnError(err, api, opts)
+			out, err = connect.Deregister(api, opts)
 		}
-	} else if cleanup {
+		audit(opts, connect.AuditResultRecord(out))
+		exitWithResult(out, err, jsonFlag, api, opts)
+	} else if offlineRequest != "" {
+		if jsonFlag && offlineRequest == "-" {
+			request := bytes.Buffer{}
+			exitOnError(connect.WriteOfflineRequest(opts, &request), api, opts)
+			printJSON(connect.NewJSONResult(i18n.T("Offline registration request created"),
+				map[string]string{"request": strings.TrimSpace(request.String())}))
+		} else {
+			exitOnError(writeOfflineRequest(opts, offlineRequest), api, opts)
+			if jsonFlag {
+				printJSON(connect.NewJSONResult(i18n.Tf("Offline registration request written to %s", offlineRequest),
+					map[string]string{"path": offlineRequest}))
+			}
+		}
+	} else if offlineCertificate != "" {
+		cert, err := connect.ImportOfflineCertificate(opts, offlineCertificate)
+		record := connect.NewAuditRecord(connect.OperationImportOfflineCertificate, err)
+		record.Details = map[string]string{"file": offlineCertificate}
+		if err == nil {
+			record.Details["subscription"] = cert.Subscription
+		}
+		audit(opts, record)
+		exitOnError(err, api, opts)
+		message := i18n.Tf("Successfully imported offline registration certificate for '%s' (expires at %s)",
+			cert.Subscription, cert.ExpiresAt.Format("2006-01-02"))
 		if jsonFlag {
-			exitOnError(errors.New("cannot use the json option with the 'cleanup' command"), api, opts)
+			printJSON(connect.NewJSONResult(message, cert))
+		} else {
+			util.Info.Print(util.Bold(util.GreenText("\n" + message)))
+		}
+	} else if switchServer != "" {
+		if isSumaManaged() {
+			exitWithFailure(connect.ErrorCodeManagedSystem, i18n.T(sumaManaged))
 		}
+		profiles.DeleteProfileCache("*")
+		out, err := connect.SwitchServer(api, opts, switchServer)
+		record := connect.AuditResultRecord(out)
+		record.Details = map[string]string{"new_server": switchServer}
+		audit(opts, record)
+		exitWithResult(out, err, jsonFlag, api, opts)
+	} else if cleanup {
 		// Clear ProfileCache on cleanup even if cleanup does not succeed.
 		profiles.DeleteProfileCache("*")
-		err := connect.Cleanup(opts.BaseURL, opts.FsRoot)
-		exitOnError(err, api, opts)
+		out, err := connect.Cleanup(opts.BaseURL, opts.FsRoot)
+		audit(opts, connect.AuditResultRecord(out))
+		exitWithResult(out, err, jsonFlag, api, opts)
 	} else if rollback {
+		out, err := connect.Rollback(api.GetConnection(), opts)
+		audit(opts, connect.AuditResultRecord(out))
+		exitWithResult(out, err, jsonFlag, api, opts)
+	} else if info && explain {
+		output, err := connect.ExplainSystemInformation(opts, outputFormat)
+		exitOnError(err, api, opts)
 		if jsonFlag {
-			exitOnError(errors.New("cannot use the json option with the 'rollback' command"), api, opts)
+			output = connect.NewJSONResult(i18n.T("Collectors of the information reported to the registration server"), json.RawMessage(output)).JSON()
 		}
-		err := connect.Rollback(api.GetConnection(), opts)
-		exitOnError(err, api, opts)
+		exitOnError(connect.WriteOutput(output, outputFile), api, opts)
 	} else if info {
-		sysInfo, err := connect.FetchSystemInformation("", opts.Collectors)
+		output, err := connect.RenderSystemInformation(opts, outputFormat)
 		exitOnError(err, api, opts)
-
-		profileInfo, err := connect.FetchSystemProfiles("", false, opts.Collectors)
-		exitOnError(err, api, opts)
-
-		// -i/--info outputs json data and is expected to be a single
-		// json blob. So, copy contents of profileInfo to
-		// sysInfo amd marshal sysInfo
-		for key, value := range profileInfo {
-			sysInfo[key] = value
+		if jsonFlag {
+			output = connect.NewJSONResult(i18n.T("Information reported to the registration server"), json.RawMessage(output)).JSON()
 		}
-		out, err := json.Marshal(sysInfo)
-		exitOnError(err, api, opts)
-
-		fmt.Print(string(out))
+		exitOnError(connect.WriteOutput(output, outputFile), api, opts)
 	} else {
 		if instanceDataFile != "" && opts.IsScc() {
-			fmt.Print("Please use --instance-data only in combination ")
-			fmt.Print("with --url pointing to your RMT or SMT server\n")
-			os.Exit(1)
+			exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.T("Please use --instance-data only in combination "+
+				"with --url pointing to your RMT or SMT server"))
 		} else if opts.IsScc() && token == "" && product.value == "" {
+			if jsonFlag {
+				exitWithFailure(connect.ErrorCodeInvalidUsage, i18n.T("A registration code (--regcode) is required to register against SCC"))
+			}
 			flag.Usage()
 			os.Exit(1)
 		} else if isSumaManaged() {
-			fmt.Println("This system is managed by SUSE Manager / Uyuni, do not use SUSEconnect.")
-			os.Exit(1)
+			exitWithFailure(connect.ErrorCodeManagedSystem, i18n.T(sumaManaged))
 		} else {
-			// NOTE: if the base system/extensions have EULAs we need to make
-			// sure that they are accepted before proceeding on the registering.
-			// But this has been disabled for now due to bsc#1218878,
-			// bsc#1218649.
+			// NOTE: license agreements of the products to be activated are
+			// checked right before each activation (see connect.AcceptEULA).
 
 			// We need a read-write filesystem to install release packages.
 			if err := util.ReadOnlyFilesystem(opts.FsRoot); err != nil {
@@ -346,43 +599,96 @@ func main() {
 			}
 
 			profiles.DeleteProfileCache("*")
-			err := connect.Register(api, opts)
+			out, err := connect.Register(api, opts)
+			audit(opts, connect.AuditResultRecord(out))
 			if err != nil {
 				// Clear profile cache on registration errors
 				profiles.DeleteProfileCache("*")
-				if jsonFlag {
-					out := connect.RegisterOut{Success: false, Message: err.Error()}
-					str, _ := json.Marshal(&out)
-					fmt.Println(string(str))
-					os.Exit(1)
-				} else {
-					exitOnError(err, api, opts)
-				}
+				exitWithResult(out, err, jsonFlag, api, opts)
 			}
+			registered = out
 
 			// After successful registration we try to set labels if we are
 			// targetting SCC.
 			if opts.IsScc() && len(labels) > 0 {
 				_, err := api.AssignLabels(strings.Split(labels, ","))
+				record := connect.NewAuditRecord(connect.OperationSetLabels, err)
+				record.Details = map[string]string{"labels": labels}
+				audit(opts, record)
 				if err != nil && !jsonFlag {
-					fmt.Printf("Problem setting labels for this system: %s\n", err)
+					fmt.Println(i18n.Tf("Problem setting labels for this system: %s", err))
 				}
 			}
 		}
 	}
 	if writeConfig {
-		if err := opts.SaveAsConfiguration(); err != nil {
-			fmt.Printf("SUSEConnect error: cannot save configuration: %s\n", err)
-			os.Exit(1)
+		err := opts.SaveAsConfiguration()
+		record := connect.NewAuditRecord(connect.OperationWriteConfig, err)
+		record.Details = map[string]string{"path": opts.Path}
+		audit(opts, record)
+		if err != nil {
+			exitWithFailure(connect.ErrorCodeGeneric, i18n.Tf("SUSEConnect error: cannot save configuration: %s", err))
 		}
 	}
+	// The result of the registration is printed once the configuration has
+	// been saved, so failing to do so is still reported as a single document.
+	if registered != nil && jsonFlag {
+		fmt.Println(registered.JSON())
+	}
+}
+
+// writeOfflineRequest writes the offline registration request into the given
+// path, or into the standard output if the path is "-".
+func writeOfflineRequest(opts *connect.Options, path string) error {
+	if path == "-" {
+		return connect.WriteOfflineRequest(opts, os.Stdout)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := connect.WriteOfflineRequest(opts, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if !jsonOutput {
+		util.Info.Print(i18n.Tf("Offline registration request written to %s. Upload it to SCC to obtain an offline registration certificate, then import it with --offline-certificate.", path))
+	}
+	return nil
+}
+
+// exitWithResult prints the result of an operation when running in JSON mode
+// and exits on failure.
+func exitWithResult(out *connect.RegisterOut, err error, jsonFlag bool, api connect.WrappedAPI, opts *connect.Options) {
+	if !jsonFlag {
+		exitOnError(err, api, opts)
+		return
+	}
+	fmt.Println(out.JSON())
+	if err != nil {
+		os.Exit(1)
+	}
 }
 
+// exitOnError reports the given error and exits with the exit code matching
+// it. In JSON mode the error is printed as a JSON document instead.
 func exitOnError(err error, api connect.WrappedAPI, opts *connect.Options) {
-	util.Debug.Println("exitOnError err: ", err)
 	if err == nil {
 		return
 	}
+	slog.Debug("Exiting on error", "error", err)
+
+	exit := func(message string, code int) {
+		if jsonOutput {
+			printJSON(connect.NewJSONError(err, message, nil))
+		} else {
+			fmt.Println(message)
+		}
+		os.Exit(code)
+	}
 
 	// command in error message should match action user needs to do.
 	// so, if TRANSACTIONAL_UPDATE is active or we are trying update
@@ -391,44 +697,43 @@ func exitOnError(err error, api connect.WrappedAPI, opts *connect.Options) {
 	command_string := "SUSEConnect"
 	if os.Getenv("TRANSACTIONAL_UPDATE") != "" {
 		command_string = "transactional-update register"
-	} else if err1 := util.ReadOnlyFilesystem(opts.FsRoot); err1 != nil {
-		if strings.Contains(err1.Error(), "transactional-update register") {
-			command_string = "transactional-update register"
+	} else if opts != nil {
+		if err1 := util.ReadOnlyFilesystem(opts.FsRoot); err1 != nil {
+			if strings.Contains(err1.Error(), "transactional-update register") {
+				command_string = "transactional-update register"
+			}
 		}
 	}
 
 	if ze, ok := err.(zypper.ZypperError); ok {
-		fmt.Println(ze)
-		os.Exit(ze.ExitCode)
+		exit(ze.Error(), ze.ExitCode)
 	}
 	if ue, ok := err.(*url.Error); ok && errors.Is(ue, syscall.ECONNREFUSED) {
-		fmt.Println("Error:", err)
-		os.Exit(64)
+		exit(i18n.Tf("Error: %v", err), 64)
 	}
 	if je, ok := err.(connect.JSONError); ok {
 		if connect.IsOutdatedRegProxy(api.GetConnection(), opts) {
-			fmt.Println(outdatedRegProxy)
-		} else {
-			fmt.Print("Error: Cannot parse response from server\n")
-			fmt.Println(je)
+			exit(i18n.T(outdatedRegProxy), 66)
 		}
-		os.Exit(66)
+		exit(i18n.Tf("Error: Cannot parse response from server\n%s", je), 66)
 	}
 
 	handleAPIError := func(code int, err error) {
 		if code == http.StatusUnauthorized && api.IsRegistered() {
-			errorMsg := fmt.Sprintf("Invalid system credentials, probably because the "+
+			errorMsg := i18n.Tf("Invalid system credentials, probably because the "+
 				"registered system was deleted in SUSE Customer Center. "+
 				"Check %s whether your system appears there. "+
 				"If it does not, please call %s --cleanup and re-register this system.", opts.BaseURL, command_string)
-			jsonOutput, _ := json.Marshal(map[string]string{"Error": errorMsg})
-			fmt.Println(string(jsonOutput))
+			if !jsonOutput {
+				// Kept for the scripts parsing this message.
+				legacy, _ := json.Marshal(map[string]string{"Error": errorMsg})
+				errorMsg = string(legacy)
+			}
+			exit(errorMsg, 67)
 		} else if connect.IsOutdatedRegProxy(api.GetConnection(), opts) {
-			fmt.Println(outdatedRegProxy)
-		} else {
-			fmt.Println(err)
+			exit(i18n.T(outdatedRegProxy), 67)
 		}
-		os.Exit(67)
+		exit(err.Error(), 67)
 	}
 	if ae, ok := err.(connect.APIError); ok {
 		handleAPIError(ae.Code, err)
@@ -440,28 +745,77 @@ func exitOnError(err error, api connect.WrappedAPI, opts *connect.Options) {
 
 	switch err {
 	case connect.ErrSystemNotRegistered:
-		fmt.Print("Deregistration failed. Check if the system has been ")
-		fmt.Print("registered using the --status-text option or use the ")
-		fmt.Print("--regcode parameter to register it.\n")
-		os.Exit(69)
+		exit(i18n.T("Deregistration failed. Check if the system has been "+
+			"registered using the --status-text option or use the "+
+			"--regcode parameter to register it."), 69)
 	case connect.ErrListExtensionsUnregistered:
-		fmt.Print("To list extensions, you must first register the base product, ")
-		fmt.Printf("using: %s -r <registration code>\n", command_string)
-		os.Exit(1)
+		exit(i18n.Tf("To list extensions, you must first register the base product, "+
+			"using: %s -r <registration code>", command_string), 1)
 	case connect.ErrBaseProductDeactivation:
-		fmt.Printf("Can not deregister base product. Use %s -d to deactivate ", command_string)
-		fmt.Print("the whole system.\n")
-		os.Exit(70)
+		exit(i18n.Tf("Can not deregister base product. Use %s -d to deactivate "+
+			"the whole system.", command_string), 70)
 	case connect.ErrPingFromUnregistered:
-		fmt.Print("Error sending keepalive: ")
-		fmt.Print("System is not registered. Use the --regcode parameter to register it.\n")
-		os.Exit(71)
+		exit(i18n.T("Error sending keepalive: "+
+			"System is not registered. Use the --regcode parameter to register it."), 71)
 	default:
-		fmt.Printf("%s error: %s\n", "SUSEConnect", err)
-		os.Exit(1)
+		exit(i18n.Tf("SUSEConnect error: %s", err), 1)
+	}
+}
+
+// exitWithUsage reports an invalid combination of flags and exits.
+func exitWithUsage(message string) {
+	if jsonOutput {
+		printJSON(connect.NewJSONFailure(connect.ErrorCodeInvalidUsage, message, nil))
+	} else {
+		fmt.Fprintf(os.Stderr, "%s\n\n", i18n.Tf("Error: %s", message))
+		flag.Usage()
+	}
+	os.Exit(usageExitCode)
+}
+
+// checkSubscriptionsRequested returns whether --check-subscriptions is part of
+// the given arguments. It is looked up before parsing them, so invalid flags
+// are reported with the exit code of the check as well.
+func checkSubscriptionsRequested(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+		if name == "check-subscriptions" {
+			enabled, err := strconv.ParseBool(value)
+			return !hasValue || (err == nil && enabled)
+		}
+	}
+	return false
+}
+
+// exitWithFailure prints the given message, or a JSON document with the given
+// error code in JSON mode, and exits.
+func exitWithFailure(code connect.ErrorCode, message string) {
+	if jsonOutput {
+		printJSON(connect.NewJSONFailure(code, message, nil))
+	} else {
+		fmt.Println(message)
+	}
+	os.Exit(1)
+}
+
+// audit records an operation in the audit log. Failing to do so does not
+// fail the operation.
+func audit(opts *connect.Options, record *connect.AuditRecord) {
+	if err := connect.Audit(opts, record); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.Tf("Warning: could not write the audit log: %v", err))
 	}
 }
 
+func printJSON(result *connect.JSONResult) {
+	fmt.Println(result.JSON())
+}
+
 func validateURL(s string) error {
 	u, err := url.Parse(s)
 	if err != nil {
@@ -480,6 +834,70 @@ func fileExists(path string) bool {
 	return true
 }
 
+// runKeepAliveDaemon sends keepalive calls until SIGTERM or SIGINT is
+// received. The configuration is read again on SIGHUP, keeping the options
+// given on the command line.
+func runKeepAliveDaemon(configPath string, opts, fromFile *connect.Options) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	reload := make(chan struct{})
+	go func() {
+		for range hup {
+			reload <- struct{}{}
+		}
+	}()
+
+	first := true
+	daemon := &connect.KeepAliveDaemon{
+		Load: func() (*connect.Options, error) {
+			if first {
+				first = false
+				return opts, nil
+			}
+			reloaded, err := connect.ReadFromConfiguration(configPath)
+			if err != nil {
+				return nil, err
+			}
+			mergeCommandLine(reloaded, opts, fromFile)
+			slog.Info("Reloaded configuration", "path", configPath, "server", reloaded.BaseURL)
+			return reloaded, nil
+		},
+		Reload: reload,
+	}
+	return daemon.Run(ctx)
+}
+
+// mergeCommandLine applies the options given on the command line to the
+// reloaded options: the ones which can only be given on the command line,
+// and the ones which differ from the configuration file they were read from.
+func mergeCommandLine(reloaded, opts, fromFile *connect.Options) {
+	if opts.BaseURL != fromFile.BaseURL {
+		reloaded.ChangeBaseURL(opts.BaseURL)
+	}
+	if opts.Namespace != fromFile.Namespace {
+		reloaded.Namespace = opts.Namespace
+	}
+	if opts.Language != fromFile.Language {
+		reloaded.Language = opts.Language
+	}
+	if opts.Email != fromFile.Email {
+		reloaded.Email = opts.Email
+	}
+	if opts.AutoAgreeEULA != fromFile.AutoAgreeEULA {
+		reloaded.AutoAgreeEULA = opts.AutoAgreeEULA
+	}
+	reloaded.FsRoot = opts.FsRoot
+	reloaded.Token = opts.Token
+	reloaded.Product = opts.Product
+	reloaded.InstanceDataFile = opts.InstanceDataFile
+	reloaded.AutoImportRepoKeys = opts.AutoImportRepoKeys
+	reloaded.SkipServiceInstall = opts.SkipServiceInstall
+	reloaded.OutputKind = opts.OutputKind
+}
+
 func isSumaManaged() bool {
 	return fileExists("/etc/sysconfig/rhn/systemid")
 }
@@ -4,6 +4,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/SUSE/connect-ng/internal/connect"
 )
 
 func TestEmptyToken(t *testing.T) {
@@ -102,3 +104,53 @@ func TestProcessTokenFromBadFile(t *testing.T) {
 		t.Fatalf("Bad error message; got '%v'", err)
 	}
 }
+
+func TestMergeCommandLine(t *testing.T) {
+	fromFile := connect.DefaultOptions()
+	fromFile.Namespace = "file-namespace"
+	fromFile.Email = "file@example.com"
+
+	// --url and --root given on top of the configuration file.
+	opts := *fromFile
+	opts.ChangeBaseURL("https://rmt.example.com")
+	opts.FsRoot = "/mnt"
+	opts.Token = "regcode"
+	opts.SkipServiceInstall = true
+
+	// The configuration file changed in the meantime.
+	reloaded := connect.DefaultOptions()
+	reloaded.Namespace = "new-namespace"
+	reloaded.Email = "file@example.com"
+	reloaded.Insecure = true
+
+	mergeCommandLine(reloaded, &opts, fromFile)
+
+	if reloaded.BaseURL != "https://rmt.example.com" || reloaded.ServerType != connect.RmtProvider {
+		t.Errorf("Expected the URL of the command line to be kept, got '%s'", reloaded.BaseURL)
+	}
+	if reloaded.Namespace != "new-namespace" || !reloaded.Insecure {
+		t.Errorf("Expected the reloaded configuration to be used, got '%s'", reloaded.Namespace)
+	}
+	if reloaded.FsRoot != "/mnt" || reloaded.Token != "regcode" || !reloaded.SkipServiceInstall {
+		t.Errorf("Expected the command line only options to be kept")
+	}
+}
+
+func TestCheckSubscriptionsRequested(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"--check-subscriptions", "--bogus"}, true},
+		{[]string{"--warn-days", "10", "-check-subscriptions"}, true},
+		{[]string{"--check-subscriptions=true"}, true},
+		{[]string{"--check-subscriptions=false"}, false},
+		{[]string{"--status", "--bogus"}, false},
+		{[]string{"--", "--check-subscriptions"}, false},
+	}
+	for _, test := range tests {
+		if got := checkSubscriptionsRequested(test.args); got != test.expected {
+			t.Errorf("Expected %v for %v, got %v", test.expected, test.args, got)
+		}
+	}
+}
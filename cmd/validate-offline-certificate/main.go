@@ -11,8 +11,8 @@ import (
 func main() {
 	fmt.Println("validate-offline-certificate: Validate a offline registration certificate and print useful information")
 
-	if len(os.Args) != 4 {
-		fmt.Println("./validate-offline-certificate <filename> <regcode> <uuid>")
+	if len(os.Args) != 4 && len(os.Args) != 5 {
+		fmt.Println("./validate-offline-certificate <filename> <regcode> <uuid> [public-key-pem-file]")
 		os.Exit(1)
 	}
 
@@ -35,7 +35,26 @@ func main() {
 		os.Exit(1)
 	}
 
-	valid, validationErr := cert.IsValid()
+	ring, ringErr := registration.DefaultKeyRing()
+	if ringErr != nil {
+		fmt.Printf("key error: %s\n", ringErr)
+		os.Exit(1)
+	}
+
+	// Certificates issued by a registration proxy are signed with its own key
+	if len(os.Args) == 5 {
+		key, keyErr := os.ReadFile(os.Args[4])
+		if keyErr != nil {
+			fmt.Printf("Reading %s failed: %s\n", os.Args[4], keyErr)
+			os.Exit(1)
+		}
+		if _, keyErr = ring.AddPEM(key); keyErr != nil {
+			fmt.Printf("key error: %s\n", keyErr)
+			os.Exit(1)
+		}
+	}
+
+	valid, validationErr := cert.IsValidWith(ring)
 
 	if validationErr != nil {
 		fmt.Printf("validation error: %s\n", validationErr)
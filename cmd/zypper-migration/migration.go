@@ -6,8 +6,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"sort"
@@ -16,6 +15,7 @@ import (
 	"syscall"
 
 	"github.com/SUSE/connect-ng/internal/connect"
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/internal/zypper"
 	"github.com/SUSE/connect-ng/pkg/connection"
@@ -32,13 +32,6 @@ var (
 	ErrInterrupted = errors.New("Interrupted")
 )
 
-// logger shortcuts
-var (
-	Debug      *log.Logger = util.Debug
-	QuietOut   *log.Logger = util.QuietOut
-	VerboseOut             = log.New(io.Discard, "", 0)
-)
-
 // implements flag.Value interface used to hold values of args which could be
 // passed multiple times e.g. $ command -x 1 -x 2 -x 3
 type multiArg []string
@@ -60,6 +53,8 @@ func main() {
 
 	var (
 		debug                    bool
+		logFormat                string
+		logJournal               bool
 		verbose                  bool
 		quiet                    bool
 		nonInteractive           bool
@@ -81,10 +76,12 @@ func main() {
 	)
 
 	flag.Usage = func() {
-		fmt.Print(migrationUsageText)
+		fmt.Print(i18n.T(migrationUsageText))
 	}
 	// flags without variables match defaults
 	flag.BoolVar(&debug, "debug", false, "")
+	flag.StringVar(&logFormat, "log-format", "", "")
+	flag.BoolVar(&logJournal, "log-journal", false, "")
 	flag.Bool("no-verbose", false, "")
 	flag.BoolVar(&verbose, "verbose", false, "")
 	flag.BoolVar(&verbose, "v", false, "")
@@ -131,21 +128,30 @@ func main() {
 	// negations/negatives below
 	selfUpdate := !noSelfUpdate
 
-	if verbose {
-		VerboseOut.SetOutput(os.Stdout)
+	// Progress messages are printed unless --quiet is given, the verbose ones
+	// only with --verbose.
+	logOpts := util.LogOptions{Level: util.LevelInfo, Format: util.LogConsole, Journal: logJournal}
+	if logFormat != "" {
+		parsed, err := util.ParseLogFormat(logFormat)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		logOpts.Format = parsed
 	}
-
-	if debug {
-		util.EnableDebug()
-	}
-
-	if !quiet {
-		QuietOut.SetOutput(os.Stdout)
+	switch {
+	case debug:
+		logOpts.Level = util.LevelDebug
+	case verbose:
+		logOpts.Level = util.LevelVerbose
+	case quiet:
+		logOpts.Level = util.LevelWarn
 	}
+	util.SetupLogging(logOpts)
 
 	opts, err := connect.ReadFromConfiguration(connect.DefaultConfigPath)
 	if err != nil {
-		fmt.Printf("Something went wrong when reading the configuration: %v\n", err.Error())
+		fmt.Println(i18n.Tf("Something went wrong when reading the configuration: %v", err.Error()))
 		os.Exit(1)
 	}
 
@@ -161,11 +167,11 @@ func main() {
 
 	if !connect.IsSnapperConfigured() {
 		noSnapshots = true
-		VerboseOut.Println("Snapper not configured")
+		util.Verbose("Snapper not configured")
 	}
 
 	if toProduct != "" && fsRoot == "" && !breakMySystem {
-		fmt.Println("The --product option can only be used together with the --root option")
+		fmt.Println(i18n.T("The --product option can only be used together with the --root option"))
 		os.Exit(1)
 	}
 
@@ -177,27 +183,27 @@ func main() {
 			echo = util.SetSystemEcho(true)
 		}
 		if pending, err := zypper.PatchCheck(true, quiet, verbose, nonInteractive, false); err != nil {
-			fmt.Printf("patch pre-check failed: %v\n", err)
+			fmt.Println(i18n.Tf("patch pre-check failed: %v", err))
 			os.Exit(1)
 		} else if pending {
 			// install pending updates and restart
 			if err := zypper.Patch(true, quiet, verbose, nonInteractive, true); err != nil {
-				fmt.Printf("patch failed: %v\n", err)
+				fmt.Println(i18n.Tf("patch failed: %v", err))
 				os.Exit(1)
 			}
 			// stop infinite restarting
 			// check that the patches were really installed
 			if pending, err := zypper.PatchCheck(true, true, false, true, true); pending || err != nil {
 				if pending {
-					fmt.Println("there are still some patches pending")
+					fmt.Println(i18n.T("there are still some patches pending"))
 				}
 				if err != nil {
-					fmt.Printf("patch check returned error: %v\n", err)
+					fmt.Println(i18n.Tf("patch check returned error: %v", err))
 				}
-				fmt.Println("patch failed, exiting.")
+				fmt.Println(i18n.T("patch failed, exiting."))
 				os.Exit(1)
 			}
-			QuietOut.Print("\nRestarting the migration script...\n")
+			slog.Info(i18n.T("\nRestarting the migration script...\n"))
 			// this should replace current process with a new one but stop on error
 			// just in case
 			if err := syscall.Exec(os.Args[0], os.Args, []string{}); err != nil {
@@ -213,14 +219,13 @@ func main() {
 			opts.FsRoot = fsRoot
 		}
 	}
-	QuietOut.Print("\n")
 
 	// This is only necessary, if we run with --root option
 	if !nonInteractive {
 		echo = util.SetSystemEcho(true)
 	}
 	if err := zypper.RefreshRepos("", false, quiet, verbose, nonInteractive, autoImportRepoKeys); err != nil {
-		fmt.Println("repository refresh failed, exiting")
+		fmt.Println(i18n.T("repository refresh failed, exiting"))
 		os.Exit(1)
 	}
 	if !nonInteractive {
@@ -229,14 +234,14 @@ func main() {
 
 	systemProducts, err := checkSystemProducts(api, true, autoImportRepoKeys, nonInteractive, opts)
 	if err != nil {
-		fmt.Printf("Can't determine the list of installed products: %v\n", err)
+		fmt.Println(i18n.Tf("Can't determine the list of installed products: %v", err))
 		os.Exit(1)
 	}
 
 	printProducts(systemProducts)
 
 	if len(systemProducts) == 0 {
-		fmt.Println("No products found, migration is not possible.")
+		fmt.Println(i18n.T("No products found, migration is not possible."))
 		os.Exit(1)
 	}
 
@@ -249,7 +254,7 @@ func main() {
 
 	allMigrations, err := fetchAllMigrations(api.GetConnection(), opts, systemProducts, toProduct)
 	if err != nil {
-		fmt.Printf("Can't get available migrations from server: %v\n", err)
+		fmt.Println(i18n.Tf("Can't get available migrations from server: %v", err))
 		os.Exit(1)
 	}
 
@@ -274,12 +279,12 @@ func main() {
 	if len(unavailableMigrations) > 0 && !quiet {
 		printMigrations(unavailableMigrations,
 			installedIDs,
-			"Unavailable migrations (product is not mirrored):",
+			i18n.T("Unavailable migrations (product is not mirrored):"),
 			false)
 	}
 
 	if len(migrations) == 0 {
-		QuietOut.Print("No migration available.\n\n")
+		slog.Info(i18n.T("No migration available.\n\n"))
 		if len(unavailableMigrations) > 0 {
 			// no need to print a msg - unavailable migrations are listed above
 			os.Exit(1)
@@ -294,18 +299,18 @@ func main() {
 
 	// this part is only used in interactive mode
 	for migrationNum <= 0 || migrationNum > len(migrations) {
-		printMigrations(migrations, installedIDs, "Available migrations:", true)
+		printMigrations(migrations, installedIDs, i18n.T("Available migrations:"), true)
 		if query {
 			os.Exit(0)
 		}
-		fmt.Print("[num/q]: ")
+		fmt.Print(i18n.T("[num/q]: "))
 		scanner := bufio.NewScanner(os.Stdin)
 		if !scanner.Scan() {
-			QuietOut.Print("\nStandard input seems to be closed, please use '--non-interactive' option\n")
+			slog.Info(i18n.T("\nStandard input seems to be closed, please use '--non-interactive' option\n"))
 			os.Exit(1)
 		}
 		choice := strings.ToLower(strings.TrimSpace(scanner.Text()))
-		if choice == "q" {
+		if choice == "q" || choice == i18n.T("q") {
 			os.Exit(0)
 		}
 		if n, err := strconv.Atoi(choice); err == nil {
@@ -321,7 +326,7 @@ func main() {
 		if err != nil {
 			// NOTE: original version ignored all errors here.
 			// Snapshot number was usually left at 0 in those cases.
-			fmt.Printf("Snapshot creation failed: %v", err)
+			fmt.Print(i18n.Tf("Snapshot creation failed: %v", err))
 		}
 		preSnapshotNum = snapshotNum
 	}
@@ -335,7 +340,7 @@ func main() {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		sig := <-sigs
-		util.Debug.Printf("Signal received: %v", sig)
+		slog.Debug("Signal received", "signal", sig)
 		interrupted = true
 	}()
 
@@ -346,16 +351,16 @@ func main() {
 
 	if err != nil {
 		fmt.Println(err)
-		QuietOut.Print("\nMigration failed.\n\n")
+		slog.Info(i18n.T("\nMigration failed.\n\n"))
 	}
 
 	if fsInconsistent {
-		fmt.Println("The migration to the new service pack has failed. The system is most")
-		fmt.Println("likely in an inconsistent state.")
-		fmt.Print("\n")
-		fmt.Println("We strongly recommend to rollback to a snapshot created before the")
-		fmt.Println("migration was started (via selecting the snapshot in the boot menu")
-		fmt.Println("if you use snapper) or restore the system from a backup.")
+		fmt.Print(i18n.T("The migration to the new service pack has failed. The system is most\n" +
+			"likely in an inconsistent state.\n" +
+			"\n" +
+			"We strongly recommend to rollback to a snapshot created before the\n" +
+			"migration was started (via selecting the snapshot in the boot menu\n" +
+			"if you use snapper) or restore the system from a backup.\n"))
 		os.Exit(2)
 	}
 
@@ -363,7 +368,7 @@ func main() {
 		_, err := connect.CreatePostSnapshot(preSnapshotNum)
 		if err != nil {
 			// NOTE: original version ignored all errors here.
-			fmt.Printf("Snapshot creation failed: %v", err)
+			fmt.Print(i18n.Tf("Snapshot creation failed: %v", err))
 		}
 		// NOTE: original code contains disabled part of code titled:
 		// "Filesystem rollback - considered too dangerous" here
@@ -375,7 +380,7 @@ func main() {
 	if err == nil {
 		_, err := checkSystemProducts(api, false, autoImportRepoKeys, nonInteractive, opts)
 		if err != nil {
-			fmt.Printf("Can't determine the list of installed products after migration: %v\n", err)
+			fmt.Println(i18n.Tf("Can't determine the list of installed products after migration: %v", err))
 			// the system has been sucessfully upgraded, zypper reported no error so
 			// the only way to get here is a scc problem - it is better to just exit
 			os.Exit(1)
@@ -383,18 +388,20 @@ func main() {
 	}
 
 	if err != nil {
-		QuietOut.Print("\nPerforming repository rollback...\n")
+		slog.Info(i18n.T("\nPerforming repository rollback...\n"))
 
 		// restore repo configuration from backup file
 		if err := zypper.Restore(); err != nil {
 			// NOTE: original ignores failures of this command
-			fmt.Printf("Zypper restore failed: %v\n", err)
+			fmt.Println(i18n.Tf("Zypper restore failed: %v", err))
 		}
 
-		if err := connect.Rollback(api.GetConnection(), opts); err == nil {
-			QuietOut.Println("Rollback successful.")
+		out, err := connect.Rollback(api.GetConnection(), opts)
+		audit(opts, connect.AuditResultRecord(out))
+		if err == nil {
+			slog.Info(i18n.T("Rollback successful."))
 		} else {
-			fmt.Printf("Rollback failed: %v\n", err)
+			fmt.Println(i18n.Tf("Rollback failed: %v", err))
 		}
 		os.Exit(1)
 	}
@@ -418,15 +425,17 @@ func checkSystemProducts(api connect.WrappedAPI, rollbackOnFailure, autoImportRe
 		}
 		if err != nil {
 			releasePackageMissing = true
-			QuietOut.Printf("Can't install release package for registered product %s\n", p.Name)
-			QuietOut.Printf("%v\n", err)
+			slog.Info(i18n.Tf("Can't install release package for registered product %s", p.Name))
+			slog.Info(err.Error())
 		}
 	}
 
 	if releasePackageMissing && rollbackOnFailure {
 		// some release packages are missing and can't be installed
-		QuietOut.Println("Calling SUSEConnect rollback to make sure SCC is synchronized with the system state.")
-		if err := connect.Rollback(api.GetConnection(), opts); err != nil {
+		slog.Info(i18n.T("Calling SUSEConnect rollback to make sure SCC is synchronized with the system state."))
+		out, err := connect.Rollback(api.GetConnection(), opts)
+		audit(opts, connect.AuditResultRecord(out))
+		if err != nil {
 			return systemProducts, err
 		}
 		// re-read the list of products
@@ -440,11 +449,11 @@ func checkSystemProducts(api connect.WrappedAPI, rollbackOnFailure, autoImportRe
 }
 
 func printProducts(products []registration.Product) {
-	VerboseOut.Println("Installed products:")
+	util.Verbose(i18n.T("Installed products:"))
 	for _, p := range products {
-		VerboseOut.Printf("  %-25s %s\n", p.ToTriplet(), p.Summary)
+		util.Verbose(fmt.Sprintf("  %-25s %s", p.ToTriplet(), p.Summary))
 	}
-	VerboseOut.Print("\n")
+	util.Verbose("\n")
 }
 
 func printMigrations(migrations []connect.MigrationPath,
@@ -461,10 +470,10 @@ func printMigrations(migrations []connect.MigrationPath,
 				prefix = fmt.Sprintf("   %2d |", idx+1)
 			}
 			if !p.Available {
-				suffix = suffix + " (not available)"
+				suffix = suffix + i18n.T(" (not available)")
 			}
 			if installedIDs.Contains(p.ToTriplet()) {
-				suffix = suffix + " (already installed)"
+				suffix = suffix + i18n.T(" (already installed)")
 			}
 			if strings.Contains(strings.TrimSpace(p.ReleaseStage), "beta") {
 				suffix = suffix + " (BETA)"
@@ -554,27 +563,27 @@ func cleanupProductRepos(p registration.Product, force, autoImportRepoKeys bool)
 		if !found {
 			continue
 		}
-		QuietOut.Printf("Found obsolete repository %s", availableProduct.Repo)
+		slog.Info(i18n.Tf("Found obsolete repository %s", availableProduct.Repo))
 		if force {
-			QuietOut.Println("... disabling.")
+			slog.Info(i18n.T("... disabling."))
 			zypper.DisableRepo(availableProduct.Repo)
 		} else {
 			for {
-				fmt.Printf("\nDisable obsolete repository %s [y/n] (y): ", availableProduct.Repo)
+				fmt.Print(i18n.Tf("\nDisable obsolete repository %s [y/n] (y): ", availableProduct.Repo))
 				scanner := bufio.NewScanner(os.Stdin)
 				if !scanner.Scan() {
-					QuietOut.Print("\nStandard input seems to be closed, please use '--non-interactive' option\n")
+					slog.Info(i18n.T("\nStandard input seems to be closed, please use '--non-interactive' option\n"))
 					os.Exit(1)
 				}
 				choice := strings.ToLower(strings.TrimSpace(scanner.Text()))
 				if interrupted {
 					return ErrInterrupted
 				}
-				if choice == "n" {
+				if choice == "n" || choice == i18n.T("n") {
 					fmt.Print("\n")
 					break
-				} else if choice == "y" || choice == "" {
-					fmt.Println("... disabling.")
+				} else if choice == "y" || choice == i18n.T("y") || choice == "" {
+					fmt.Println(i18n.T("... disabling."))
 					zypper.DisableRepo(availableProduct.Repo)
 					break
 				}
@@ -596,24 +605,34 @@ func isSUSEService(service zypper.ZypperService, baseURL string) bool {
 // adds/removes services to match target state
 // disables obsolete repos
 // returns base product version string
-func migrateSystem(opts *connect.Options, migration connect.MigrationPath, baseURL string, forceDisableRepos, autoImportRepoKeys, insecure bool) (string, error) {
-	var baseProductVersion string
+func migrateSystem(opts *connect.Options, migration connect.MigrationPath, baseURL string, forceDisableRepos, autoImportRepoKeys, insecure bool) (baseProductVersion string, err error) {
+	record := connect.NewAuditRecord(connect.OperationMigrate, nil)
+	removed := []string{}
+	defer func() {
+		if len(removed) > 0 {
+			record.Details = map[string]string{"removed_services": strings.Join(removed, ",")}
+		}
+		record.SetError(err)
+		audit(opts, record)
+	}()
 
 	systemServices, _ := zypper.InstalledServices()
 	migratedServices := connect.NewStringSet()
 
 	for _, p := range migration {
-		msg := "Upgrading product " + p.FriendlyName
-		QuietOut.Println(msg)
+		msg := i18n.Tf("Upgrading product %s", p.FriendlyName)
+		slog.Info(msg)
 		conn := connect.NewWrappedAPI(opts)
 		meta, _, err := registration.Upgrade(conn.GetConnection(), p.Identifier, p.Version, p.Arch)
 		if err != nil {
+			record.AddProduct(p, connect.ActionUpgrade, "", err)
 			return baseProductVersion, fmt.Errorf("%s: %v", msg, err)
 		}
+		record.AddProduct(p, connect.ActionUpgrade, meta.Name, nil)
 
 		if meta.ObsoletedName != "" {
-			msg := "Removing service " + meta.ObsoletedName
-			VerboseOut.Println(msg)
+			msg := i18n.Tf("Removing service %s", meta.ObsoletedName)
+			util.Verbose(msg)
 			err = connect.MigrationRemoveService(meta.ObsoletedName)
 			if err != nil {
 				return baseProductVersion, err
@@ -624,8 +643,8 @@ func migrateSystem(opts *connect.Options, migration connect.MigrationPath, baseU
 			return baseProductVersion, err
 		}
 
-		msg = "Adding service " + meta.Name
-		VerboseOut.Println(msg)
+		msg = i18n.Tf("Adding service %s", meta.Name)
+		util.Verbose(msg)
 		err = connect.MigrationAddService(meta.URL, meta.Name, insecure)
 		if err != nil {
 			return baseProductVersion, err
@@ -644,17 +663,26 @@ func migrateSystem(opts *connect.Options, migration connect.MigrationPath, baseU
 	// remove SUSE services which don't have migration available (bsc#1161891)
 	for _, s := range systemServices {
 		if isSUSEService(s, baseURL) && !migratedServices.Contains(s.Name) {
-			msg := "Removing service " + s.Name + " (no migration available)"
-			VerboseOut.Println(msg)
+			msg := i18n.Tf("Removing service %s (no migration available)", s.Name)
+			util.Verbose(msg)
 			err := connect.MigrationRemoveService(s.Name)
 			if err != nil {
 				return baseProductVersion, fmt.Errorf("%s: %v", msg, err)
 			}
+			removed = append(removed, s.Name)
 		}
 	}
 	return baseProductVersion, nil
 }
 
+// audit records an operation in the audit log of SUSEConnect. Failing to do
+// so does not fail the migration.
+func audit(opts *connect.Options, record *connect.AuditRecord) {
+	if err := connect.Audit(opts, record); err != nil {
+		fmt.Println(i18n.Tf("Warning: could not write the audit log: %v", err))
+	}
+}
+
 // containsProduct returns true if given slice of products contains one with given name.
 func containsProduct(products []registration.Product, name string) bool {
 	for _, p := range products {
@@ -692,7 +720,7 @@ func applyMigration(opts *connect.Options, migration connect.MigrationPath, base
 
 	if err := zypper.Backup(); err != nil {
 		// NOTE: original ignores failures of this command
-		fmt.Printf("Zypper backup failed: %v\n", err)
+		fmt.Println(i18n.Tf("Zypper backup failed: %v", err))
 	}
 
 	if interrupted {
@@ -701,7 +729,7 @@ func applyMigration(opts *connect.Options, migration connect.MigrationPath, base
 
 	// Disable all old repos in case of Leap -> SLES migration (bsc#1184237)
 	if containsProduct(systemProducts, "Leap") && containsProduct(migration, "SLES") {
-		QuietOut.Println("Migration from Leap to SLES - disabling old repositories")
+		slog.Info(i18n.T("Migration from Leap to SLES - disabling old repositories"))
 		repos, err := zypper.Repositories()
 		if err != nil {
 			return fsInconsistent, err
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	_ "embed"
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"strings"
 
 	"github.com/SUSE/connect-ng/internal/connect"
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 )
 
@@ -143,10 +145,10 @@ func main() {
 	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
 		// print usage text
 		if err == flag.ErrHelp {
-			fmt.Print(searchPackagesUsageText)
+			fmt.Print(i18n.T(searchPackagesUsageText))
 			os.Exit(0)
 		}
-		fmt.Printf("Could not parse the options: %v\n", err)
+		fmt.Println(i18n.Tf("Could not parse the options: %v", err))
 	}
 	if bNoop || sNoop != "" {
 		os.Exit(0)
@@ -170,7 +172,7 @@ func main() {
 	}
 
 	if len(results) == 0 && !xmlout {
-		fmt.Print("No package found\n\n")
+		fmt.Print(i18n.T("No package found\n\n"))
 		os.Exit(0)
 	}
 
@@ -187,7 +189,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	fmt.Print("Following packages were found in following modules:\n\n")
+	fmt.Print(i18n.T("Following packages were found in following modules:\n\n"))
 
 	resultsTable := make([][]string, 0)
 	if details {
@@ -202,9 +204,9 @@ func main() {
 
 	resultsTable = uniqueTable(resultsTable)
 
-	header := []string{"Package", "Module or Repository", "SUSEConnect Activation Command"}
+	header := []string{i18n.T("Package"), i18n.T("Module or Repository"), i18n.T("SUSEConnect Activation Command")}
 	if groupByModule {
-		header = []string{"Module or Repository", "Package"}
+		header = []string{i18n.T("Module or Repository"), i18n.T("Package")}
 		modules := make(map[string][]string, 0)
 		for _, row := range resultsTable {
 			modules[row[1]] = append(modules[row[1]], row[0])
@@ -228,7 +230,7 @@ func main() {
 
 	printTable(header, resultsTable)
 
-	fmt.Print("\nTo activate the respective module or product, use SUSEConnect --product.\nUse SUSEConnect --help for more details.\n\n")
+	fmt.Print(i18n.T("\nTo activate the respective module or product, use SUSEConnect --product.\nUse SUSEConnect --help for more details.\n\n"))
 }
 
 func printTable(header []string, table [][]string) {
@@ -317,15 +319,15 @@ func searchInRepos(patterns []string, matchExact, caseSensitive bool) []searchRe
 			continue
 		}
 		reponame := repo.Name()
-		pkgStatus := "Available in repo " + reponame
+		pkgStatus := i18n.Tf("Available in repo %s", reponame)
 
 		if reponame == "@System" {
-			reponame = "Installed"
-			pkgStatus = "Installed"
+			reponame = i18n.T("Installed")
+			pkgStatus = i18n.T("Installed")
 		}
 		repoPackages, err := readRepoIndex(filepath.Join(reposPath, repo.Name(), "solv.idx"))
 		if err != nil {
-			fmt.Printf("Cannot read index for repository %v.\n", reponame)
+			fmt.Println(i18n.Tf("Cannot read index for repository %v.", reponame))
 		}
 		for _, p := range repoPackages {
 			p.Repo = reponame
@@ -348,7 +350,7 @@ func searchInModules(opts *connect.Options, patterns []string, matchExact, caseS
 	for _, query := range patterns {
 		found, err := connect.SearchPackage(api.GetConnection(), opts, query)
 		if err != nil {
-			fmt.Printf("Could not search for the package: %v", err)
+			fmt.Print(i18n.Tf("Could not search for the package: %v", err))
 		}
 		for _, pkg := range found {
 			if !packageWanted(pkg.Name, query, matchExact, caseSensitive) {
@@ -374,35 +376,35 @@ func searchInModules(opts *connect.Options, patterns []string, matchExact, caseS
 }
 
 func checkUnsupportedFlags() error {
-	// flag -> reason details
+	// flag -> reason
 	unsupported := map[string]string{
-		"match-words":         "by whole words",
-		"provides":            "by dependencies",
-		"recommends":          "by dependencies",
-		"requires":            "by dependencies",
-		"suggests":            "by dependencies",
-		"supplements":         "by dependencies",
-		"conflicts":           "by dependencies",
-		"obsoletes":           "by dependencies",
-		"f":                   "in file list",
-		"file-list":           "in file list",
-		"d":                   "in summaries and descriptions",
-		"search-descriptions": "in summaries and descriptions",
+		"match-words":         i18n.T("Extended search does not support search by whole words."),
+		"provides":            i18n.T("Extended search does not support search by dependencies."),
+		"recommends":          i18n.T("Extended search does not support search by dependencies."),
+		"requires":            i18n.T("Extended search does not support search by dependencies."),
+		"suggests":            i18n.T("Extended search does not support search by dependencies."),
+		"supplements":         i18n.T("Extended search does not support search by dependencies."),
+		"conflicts":           i18n.T("Extended search does not support search by dependencies."),
+		"obsoletes":           i18n.T("Extended search does not support search by dependencies."),
+		"f":                   i18n.T("Extended search does not support search in file list."),
+		"file-list":           i18n.T("Extended search does not support search in file list."),
+		"d":                   i18n.T("Extended search does not support search in summaries and descriptions."),
+		"search-descriptions": i18n.T("Extended search does not support search in summaries and descriptions."),
 	}
 	reasons := connect.NewStringSet()
 	flag.Visit(func(f *flag.Flag) {
-		if details, found := unsupported[f.Name]; found {
-			reasons.Add(fmt.Sprintf("Extended search does not support search %s.", details))
+		if reason, found := unsupported[f.Name]; found {
+			reasons.Add(reason)
 			return
 		}
 		// special case: --type <TYPE> argument
 		if f.Name == "type" && f.Value.String() != "package" {
-			reasons.Add("Extended package search can only search for the resolvable type 'package'.")
+			reasons.Add(i18n.T("Extended package search can only search for the resolvable type 'package'."))
 		}
 	})
 
 	if reasons.Len() != 0 {
-		return fmt.Errorf("Cannot perform extended package search:\n\n%v", strings.Join(reasons.Strings(), "\n"))
+		return errors.New(i18n.Tf("Cannot perform extended package search:\n\n%v", strings.Join(reasons.Strings(), "\n")))
 	}
 	return nil
 }
@@ -21,9 +21,14 @@ result, error := collectors.CollectInformation("x86_64", MandatoryCollectors)
 package collectors
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"maps"
+	"sync"
+	"time"
 
-	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/pkg/profiles"
 )
 
@@ -36,24 +41,139 @@ const (
 	ARCHITECTURE_Z      = "s390x"
 )
 
+const (
+	// CollectorTimeout is the time a single collector is given to run.
+	CollectorTimeout = 30 * time.Second
+
+	// CollectDeadline is the time all the collectors of a collection are
+	// given to run.
+	CollectDeadline = 60 * time.Second
+
+	maxParallelCollectors = 8
+)
+
+var (
+	// test method overwrites
+	localCollectorTimeout = CollectorTimeout
+	localCollectDeadline  = CollectDeadline
+)
+
+var (
+	ErrCollectorTimeout = errors.New("collector timed out")
+	ErrCollectDeadline  = errors.New("collection deadline exceeded")
+)
+
 var NoResult = Result{}
 
 type Collector interface {
 	run(arch string) (Result, error)
 }
 
-func CollectInformation(architecture string, collectors []Collector) (Result, error) {
-	obj := Result{}
+// NamedCollector is a collector along with the name it is configured with.
+type NamedCollector struct {
+	Name      string
+	Collector Collector
+}
 
+// CollectorRun is the outcome of a single collector of a collection.
+type CollectorRun struct {
+	Name     string
+	Duration time.Duration
+	Result   Result
+	Err      error
+}
+
+// CollectInformation runs the given collectors and returns their merged
+// results. It fails if one of them fails or times out.
+func CollectInformation(architecture string, collectors []Collector) (Result, error) {
+	named := make([]NamedCollector, 0, len(collectors))
 	for _, collector := range collectors {
-		res, err := collector.run(architecture)
-		if err != nil {
-			util.Debug.Printf("Collecting system information failed: %s", err)
+		named = append(named, NamedCollector{Name: fmt.Sprintf("%T", collector), Collector: collector})
+	}
+	result, runs := RunCollectors(architecture, named)
+	var errs []error
+	for _, run := range runs {
+		if run.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", run.Name, run.Err))
 		}
-		maps.Copy(obj, res)
 	}
+	if err := errors.Join(errs...); err != nil {
+		return NoResult, err
+	}
+	return result, nil
+}
+
+// RunCollectors runs the given collectors concurrently and returns their
+// merged results along with the outcome of each of them, in the order of the
+// collectors. A collector which runs longer than CollectorTimeout or is not
+// done by CollectDeadline does not contribute to the results. Such a
+// collector is abandoned, not stopped.
+func RunCollectors(architecture string, collectors []NamedCollector) (Result, []CollectorRun) {
+	beginCollection()
+	defer endCollection()
+
+	ctx, cancel := context.WithTimeout(context.Background(), localCollectDeadline)
+	defer cancel()
+
+	runs := make([]CollectorRun, len(collectors))
+	slots := make(chan struct{}, maxParallelCollectors)
+	var wg sync.WaitGroup
+	for i, collector := range collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				runs[i] = runCollector(ctx, architecture, collector)
+				<-slots
+			case <-ctx.Done():
+				runs[i] = CollectorRun{Name: collector.Name, Err: ErrCollectDeadline}
+			}
+		}()
+	}
+	wg.Wait()
 
-	return obj, nil
+	obj := Result{}
+	for _, run := range runs {
+		switch {
+		case errors.Is(run.Err, ErrCollectorTimeout), errors.Is(run.Err, ErrCollectDeadline):
+			slog.Warn("Collecting system information failed", "collector", run.Name, "error", run.Err)
+		case run.Err != nil:
+			slog.Debug("Collecting system information failed", "collector", run.Name, "error", run.Err)
+		}
+		maps.Copy(obj, run.Result)
+	}
+	return obj, runs
+}
+
+func runCollector(ctx context.Context, architecture string, collector NamedCollector) CollectorRun {
+	start := time.Now()
+	// Buffered for the abandoned collectors to be able to finish.
+	done := make(chan CollectorRun, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- CollectorRun{Err: fmt.Errorf("collector panicked: %v", r)}
+			}
+		}()
+		res, err := collector.Collector.run(architecture)
+		done <- CollectorRun{Result: res, Err: err}
+	}()
+
+	timer := time.NewTimer(localCollectorTimeout)
+	defer timer.Stop()
+
+	run := CollectorRun{}
+	select {
+	case run = <-done:
+	case <-timer.C:
+		run.Err = ErrCollectorTimeout
+	case <-ctx.Done():
+		run.Err = ErrCollectDeadline
+	}
+	run.Name = collector.Name
+	run.Duration = time.Since(start)
+	return run
 }
 
 // Extract a value from the already existing result set preserving the existing value type
@@ -2,9 +2,11 @@ package collectors
 
 import (
 	"errors"
-	"maps"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,10 +38,6 @@ func TestCollectInformationWithFailingCollector(t *testing.T) {
 	resultCollector1 := Result{"metric1": "value1"}
 	resultCollector3 := Result{"metric3": "value3"}
 
-	expectedResult := Result{}
-	maps.Copy(expectedResult, resultCollector1)
-	maps.Copy(expectedResult, resultCollector3)
-
 	collector1 := FakeCollector{}
 	collector2 := FakeCollector{}
 	collector3 := FakeCollector{}
@@ -57,8 +55,8 @@ func TestCollectInformationWithFailingCollector(t *testing.T) {
 
 	result, err := CollectInformation(ARCHITECTURE_X86_64, collectors)
 
-	assert.NoError(err)
-	assert.Equal(expectedResult, result)
+	assert.ErrorContains(err, "I am error")
+	assert.Equal(NoResult, result)
 }
 
 func TestFromResult(t *testing.T) {
@@ -69,3 +67,210 @@ func TestFromResult(t *testing.T) {
 	assert.Equal("some string", FromResult(result, "valueB", "default value"))
 	assert.Equal("default value", FromResult(result, "valueC", "default value"))
 }
+
+// funcCollector runs the function it wraps.
+type funcCollector func(arch string) (Result, error)
+
+func (f funcCollector) run(arch string) (Result, error) {
+	return f(arch)
+}
+
+func sleepingCollector(d time.Duration, key string) funcCollector {
+	return func(string) (Result, error) {
+		time.Sleep(d)
+		return Result{key: d.String()}, nil
+	}
+}
+
+func mockCollectorTimeouts(t *testing.T, timeout, deadline time.Duration) {
+	origTimeout, origDeadline := localCollectorTimeout, localCollectDeadline
+	t.Cleanup(func() { localCollectorTimeout, localCollectDeadline = origTimeout, origDeadline })
+	localCollectorTimeout, localCollectDeadline = timeout, deadline
+}
+
+func TestRunCollectorsConcurrently(t *testing.T) {
+	assert := assert.New(t)
+
+	collectors := []NamedCollector{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		collectors = append(collectors, NamedCollector{Name: name, Collector: sleepingCollector(200*time.Millisecond, name)})
+	}
+
+	start := time.Now()
+	result, runs := RunCollectors(ARCHITECTURE_X86_64, collectors)
+	assert.Less(time.Since(start), 700*time.Millisecond)
+	assert.Len(result, 4)
+	for i, run := range runs {
+		assert.Equal(collectors[i].Name, run.Name)
+		assert.NoError(run.Err)
+		assert.GreaterOrEqual(run.Duration, 200*time.Millisecond)
+	}
+}
+
+func TestRunCollectorsPartialResults(t *testing.T) {
+	assert := assert.New(t)
+	mockCollectorTimeouts(t, 100*time.Millisecond, time.Minute)
+
+	result, runs := RunCollectors(ARCHITECTURE_X86_64, []NamedCollector{
+		{Name: "fast", Collector: sleepingCollector(0, "fast")},
+		{Name: "hung", Collector: sleepingCollector(time.Minute, "hung")},
+		{Name: "failing", Collector: funcCollector(func(string) (Result, error) { return NoResult, errors.New("I am error") })},
+		{Name: "panicking", Collector: funcCollector(func(string) (Result, error) { panic("oops") })},
+	})
+
+	assert.Equal(Result{"fast": "0s"}, result)
+	assert.NoError(runs[0].Err)
+	assert.ErrorIs(runs[1].Err, ErrCollectorTimeout)
+	assert.EqualError(runs[2].Err, "I am error")
+	assert.ErrorContains(runs[3].Err, "panicked: oops")
+}
+
+func TestRunCollectorsDeadline(t *testing.T) {
+	assert := assert.New(t)
+	mockCollectorTimeouts(t, time.Minute, 100*time.Millisecond)
+
+	collectors := []NamedCollector{}
+	// Fill all the slots, one collector is not started.
+	for i := 0; i <= maxParallelCollectors; i++ {
+		collectors = append(collectors, NamedCollector{Name: fmt.Sprint(i), Collector: sleepingCollector(time.Minute, fmt.Sprint(i))})
+	}
+
+	start := time.Now()
+	result, runs := RunCollectors(ARCHITECTURE_X86_64, collectors)
+	assert.Less(time.Since(start), 5*time.Second)
+	assert.Empty(result)
+	notStarted := 0
+	for _, run := range runs {
+		assert.ErrorIs(run.Err, ErrCollectDeadline)
+		if run.Duration == 0 {
+			notStarted++
+		}
+	}
+	assert.Equal(1, notStarted)
+}
+
+func TestRunCollectorsMergeOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	result, _ := RunCollectors(ARCHITECTURE_X86_64, []NamedCollector{
+		{Name: "first", Collector: sleepingCollector(50*time.Millisecond, "key")},
+		{Name: "second", Collector: sleepingCollector(0, "key")},
+	})
+	// The results of later collectors win, whatever the order they finish.
+	assert.Equal(Result{"key": "0s"}, result)
+}
+
+func TestSharedSystemdClient(t *testing.T) {
+	assert := assert.New(t)
+
+	client := util.NewMockSystemdClient("")
+	closes := 0
+	client.CloseFunc = func() error {
+		closes++
+		return nil
+	}
+	connects := 0
+	orig := localNewSystemdClient
+	t.Cleanup(func() { localNewSystemdClient = orig })
+	localNewSystemdClient = func() (util.SystemdClient, error) {
+		connects++
+		return client, nil
+	}
+
+	useClient := funcCollector(func(string) (Result, error) {
+		c, release, err := useSystemdClient()
+		if err != nil {
+			return NoResult, err
+		}
+		defer release()
+		assert.Same(client, c)
+		return NoResult, nil
+	})
+	RunCollectors(ARCHITECTURE_X86_64, []NamedCollector{
+		{Name: "k8s", Collector: useClient},
+		{Name: "ha", Collector: useClient},
+	})
+	assert.Equal(1, connects)
+	assert.Equal(1, closes)
+
+	// Not connected if no collector needs it.
+	RunCollectors(ARCHITECTURE_X86_64, []NamedCollector{{Name: "fast", Collector: sleepingCollector(0, "fast")}})
+	assert.Equal(1, connects)
+	assert.Equal(1, closes)
+}
+
+func TestSharedSystemdClientFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	connects := 0
+	orig := localNewSystemdClient
+	t.Cleanup(func() { localNewSystemdClient = orig })
+	localNewSystemdClient = func() (util.SystemdClient, error) {
+		connects++
+		return nil, errors.New("no system bus")
+	}
+
+	useClient := funcCollector(func(string) (Result, error) {
+		_, _, err := useSystemdClient()
+		return NoResult, err
+	})
+	_, runs := RunCollectors(ARCHITECTURE_X86_64, []NamedCollector{
+		{Name: "k8s", Collector: useClient},
+		{Name: "ha", Collector: useClient},
+	})
+	assert.Equal(1, connects)
+	assert.EqualError(runs[0].Err, "no system bus")
+	assert.EqualError(runs[1].Err, "no system bus")
+}
+
+func TestSharedSystemdClientHanging(t *testing.T) {
+	assert := assert.New(t)
+
+	// Connecting never returns until the test releases it.
+	client := util.NewMockSystemdClient("")
+	closed := make(chan struct{})
+	client.CloseFunc = func() error {
+		close(closed)
+		return nil
+	}
+	connected := make(chan struct{})
+	origConnect, origTimeout := localNewSystemdClient, localSystemdConnectTimeout
+	t.Cleanup(func() { localNewSystemdClient, localSystemdConnectTimeout = origConnect, origTimeout })
+	localNewSystemdClient = func() (util.SystemdClient, error) {
+		<-connected
+		return client, nil
+	}
+	localSystemdConnectTimeout = 10 * time.Millisecond
+
+	useClient := funcCollector(func(string) (Result, error) {
+		_, release, err := useSystemdClient()
+		if err != nil {
+			return NoResult, err
+		}
+		defer release()
+		return NoResult, nil
+	})
+	done := make(chan []CollectorRun)
+	go func() {
+		_, runs := RunCollectors(ARCHITECTURE_X86_64, []NamedCollector{
+			{Name: "k8s", Collector: useClient},
+			{Name: "ha", Collector: useClient},
+		})
+		done <- runs
+	}()
+	select {
+	case runs := <-done:
+		assert.ErrorIs(runs[0].Err, errSystemdConnectTimeout)
+		assert.ErrorIs(runs[1].Err, errSystemdConnectTimeout)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the collection waited on the connection to systemd")
+	}
+
+	// The client is closed once connected.
+	close(connected)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the systemd client was not closed")
+	}
+}
@@ -41,6 +41,8 @@ type CollectorRegistryEntry struct {
 	Metadata         CollectorMetadata
 	Collector        Collector
 	CollectorFactory func(updateDataIDs bool) Collector
+	// Commands and files the collector gets its information from
+	Sources []string
 }
 
 // collectorsRegistry is the single source of truth for all collectors.
@@ -52,71 +54,88 @@ var collectorsRegistry = map[string]CollectorRegistryEntry{
 	"arch": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: Architecture{},
+		Sources:   []string{"uname -i", "uname -m"},
 	},
 	"hypervisor": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: Virtualization{},
+		Sources:   []string{"systemd-detect-virt -v", "/proc/cpuinfo"},
 	},
 	"cloud_provider": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: CloudProvider{},
+		Sources:   []string{"dmidecode -t system"},
 	},
 	"container_runtime": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: ContainerRuntime{},
+		Sources:   []string{"/proc/self/cgroup", "/proc/1/cmdline", "/run/systemd/container", "/proc/mounts", "/run/.containerenv", "/.dockerenv"},
 	},
 	"cpus": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: CPU{},
+		Sources:   []string{"lscpu -p=cpu,socket", "dmidecode -t processor", "read_values -s", "/proc/ppc64/lparcfg", "/sys/firmware/devicetree"},
 	},
 	"mem_total": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: Memory{},
+		Sources:   []string{"/proc/meminfo"},
 	},
 	"vendor": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: Vendor{},
+		Sources:   []string{"dmidecode -s system-manufacturer", "/proc/sysinfo", "/sys/firmware/devicetree"},
 	},
 	"uname": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: Uname{},
+		Sources:   []string{"uname -r -v"},
 	},
 	"hostname": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: Hostname{},
+		Sources:   []string{"gethostname(2)"},
 	},
 	"uuid": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: UUID{},
+		Sources:   []string{"/sys/hypervisor/uuid", "dmidecode -s system-uuid", "/etc/machine-id"},
 	},
 	"sap": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: SAP{},
+		Sources:   []string{"/usr/sap"},
 	},
 	"kubernetes_provider": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: K8S{},
+		Sources:   []string{"systemd (D-Bus)", "rke2 --version", "k3s --version"},
 	},
 	"ha_active": {
 		Metadata:  CollectorMetadata{DefaultEnabled: true, Mandatory: true, Type: SystemInfoCollector},
 		Collector: HA{},
+		Sources:   []string{"systemd (D-Bus)", "/usr/sbin/pacemakerd --version"},
 	},
 	// Optional collectors
 	"pci_data": {
 		Metadata:         CollectorMetadata{DefaultEnabled: true, Mandatory: false, Type: ProfileCollector},
 		CollectorFactory: func(updateDataIDs bool) Collector { return PCI{UpdateDataIDs: updateDataIDs} },
+		Sources:          []string{"lspci -s .0"},
 	},
 	"mcp_stats": {
 		Metadata:         CollectorMetadata{DefaultEnabled: true, Mandatory: false, Type: ProfileCollector},
 		CollectorFactory: func(updateDataIDs bool) Collector { return MCP{UpdateDataIDs: updateDataIDs} },
+		Sources:          []string{"/var/lib/suseconnect-mcp/suseconnectmcp"},
 	},
 	"mod_list": {
 		Metadata:         CollectorMetadata{DefaultEnabled: true, Mandatory: false, Type: ProfileCollector},
 		CollectorFactory: func(updateDataIDs bool) Collector { return LSMOD{UpdateDataIDs: updateDataIDs} },
+		Sources:          []string{"lsmod"},
 	},
 	"rpm_packages": {
 		Metadata:         CollectorMetadata{DefaultEnabled: false, Mandatory: false, Type: ProfileCollector},
 		CollectorFactory: func(updateDataIDs bool) Collector { return RPMPackages{UpdateDataIDs: updateDataIDs} },
+		Sources:          []string{"rpm -qa"},
 	},
 }
 
@@ -163,6 +182,29 @@ func NewCollectorOptions(collectors map[string]collectorsconfig.CollectorConfig)
 	}
 }
 
+// Where the enabled state of a collector comes from
+const (
+	SourceMandatory = "mandatory"
+	SourceConfig    = "config"
+	SourceDefault   = "default"
+)
+
+// EnabledSource tells whether the enabled state of a collector is forced
+// because it is mandatory, set in the configuration or its default.
+func (c *CollectorOptions) EnabledSource(collectorName string) string {
+	if IsMandatoryCollector(collectorName) {
+		return SourceMandatory
+	}
+
+	if config, ok := c.collectors[collectorName]; ok {
+		if _, validState := stateToEnabled[config.State]; validState {
+			return SourceConfig
+		}
+	}
+
+	return SourceDefault
+}
+
 func (c *CollectorOptions) IsCollectorEnabled(collectorName string) bool {
 	if IsMandatoryCollector(collectorName) {
 		return true
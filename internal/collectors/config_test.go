@@ -99,6 +99,9 @@ func TestCollectorRegistryMetadata(t *testing.T) {
 		if entry.Metadata.Type == ProfileCollector {
 			assert.NotNil(entry.CollectorFactory, "Profile collector %s should have a factory function", name)
 		}
+
+		// Verify all collectors tell where their information comes from
+		assert.NotEmpty(entry.Sources, "Collector %s should have sources", name)
 	}
 }
 
@@ -126,3 +129,22 @@ func TestStateValidation(t *testing.T) {
 	opts3 := NewCollectorOptions(invalidConfig)
 	assert.True(opts3.IsCollectorEnabled("pci_data")) // Falls back to default (enabled)
 }
+
+func TestCollectorOptionsEnabledSource(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := NewCollectorOptions(map[string]collectorsconfig.CollectorConfig{
+		"cpus":         {State: StateDisabled},
+		"pci_data":     {State: StateDisabled},
+		"rpm_packages": {State: "invalid_state"},
+		"rack":         {State: StateEnabled},
+	})
+
+	assert.Equal(SourceMandatory, opts.EnabledSource("cpus"))
+	assert.Equal(SourceConfig, opts.EnabledSource("pci_data"))
+	assert.Equal(SourceDefault, opts.EnabledSource("rpm_packages"))
+	assert.Equal(SourceDefault, opts.EnabledSource("mod_list"))
+	// External collectors
+	assert.Equal(SourceConfig, opts.EnabledSource("rack"))
+	assert.Equal(SourceDefault, opts.EnabledSource("owner"))
+}
@@ -0,0 +1,230 @@
+package collectors
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/profiles"
+	"gopkg.in/yaml.v3"
+)
+
+// ExternalCollectorsDir holds site specific collectors: executables printing
+// a JSON or YAML document, or static .json, .yaml or .yml files. The base
+// name of a file is the name of its collector, which is disabled unless
+// enabled in the collectors section of the configuration. The document is
+// added to the system information under that name, or sent as a profile if
+// the name is followed by a ".profile" suffix (e.g. cmdb.profile.json).
+// It lives next to the configuration file, as /etc/SUSEConnect is a file.
+const ExternalCollectorsDir = "/etc/SUSEConnect.collectors.d"
+
+const (
+	// ExternalCollectorTimeout is the time an executable collector is given
+	// to print its document.
+	ExternalCollectorTimeout = 10 * time.Second
+
+	// ExternalCollectorMaxSize is the maximum size of the document of an
+	// external collector.
+	ExternalCollectorMaxSize = 64 << 10
+
+	externalProfileSuffix = ".profile"
+)
+
+var (
+	// test method overwrites
+	localExternalCollectorTimeout = ExternalCollectorTimeout
+
+	externalCollectorName = regexp.MustCompile(`^[a-z0-9_]+$`)
+	errDocumentTooLarge   = fmt.Errorf("document larger than %d bytes", ExternalCollectorMaxSize)
+)
+
+// ExternalCollector runs a collector found in ExternalCollectorsDir.
+type ExternalCollector struct {
+	Name          string
+	Path          string
+	Type          CollectorType
+	Executable    bool
+	UpdateDataIDs bool
+}
+
+// DiscoverExternalCollectors returns the external collectors found in dir,
+// sorted by name. Files which cannot be used are skipped: hidden files,
+// files with an invalid name or the name of a built-in collector, and files
+// which can be modified by users other than their owner or not owned by
+// root or the current user.
+func DiscoverExternalCollectors(dir string) []ExternalCollector {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("Could not read the external collectors", "path", dir, "error", err)
+		}
+		return nil
+	}
+
+	found := map[string]ExternalCollector{}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		collector, ok := externalCollector(path)
+		if !ok {
+			continue
+		}
+		if previous, duplicate := found[collector.Name]; duplicate {
+			slog.Warn("Skipping duplicate external collector", "name", collector.Name, "path", path, "used", previous.Path)
+			continue
+		}
+		found[collector.Name] = collector
+	}
+
+	result := make([]ExternalCollector, 0, len(found))
+	for _, collector := range found {
+		result = append(result, collector)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func externalCollector(path string) (ExternalCollector, bool) {
+	base := filepath.Base(path)
+	if strings.HasPrefix(base, ".") {
+		return ExternalCollector{}, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		slog.Debug("Skipping external collector, not a regular file", "path", path)
+		return ExternalCollector{}, false
+	}
+
+	collector := ExternalCollector{Path: path, Type: SystemInfoCollector}
+	name := base
+	switch ext := filepath.Ext(base); ext {
+	case ".json", ".yaml", ".yml":
+		name = strings.TrimSuffix(base, ext)
+	default:
+		if info.Mode().Perm()&0111 == 0 {
+			slog.Debug("Skipping external collector, neither executable nor a JSON or YAML file", "path", path)
+			return ExternalCollector{}, false
+		}
+		collector.Executable = true
+	}
+	if trimmed, ok := strings.CutSuffix(name, externalProfileSuffix); ok {
+		name = trimmed
+		collector.Type = ProfileCollector
+	}
+	collector.Name = name
+
+	if !externalCollectorName.MatchString(name) {
+		slog.Warn("Skipping external collector with an invalid name, use lower case letters, digits and underscores", "path", path)
+		return ExternalCollector{}, false
+	}
+	if IsValidCollector(name) {
+		slog.Warn("Skipping external collector named after a built-in collector", "path", path)
+		return ExternalCollector{}, false
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		slog.Warn("Skipping external collector writable by group or others", "path", path)
+		return ExternalCollector{}, false
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Uid != 0 && int(stat.Uid) != os.Geteuid() {
+		slog.Warn("Skipping external collector not owned by root", "path", path)
+		return ExternalCollector{}, false
+	}
+	return collector, true
+}
+
+func (c ExternalCollector) run(arch string) (Result, error) {
+	slog.Debug("Running external collector", "name", c.Name, "path", c.Path)
+	data, err := c.document(arch)
+	if err != nil {
+		slog.Warn("External collector failed", "name", c.Name, "path", c.Path, "error", err)
+		return NoResult, err
+	}
+
+	var document any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		slog.Warn("External collector returned neither JSON nor YAML", "name", c.Name, "path", c.Path, "error", err)
+		return NoResult, err
+	}
+	if document == nil {
+		slog.Debug("External collector returned an empty document", "name", c.Name)
+		return NoResult, nil
+	}
+
+	if c.Type == ProfileCollector {
+		return profiles.BuildProfile(c.UpdateDataIDs, c.Name, strings.ReplaceAll(c.Name, "_", "-")+"-external-profile-id", document)
+	}
+	return Result{c.Name: document}, nil
+}
+
+// document returns the output of an executable collector, or the content of
+// a static one.
+func (c ExternalCollector) document(arch string) ([]byte, error) {
+	if !c.Executable {
+		file, err := os.Open(c.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return readLimited(file)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), localExternalCollectorTimeout)
+	defer cancel()
+
+	var stdout, stderr limitedBuffer
+	cmd := exec.CommandContext(ctx, c.Path)
+	cmd.Env = append(os.Environ(), "SUSECONNECT_ARCH="+arch)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait for children which inherited the output after a timeout.
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out after %s", localExternalCollectorTimeout)
+	}
+	if stdout.exceeded {
+		return nil, errDocumentTooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.buf.String()))
+	}
+	return stdout.buf.Bytes(), nil
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, ExternalCollectorMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > ExternalCollectorMaxSize {
+		return nil, errDocumentTooLarge
+	}
+	return data, nil
+}
+
+// limitedBuffer stops the outputs of an executable collector once
+// ExternalCollectorMaxSize is exceeded.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > ExternalCollectorMaxSize {
+		b.exceeded = true
+		return 0, errDocumentTooLarge
+	}
+	return b.buf.Write(p)
+}
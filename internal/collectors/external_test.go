@@ -0,0 +1,126 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/profiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeExternalCollector(t *testing.T, dir, name, content string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	// Do not depend on the umask.
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiscoverExternalCollectors(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	writeExternalCollector(t, dir, "rack.json", `{"rack": "B12"}`, 0644)
+	writeExternalCollector(t, dir, "owner.yaml", "owner: team-a\n", 0644)
+	writeExternalCollector(t, dir, "cmdb.profile", "#!/bin/sh\necho '{}'\n", 0755)
+	// Skipped
+	writeExternalCollector(t, dir, "rack.yml", "rack: B13\n", 0644)
+	writeExternalCollector(t, dir, "notes.txt", "not a collector", 0644)
+	writeExternalCollector(t, dir, ".hidden.json", "{}", 0644)
+	writeExternalCollector(t, dir, "Cost-Center.json", "{}", 0644)
+	writeExternalCollector(t, dir, "cpus.json", "{}", 0644)
+	writeExternalCollector(t, dir, "shared.json", "{}", 0666)
+	assert.NoError(os.Mkdir(filepath.Join(dir, "subdir"), 0755))
+
+	found := DiscoverExternalCollectors(dir)
+	assert.Equal([]ExternalCollector{
+		{Name: "cmdb", Path: filepath.Join(dir, "cmdb.profile"), Type: ProfileCollector, Executable: true},
+		{Name: "owner", Path: filepath.Join(dir, "owner.yaml"), Type: SystemInfoCollector},
+		{Name: "rack", Path: filepath.Join(dir, "rack.json"), Type: SystemInfoCollector},
+	}, found)
+
+	assert.Empty(DiscoverExternalCollectors(filepath.Join(dir, "missing")))
+}
+
+func TestExternalCollectorRunStatic(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	collector := ExternalCollector{Name: "rack", Path: writeExternalCollector(t, dir, "rack.yaml", "rack: B12\nunits: [3, 4]\n", 0644)}
+
+	result, err := collector.run(ARCHITECTURE_X86_64)
+	assert.NoError(err)
+	assert.Equal(Result{"rack": map[string]any{"rack": "B12", "units": []any{3, 4}}}, result)
+
+	collector.Path = writeExternalCollector(t, dir, "broken.json", `{"rack": `, 0644)
+	_, err = collector.run(ARCHITECTURE_X86_64)
+	assert.Error(err)
+
+	collector.Path = writeExternalCollector(t, dir, "large.json", `"`+strings.Repeat("x", ExternalCollectorMaxSize)+`"`, 0644)
+	_, err = collector.run(ARCHITECTURE_X86_64)
+	assert.ErrorIs(err, errDocumentTooLarge)
+}
+
+func TestExternalCollectorRunExecutable(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	path := writeExternalCollector(t, dir, "owner", "#!/bin/sh\necho \"{\\\"owner\\\": \\\"team-a\\\", \\\"arch\\\": \\\"$SUSECONNECT_ARCH\\\"}\"\n", 0755)
+	collector := ExternalCollector{Name: "owner", Path: path, Executable: true}
+
+	result, err := collector.run(ARCHITECTURE_ARM64)
+	assert.NoError(err)
+	assert.Equal(Result{"owner": map[string]any{"owner": "team-a", "arch": ARCHITECTURE_ARM64}}, result)
+
+	collector.Path = writeExternalCollector(t, dir, "failing", "#!/bin/sh\necho 'no CMDB entry' >&2\nexit 1\n", 0755)
+	_, err = collector.run(ARCHITECTURE_X86_64)
+	assert.ErrorContains(err, "no CMDB entry")
+
+	collector.Path = writeExternalCollector(t, dir, "large", "#!/bin/sh\nhead -c 100000 /dev/zero\n", 0755)
+	_, err = collector.run(ARCHITECTURE_X86_64)
+	assert.ErrorIs(err, errDocumentTooLarge)
+}
+
+func TestExternalCollectorTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	orig := localExternalCollectorTimeout
+	t.Cleanup(func() { localExternalCollectorTimeout = orig })
+	localExternalCollectorTimeout = 100 * time.Millisecond
+
+	path := writeExternalCollector(t, t.TempDir(), "slow", "#!/bin/sh\nsleep 10\necho '{}'\n", 0755)
+	collector := ExternalCollector{Name: "slow", Path: path, Executable: true}
+
+	start := time.Now()
+	_, err := collector.run(ARCHITECTURE_X86_64)
+	assert.ErrorContains(err, "timed out")
+	assert.Less(time.Since(start), 5*time.Second)
+}
+
+func TestExternalCollectorRunProfile(t *testing.T) {
+	assert := assert.New(t)
+
+	profiles.SetProfileFilePath(t.TempDir() + "/")
+	t.Cleanup(func() { profiles.SetProfileFilePath("/run/suseconnect/") })
+
+	path := writeExternalCollector(t, t.TempDir(), "cmdb.profile.json", `{"cost_center": "4711"}`, 0644)
+	collector := ExternalCollector{Name: "cmdb", Path: path, Type: ProfileCollector, UpdateDataIDs: true}
+
+	result, err := collector.run(ARCHITECTURE_X86_64)
+	assert.NoError(err)
+	profile := result["cmdb"].(profiles.Profile)
+	assert.Equal(map[string]any{"cost_center": "4711"}, profile.Data)
+	assert.NotEmpty(profile.Id)
+
+	// Unchanged profiles are sent without their data.
+	result, err = collector.run(ARCHITECTURE_X86_64)
+	assert.NoError(err)
+	assert.Equal(profiles.Profile{Id: profile.Id}, result["cmdb"])
+}
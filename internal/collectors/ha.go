@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"log/slog"
 	"strings"
 
 	"github.com/SUSE/connect-ng/internal/util"
@@ -41,20 +42,20 @@ func isPacemakerRunning(systemdClient util.SystemdClient) (bool, error) {
 	units, err := systemdClient.ListUnitsByPatterns("pacemaker.service")
 	if err != nil {
 		if !errors.Is(err, util.SystemdMethodNotAvailable) {
-			util.Debug.Printf("systemdClient.ListUnitsByPatterns() failed: %s\n", err)
+			slog.Debug("systemdClient.ListUnitsByPatterns() failed", "error", err)
 			return false, err
 		}
 		units, err = systemdClient.ListUnits()
 	}
 
-	util.Debug.Printf("pacemakerActive: units: %+v err: %+v\n", units, err)
+	slog.Debug("Listed pacemaker units", "units", len(units), "error", err)
 	if err != nil {
-		util.Debug.Printf("systemdClient.ListUnits() failed: %s\n", err)
+		slog.Debug("systemdClient.ListUnits() failed", "error", err)
 		return false, err
 	}
 
 	for _, unit := range units {
-		util.Debug.Printf("pacemakerActive: unit: %+v \n", unit)
+		slog.Debug("Checking pacemaker unit", "unit", unit.Name, "state", unit.SubState)
 		// is pacemaker running
 		if unit.Name.Match("pacemaker.service") && unit.SubState == "running" {
 			return true, nil
@@ -66,25 +67,25 @@ func isPacemakerRunning(systemdClient util.SystemdClient) (bool, error) {
 
 func isSystemHA(systemdClient util.SystemdClient) (Result, error) {
 	haActive, err := isPacemakerRunning(systemdClient)
-	util.Debug.Print("ha:haActive ", haActive)
+	slog.Debug("Checked high availability", "active", haActive)
 	if !haActive {
 		return Result{}, err
 	}
 	pacemakerVersion, err := getPacemakerVersion()
 	if pacemakerVersion != "" {
 		result := Result{"ha_active": pacemakerVersion}
-		util.Debug.Printf("ha:pacemakerVersion:Result: %+v \n", result)
+		slog.Debug("Detected pacemaker", "version", pacemakerVersion)
 		return result, err
 	}
 	return Result{}, err
 }
 
 func (HA) run(arch string) (Result, error) {
-	systemdClient, err := util.NewDbusSystemdClient()
+	systemdClient, release, err := useSystemdClient()
 	if err != nil {
 		return nil, err
 	}
-	defer systemdClient.Close()
+	defer release()
 	result, err := isSystemHA(systemdClient)
 	return result, err
 }
@@ -1,9 +1,8 @@
 package collectors
 
 import (
+	"log/slog"
 	"os"
-
-	"github.com/SUSE/connect-ng/internal/util"
 )
 
 type Hostname struct {
@@ -13,7 +12,7 @@ func (Hostname) run(arch string) (Result, error) {
 	name, err := os.Hostname()
 
 	if err != nil || name == "" {
-		util.Debug.Printf("Couldn't fetch hostname: %s", err)
+		slog.Debug("Could not fetch hostname", "error", err)
 		return NoResult, err
 	}
 
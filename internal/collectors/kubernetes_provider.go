@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"regexp"
 	"slices"
@@ -53,11 +54,11 @@ var (
 type K8S struct{}
 
 func (K8S) run(arch string) (Result, error) {
-	systemdClient, err := util.NewDbusSystemdClient()
+	systemdClient, release, err := useSystemdClient()
 	if err != nil {
 		return nil, err
 	}
-	defer systemdClient.Close()
+	defer release()
 
 	return generateKubernetesProvider(systemdClient)
 }
@@ -65,7 +66,7 @@ func (K8S) run(arch string) (Result, error) {
 func generateKubernetesProvider(systemdClient util.SystemdClient) (Result, error) {
 	provider, err := getKubernetesProviderData(systemdClient)
 	if err != nil {
-		util.Debug.Printf("Failed to get kubernetes provider: %s", err)
+		slog.Debug("Failed to get kubernetes provider", "error", err)
 		return Result{}, err
 	}
 
@@ -86,7 +87,7 @@ func getKubernetesProviderData(systemdClient util.SystemdClient) (map[string]any
 
 	// return nil without error if no services found
 	if len(kSvcs) == 0 {
-		util.Debug.Println("No kubernetes providers found")
+		slog.Debug("No kubernetes providers found")
 		return nil, nil
 	}
 
@@ -117,7 +118,7 @@ func getKubernetesServices(systemdClient util.SystemdClient) ([]*KubernetesServi
 	units, err := systemdClient.ListUnitsByPatterns(svcPatterns...)
 	if err != nil {
 		if !errors.Is(err, util.SystemdMethodNotAvailable) {
-			util.Debug.Printf("systemdClient.ListUnitsByPatterns() failed: %s\n", err)
+			slog.Debug("systemdClient.ListUnitsByPatterns() failed", "error", err)
 			return nil, err
 		}
 
@@ -125,7 +126,7 @@ func getKubernetesServices(systemdClient util.SystemdClient) ([]*KubernetesServi
 		filterNames = true
 		units, err = systemdClient.ListUnits()
 		if err != nil {
-			util.Debug.Printf("systemdClient.ListUnits() failed: %s\n", err)
+			slog.Debug("systemdClient.ListUnits() failed", "error", err)
 			return nil, err
 		}
 	}
@@ -133,26 +134,26 @@ func getKubernetesServices(systemdClient util.SystemdClient) ([]*KubernetesServi
 	for _, unit := range units {
 		// skip if names don't match, if not already filtered by ListUnitsByPatterns
 		if filterNames && !unit.Name.Match(svcPatterns...) {
-			util.Debug.Printf("Skipping non-matching unit %q\n", unit.Name)
+			slog.Debug("Skipping non-matching unit", "unit", unit.Name)
 			continue
 		}
 
 		state, err := systemdClient.GetUnitFileState(unit.Name)
 		if err != nil {
-			util.Debug.Printf("systemdClient.GetUnitFileState(%q) failed: %s\n", unit.Name, err)
+			slog.Debug("systemdClient.GetUnitFileState() failed", "unit", unit.Name, "error", err)
 			// skip on failure
 			continue
 		}
 
 		// skip if not enabled
 		if state != "enabled" {
-			util.Debug.Printf("Skipping disabled unit %q\n", unit.Name)
+			slog.Debug("Skipping disabled unit", "unit", unit.Name)
 			continue
 		}
 
 		ks, err := NewKubernetesService(systemdClient, unit)
 		if err != nil {
-			util.Debug.Printf("NewKubernetesService(%q) failed: %s\n", unit.Name, err)
+			slog.Debug("NewKubernetesService() failed", "unit", unit.Name, "error", err)
 			// skip on failure
 			continue
 		}
@@ -3,6 +3,7 @@ package collectors
 import (
 	"bufio"
 	"bytes"
+	"log/slog"
 	"sort"
 	"strings"
 
@@ -44,7 +45,7 @@ func getKernelMods(inputStream []byte) ([]string, error) {
 }
 
 func (lsmod LSMOD) run(arch string) (Result, error) {
-	util.Debug.Print("lsmod.UpdateDataIDs: ", lsmod.UpdateDataIDs)
+	slog.Debug("Collecting kernel modules", "update_data_ids", lsmod.UpdateDataIDs)
 	modInfo, err := util.Execute([]string{"lsmod"}, nil)
 	if err != nil {
 		return Result{}, err
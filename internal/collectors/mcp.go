@@ -2,9 +2,9 @@ package collectors
 
 import (
 	"encoding/json"
+	"log/slog"
 	"os"
 
-	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/pkg/profiles"
 )
 
@@ -33,7 +33,7 @@ func getSuseconnectmcpData(inputStream []byte) ([]Action, error) {
 }
 
 func (mcp MCP) run(arch string) (Result, error) {
-	util.Debug.Print("mcp.UpdateDataIDs: ", mcp.UpdateDataIDs)
+	slog.Debug("Collecting MCP data", "update_data_ids", mcp.UpdateDataIDs)
 	output, err := mcpOsReadfile(mcpPath)
 	if err != nil {
 		return Result{}, err
@@ -4,10 +4,9 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"log/slog"
 	"strconv"
 	"strings"
-
-	"github.com/SUSE/connect-ng/internal/util"
 )
 
 type Memory struct{}
@@ -18,7 +17,7 @@ type Memory struct{}
 func (Memory) run(arch string) (Result, error) {
 	fileContent, err := localOsReadfile("/proc/meminfo")
 	if err != nil {
-		util.Debug.Print("'/proc/meminfo' could not be read!")
+		slog.Debug("Could not read /proc/meminfo", "error", err)
 		return NoResult, err
 	}
 
@@ -39,17 +38,17 @@ func parseMeminfo(file io.Reader) int {
 
 		val, err := strconv.Atoi(fields[1])
 		if err != nil {
-			util.Debug.Printf("could not obtain memory information for this system: %v", err)
+			slog.Debug("Could not obtain memory information for this system", "error", err)
 			return 0
 		}
 		return val / 1024
 	}
 
 	if err := scanner.Err(); err != nil {
-		util.Debug.Printf("could not obtain memory information for this system: %v", err)
+		slog.Debug("Could not obtain memory information for this system", "error", err)
 		return 0
 	}
 
-	util.Debug.Print("could not obtain memory information for this system")
+	slog.Debug("Could not obtain memory information for this system")
 	return 0
 }
@@ -3,6 +3,7 @@ package collectors
 import (
 	"bufio"
 	"bytes"
+	"log/slog"
 
 	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/pkg/profiles"
@@ -30,7 +31,7 @@ func getPciData(inputStream []byte) ([]string, error) {
 }
 
 func (pci PCI) run(arch string) (Result, error) {
-	util.Debug.Print("pci.UpdateDataIDs: ", pci.UpdateDataIDs)
+	slog.Debug("Collecting PCI data", "update_data_ids", pci.UpdateDataIDs)
 	output, err := util.Execute([]string{"lspci", "-s", ".0"}, nil)
 	if err != nil {
 		return Result{}, err
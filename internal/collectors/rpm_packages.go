@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"log/slog"
 	"slices"
 	"strings"
 
@@ -22,7 +23,7 @@ const (
 )
 
 func (p RPMPackages) run(arch string) (Result, error) {
-	util.Debug.Print("RPMPackages.UpdateDataIds", p.UpdateDataIDs)
+	slog.Debug("Collecting RPM packages", "update_data_ids", p.UpdateDataIDs)
 
 	queryFormat := "%{VENDOR}\\t%{NAME}\\t%{VERSION}\\t%{RELEASE}\\t%{ARCH}\n"
 	pkgs, err := util.Execute([]string{"rpm", "-qa", "--queryformat", queryFormat}, nil)
@@ -0,0 +1,133 @@
+package collectors
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/util"
+)
+
+// systemdConnectTimeout is the time the collectors wait for the connection
+// to systemd.
+const systemdConnectTimeout = 10 * time.Second
+
+var errSystemdConnectTimeout = errors.New("connecting to systemd timed out")
+
+var (
+	// test method overwrites
+	localSystemdConnectTimeout = systemdConnectTimeout
+	localNewSystemdClient      = func() (util.SystemdClient, error) {
+		client, err := util.NewDbusSystemdClient()
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	}
+)
+
+// sharedSystemd is the systemd client used by the collectors of the
+// collections in progress. It is connected on first use, so that no D-Bus
+// connection is opened if no enabled collector needs it, and closed once the
+// last collection is over. This also unblocks the collectors abandoned after
+// a timeout while waiting on D-Bus.
+//
+// The connection is made outside of the lock, so a hanging D-Bus does not
+// block the end of the collections.
+var sharedSystemd struct {
+	sync.Mutex
+	collections int
+	conn        *systemdConnection
+}
+
+// systemdConnection is a connection to systemd made once for all its users.
+type systemdConnection struct {
+	once   sync.Once
+	done   chan struct{}
+	client util.SystemdClient
+	err    error
+}
+
+func (c *systemdConnection) connect() {
+	c.once.Do(func() {
+		c.client, c.err = localNewSystemdClient()
+		close(c.done)
+	})
+}
+
+// close closes the client, once connected if the connection is still in
+// progress.
+func (c *systemdConnection) close() {
+	closeClient := func() {
+		if c.client == nil {
+			return
+		}
+		if err := c.client.Close(); err != nil {
+			slog.Debug("Closing the systemd client failed", "error", err)
+		}
+	}
+	select {
+	case <-c.done:
+		closeClient()
+	default:
+		go func() {
+			<-c.done
+			closeClient()
+		}()
+	}
+}
+
+func beginCollection() {
+	sharedSystemd.Lock()
+	defer sharedSystemd.Unlock()
+	sharedSystemd.collections++
+}
+
+func endCollection() {
+	sharedSystemd.Lock()
+	sharedSystemd.collections--
+	conn := sharedSystemd.conn
+	if sharedSystemd.collections > 0 || conn == nil {
+		sharedSystemd.Unlock()
+		return
+	}
+	sharedSystemd.conn = nil
+	sharedSystemd.Unlock()
+
+	conn.close()
+}
+
+// useSystemdClient returns the systemd client shared by the collectors of
+// the collections in progress, and the function to call once done with it.
+// Outside of a collection, a client is connected for the caller only.
+func useSystemdClient() (util.SystemdClient, func(), error) {
+	sharedSystemd.Lock()
+	if sharedSystemd.collections == 0 {
+		sharedSystemd.Unlock()
+		client, err := localNewSystemdClient()
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() { client.Close() }, nil
+	}
+	if sharedSystemd.conn == nil {
+		sharedSystemd.conn = &systemdConnection{done: make(chan struct{})}
+	}
+	conn := sharedSystemd.conn
+	sharedSystemd.Unlock()
+
+	// A failed connection is not retried by the other collectors.
+	go conn.connect()
+	timer := time.NewTimer(localSystemdConnectTimeout)
+	defer timer.Stop()
+	select {
+	case <-conn.done:
+	case <-timer.C:
+		return nil, nil, errSystemdConnectTimeout
+	}
+	if conn.err != nil {
+		return nil, nil, conn.err
+	}
+	return conn.client, func() {}, nil
+}
@@ -299,3 +299,11 @@ func (env *systemdTestEnv) NewClient() util.SystemdClient {
 
 	return mockClient
 }
+
+// MockSystemdClient makes the collectors use the given systemd client, e.g.
+// on test systems without D-Bus, until the end of the given test.
+func MockSystemdClient(t *testing.T, client util.SystemdClient) {
+	orig := localNewSystemdClient
+	t.Cleanup(func() { localNewSystemdClient = orig })
+	localNewSystemdClient = func() (util.SystemdClient, error) { return client, nil }
+}
@@ -0,0 +1,291 @@
+package connect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+// CheckState is the outcome of a subscription check. The values are the exit
+// codes expected by Nagios compatible monitoring systems, in increasing order
+// of severity (except for CheckUnknown).
+type CheckState int
+
+const (
+	CheckOK CheckState = iota
+	CheckWarning
+	CheckCritical
+	CheckUnknown
+)
+
+const (
+	// DefaultWarnDays is the default number of days before the expiration of
+	// a subscription to report a warning.
+	DefaultWarnDays = 30
+
+	// DefaultCritDays is the default number of days before the expiration of
+	// a subscription to report it as critical.
+	DefaultCritDays = 7
+)
+
+// Sources of the checked subscriptions.
+const (
+	SourceActivation         = "activation"
+	SourceOfflineCertificate = "offline_certificate"
+)
+
+var (
+	ErrCheckThresholds = errors.New("--crit-days must not be negative nor greater than --warn-days")
+
+	// test method overwrites
+	localFetchSubscriptionInfo = registration.FetchSubscriptionInfo
+)
+
+func (s CheckState) String() string {
+	switch s {
+	case CheckOK:
+		return "OK"
+	case CheckWarning:
+		return "WARNING"
+	case CheckCritical:
+		return "CRITICAL"
+	}
+	return "UNKNOWN"
+}
+
+func (s CheckState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// worse returns the most severe of both states. UNKNOWN is only reported if
+// nothing is WARNING or CRITICAL.
+func (s CheckState) worse(other CheckState) CheckState {
+	if s == CheckUnknown {
+		if other == CheckOK {
+			return s
+		}
+		return other
+	}
+	if other == CheckUnknown {
+		if s == CheckOK {
+			return other
+		}
+		return s
+	}
+	return max(s, other)
+}
+
+// SubscriptionCheck is the evaluation of a single subscription.
+type SubscriptionCheck struct {
+	Product            string     `json:"product"`
+	Name               string     `json:"name,omitempty"`
+	Source             string     `json:"source"`
+	State              CheckState `json:"state"`
+	Message            string     `json:"message"`
+	ExpiresAt          string     `json:"expires_at,omitempty"`
+	DaysLeft           *int       `json:"days_left,omitempty"`
+	SubscriptionStatus string     `json:"subscription_status,omitempty"`
+	Notifications      string     `json:"notifications,omitempty"`
+}
+
+// SubscriptionCheckResult is the outcome of `SUSEConnect --check-subscriptions`.
+type SubscriptionCheckResult struct {
+	State         CheckState          `json:"state"`
+	Summary       string              `json:"summary"`
+	WarnDays      int                 `json:"warn_days"`
+	CritDays      int                 `json:"crit_days"`
+	Subscriptions []SubscriptionCheck `json:"subscriptions"`
+}
+
+// Text renders the result as expected by Nagios: a status line followed by a
+// line for each checked subscription.
+func (r *SubscriptionCheckResult) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "SUBSCRIPTIONS %s - %s\n", r.State, r.Summary)
+	for _, s := range r.Subscriptions {
+		fmt.Fprintf(&b, "%s: %s\n", s.State, s.Message)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// JSON renders the result as JSON.
+func (r *SubscriptionCheckResult) JSON() string {
+	out, _ := json.Marshal(r)
+	return string(out)
+}
+
+// CheckSubscriptions evaluates the expiration of every subscription
+// activated on this system, and of the offline registration certificate if
+// present. Subscriptions expiring within `warnDays` or `critDays` are
+// reported as WARNING or CRITICAL. Failures to fetch the data are reported as
+// UNKNOWN instead of returning an error.
+func CheckSubscriptions(api WrappedAPI, opts *Options, warnDays, critDays int) *SubscriptionCheckResult {
+	result := &SubscriptionCheckResult{
+		WarnDays:      warnDays,
+		CritDays:      critDays,
+		Subscriptions: []SubscriptionCheck{},
+	}
+	if critDays < 0 || critDays > warnDays {
+		result.State = CheckUnknown
+		result.Summary = ErrCheckThresholds.Error()
+		return result
+	}
+
+	now := localNow()
+	if api.IsRegistered() {
+		checks, err := checkActivations(api, now, warnDays, critDays)
+		if err != nil {
+			result.State = CheckUnknown
+			result.Summary = fmt.Sprintf("could not fetch activations: %v", err)
+			return result
+		}
+		result.Subscriptions = append(result.Subscriptions, checks...)
+	}
+
+	cert, err := StoredOfflineCertificate(opts)
+	if err != nil {
+		result.Subscriptions = append(result.Subscriptions, SubscriptionCheck{
+			Source:  SourceOfflineCertificate,
+			State:   CheckUnknown,
+			Message: fmt.Sprintf("could not read offline registration certificate: %v", err),
+		})
+	} else if cert != nil {
+		result.Subscriptions = append(result.Subscriptions, checkOfflineCertificate(cert, now, warnDays, critDays))
+	}
+
+	if len(result.Subscriptions) == 0 {
+		result.State = CheckUnknown
+		result.Summary = "system is not registered"
+		return result
+	}
+
+	counts := map[CheckState]int{}
+	for _, check := range result.Subscriptions {
+		result.State = result.State.worse(check.State)
+		counts[check.State]++
+	}
+	switch result.State {
+	case CheckOK:
+		result.Summary = fmt.Sprintf("%d subscription(s) valid for more than %d days", counts[CheckOK], warnDays)
+	case CheckWarning:
+		result.Summary = fmt.Sprintf("%d subscription(s) expire within %d days", counts[CheckWarning], warnDays)
+	case CheckCritical:
+		result.Summary = fmt.Sprintf("%d subscription(s) expired or expire within %d days", counts[CheckCritical], critDays)
+	default:
+		result.Summary = fmt.Sprintf("%d subscription(s) could not be checked", counts[CheckUnknown])
+	}
+	return result
+}
+
+func checkActivations(api WrappedAPI, now time.Time, warnDays, critDays int) ([]SubscriptionCheck, error) {
+	conn := api.GetConnection()
+	activations, err := localFetchActivations(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Several products are usually covered by the same subscription.
+	notifications := map[string]string{}
+	checks := []SubscriptionCheck{}
+	for _, activation := range activations {
+		check := SubscriptionCheck{
+			Product:            activation.ToTriplet(),
+			Name:               activation.Name,
+			Source:             SourceActivation,
+			SubscriptionStatus: activation.Status,
+		}
+		if activation.RegistrationCode == "" {
+			// Free products are not bound to any subscription.
+			check.State = CheckOK
+			check.Message = fmt.Sprintf("%s does not require a subscription", check.Product)
+			checks = append(checks, check)
+			continue
+		}
+
+		regcode := activation.RegistrationCode
+		if _, ok := notifications[regcode]; !ok {
+			info, err := localFetchSubscriptionInfo(conn, regcode)
+			if err != nil {
+				slog.Debug("Could not fetch subscription info", "product", check.Product, "error", err)
+				info = &registration.SubscriptionInfo{}
+			}
+			notifications[regcode] = info.Notifications
+		}
+		check.Notifications = notifications[regcode]
+
+		evaluateExpiry(&check, activation.ExpiresAt, now, warnDays, critDays)
+		if strings.EqualFold(activation.Status, "EXPIRED") {
+			check.State = CheckCritical
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+func checkOfflineCertificate(cert *OfflineCertificateStatus, now time.Time, warnDays, critDays int) SubscriptionCheck {
+	check := SubscriptionCheck{
+		Product:       "offline registration certificate",
+		Name:          cert.Subscription,
+		Source:        SourceOfflineCertificate,
+		Notifications: cert.Notifications,
+	}
+	if products, err := localInstalledProducts(); err == nil {
+		for _, product := range products {
+			if product.IsBase {
+				check.Product = product.ToTriplet()
+			}
+		}
+	}
+
+	switch cert.State {
+	case OfflineCertificateValid, OfflineCertificateExpired:
+		evaluateExpiry(&check, cert.ExpiresAt, now, warnDays, critDays)
+	case OfflineCertificateNotYetValid:
+		check.State = CheckWarning
+		check.Message = fmt.Sprintf("%s offline registration certificate not valid before %s",
+			check.Product, cert.StartsAt.Format("2006-01-02"))
+	default:
+		check.State = CheckCritical
+		check.Message = fmt.Sprintf("%s offline registration certificate is not usable: %s", check.Product, cert.State)
+	}
+	return check
+}
+
+// evaluateExpiry sets the state and message of the check according to the
+// number of days left until the given expiration date.
+func evaluateExpiry(check *SubscriptionCheck, expiresAt, now time.Time, warnDays, critDays int) {
+	label := check.Product
+	if check.Name != "" {
+		label = fmt.Sprintf("%s (%s)", check.Product, check.Name)
+	}
+	if expiresAt.IsZero() {
+		check.State = CheckOK
+		check.Message = fmt.Sprintf("%s does not expire", label)
+		return
+	}
+
+	check.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	days := int(expiresAt.Sub(now).Hours() / 24)
+	check.DaysLeft = &days
+	date := expiresAt.Format("2006-01-02")
+
+	switch {
+	case !expiresAt.After(now):
+		check.State = CheckCritical
+		check.Message = fmt.Sprintf("%s expired on %s", label, date)
+	case days <= critDays:
+		check.State = CheckCritical
+		check.Message = fmt.Sprintf("%s expires in %d days (%s)", label, days, date)
+	case days <= warnDays:
+		check.State = CheckWarning
+		check.Message = fmt.Sprintf("%s expires in %d days (%s)", label, days, date)
+	default:
+		check.State = CheckOK
+		check.Message = fmt.Sprintf("%s expires in %d days (%s)", label, days, date)
+	}
+}
@@ -0,0 +1,167 @@
+package connect
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/testutil"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+)
+
+var checkNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func mockCheckSubscriptions(t *testing.T, activations []*registration.Activation, fetchErr error) (*Options, *MockWrappedAPI) {
+	t.Helper()
+
+	origActivations, origInfo := localFetchActivations, localFetchSubscriptionInfo
+	origNow, origProducts := localNow, localInstalledProducts
+	t.Cleanup(func() {
+		localFetchActivations, localFetchSubscriptionInfo = origActivations, origInfo
+		localNow, localInstalledProducts = origNow, origProducts
+	})
+
+	localNow = func() time.Time { return checkNow }
+	localFetchActivations = func(connection.Connection) ([]*registration.Activation, error) {
+		return activations, fetchErr
+	}
+	localFetchSubscriptionInfo = func(_ connection.Connection, regcode string) (*registration.SubscriptionInfo, error) {
+		if regcode == "old-proxy" {
+			return nil, errors.New("not found")
+		}
+		return &registration.SubscriptionInfo{Notifications: "all"}, nil
+	}
+	localInstalledProducts = func() ([]registration.Product, error) {
+		return []registration.Product{{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true}}, nil
+	}
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(activations != nil || fetchErr != nil)
+	api.On("GetConnection").Return(conn)
+	return opts, api
+}
+
+func checkActivation(identifier, regcode string, expiresIn time.Duration) *registration.Activation {
+	activation := &registration.Activation{
+		Name:             identifier + " subscription",
+		RegistrationCode: regcode,
+		Status:           "ACTIVE",
+		Product:          &registration.Product{Identifier: identifier, Version: "15.6", Arch: "x86_64"},
+	}
+	if regcode != "" {
+		activation.ExpiresAt = checkNow.Add(expiresIn)
+	}
+	return activation
+}
+
+func TestCheckSubscriptionsStates(t *testing.T) {
+	assert := assert.New(t)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		expiresIn time.Duration
+		expected  CheckState
+	}{
+		{100 * day, CheckOK},
+		{20 * day, CheckWarning},
+		{5 * day, CheckCritical},
+		{-day, CheckCritical},
+	}
+	for _, test := range tests {
+		activations := []*registration.Activation{
+			checkActivation("SLES", "regcode", test.expiresIn),
+			checkActivation("sle-module-basesystem", "", 0),
+		}
+		opts, api := mockCheckSubscriptions(t, activations, nil)
+
+		result := CheckSubscriptions(api, opts, DefaultWarnDays, DefaultCritDays)
+		assert.Equal(test.expected, result.State, "expires in %v", test.expiresIn)
+		assert.Len(result.Subscriptions, 2)
+		assert.Equal(test.expected, result.Subscriptions[0].State)
+		assert.Equal(CheckOK, result.Subscriptions[1].State)
+	}
+}
+
+func TestCheckSubscriptionsOutput(t *testing.T) {
+	assert := assert.New(t)
+
+	activations := []*registration.Activation{
+		checkActivation("SLES", "regcode", 20*24*time.Hour),
+		checkActivation("sle-ha", "old-proxy", 200*24*time.Hour),
+	}
+	opts, api := mockCheckSubscriptions(t, activations, nil)
+
+	result := CheckSubscriptions(api, opts, DefaultWarnDays, DefaultCritDays)
+	assert.Equal(CheckWarning, result.State)
+	assert.Equal("SUBSCRIPTIONS WARNING - 1 subscription(s) expire within 30 days\n"+
+		"WARNING: SLES/15.6/x86_64 (SLES subscription) expires in 20 days (2025-06-21)\n"+
+		"OK: sle-ha/15.6/x86_64 (sle-ha subscription) expires in 200 days (2025-12-18)", result.Text())
+
+	decoded := map[string]any{}
+	assert.NoError(json.Unmarshal([]byte(result.JSON()), &decoded))
+	assert.Equal("WARNING", decoded["state"])
+	subscriptions := decoded["subscriptions"].([]any)
+	assert.Equal("all", subscriptions[0].(map[string]any)["notifications"])
+	assert.Equal(float64(20), subscriptions[0].(map[string]any)["days_left"])
+	assert.NotContains(subscriptions[1], "notifications")
+}
+
+func TestCheckSubscriptionsUnknown(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := mockCheckSubscriptions(t, nil, nil)
+	result := CheckSubscriptions(api, opts, DefaultWarnDays, DefaultCritDays)
+	assert.Equal(CheckUnknown, result.State)
+	assert.Equal("system is not registered", result.Summary)
+
+	opts, api = mockCheckSubscriptions(t, nil, errors.New("connection refused"))
+	result = CheckSubscriptions(api, opts, DefaultWarnDays, DefaultCritDays)
+	assert.Equal(CheckUnknown, result.State)
+	assert.Contains(result.Summary, "connection refused")
+
+	result = CheckSubscriptions(api, opts, 7, 30)
+	assert.Equal(CheckUnknown, result.State)
+	assert.Equal(ErrCheckThresholds.Error(), result.Summary)
+}
+
+func TestCheckSubscriptionsOfflineCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := mockCheckSubscriptions(t, nil, nil)
+	mockOfflineSystem(t, offlineCertificateUUID, checkNow)
+	path := filepath.Join(opts.FsRoot, OfflineCertificatePath)
+	assert.NoError(os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(os.WriteFile(path, testutil.Fixture(t, "pkg/registration/offline_certificate/valid.cert"), 0600))
+
+	result := CheckSubscriptions(api, opts, DefaultWarnDays, DefaultCritDays)
+	assert.Equal(CheckOK, result.State)
+	assert.Len(result.Subscriptions, 1)
+	check := result.Subscriptions[0]
+	assert.Equal(SourceOfflineCertificate, check.Source)
+	assert.Equal("SLES/15.6/x86_64", check.Product)
+	assert.Equal("Rancher PRIME unlimited", check.Name)
+	assert.Equal("silent", check.Notifications)
+
+	mockOfflineSystem(t, "another-uuid", checkNow)
+	result = CheckSubscriptions(api, opts, DefaultWarnDays, DefaultCritDays)
+	assert.Equal(CheckCritical, result.State)
+	assert.Contains(result.Subscriptions[0].Message, OfflineCertificateUUIDMismatch)
+}
+
+func TestCheckStateWorse(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(CheckWarning, CheckOK.worse(CheckWarning))
+	assert.Equal(CheckCritical, CheckCritical.worse(CheckWarning))
+	assert.Equal(CheckUnknown, CheckOK.worse(CheckUnknown))
+	assert.Equal(CheckCritical, CheckUnknown.worse(CheckCritical))
+	assert.Equal(CheckWarning, CheckWarning.worse(CheckUnknown))
+}
@@ -2,11 +2,12 @@ package connect
 
 import (
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/internal/zypper"
 	"github.com/SUSE/connect-ng/pkg/connection"
@@ -14,116 +15,85 @@ import (
 	"github.com/SUSE/connect-ng/pkg/search"
 )
 
-type RegisterOut struct {
-	Success  bool             `json:"success"`
-	Products []ProductService `json:"products"`
-	Message  string           `json:"message"`
-}
-
-type ProductService struct {
-	Product ProductOut `json:"product"`
-	Service ServiceOut `json:"service"`
-}
-
-type ProductOut struct {
-	Name       string `json:"name"`
-	Identifier string `json:"identifier"`
-	Version    string `json:"version"`
-	Arch       string `json:"arch"`
-}
-
-type ServiceOut struct {
-	Id   int    `json:"id"`
-	Name string `json:"name"`
-	Url  string `json:"url"`
-}
-
 var (
 	localAddService             = zypper.AddService
 	localInstallReleasePackage  = zypper.InstallReleasePackage
 	localRemoveOrRefreshService = removeOrRefreshService
+	localAcceptEULA             = AcceptEULA
 )
 
 // Register announces the system, activates the
-// product on SCC and adds the service to the system
-func Register(api WrappedAPI, opts *Options) error {
+// product on SCC and adds the service to the system. The returned result
+// contains every product which has been attempted, even on failure.
+func Register(api WrappedAPI, opts *Options) (*RegisterOut, error) {
 	conn := api.GetConnection()
-	out := &RegisterOut{}
+	out := newRegisterOut(OperationRegister)
 
 	if opts.OutputKind != JSON {
-		printInformation(fmt.Sprintf("Registering system to %s", opts.ServerName()), opts)
+		printInformation(i18n.Tf("Registering system to %s", opts.ServerName()), opts)
 	}
 
 	installReleasePkg := true
 	if opts.Product.IsEmpty() {
 		base, err := zypper.BaseProduct()
 		if err != nil {
-			return err
+			return out, out.Finish("", err)
 		}
 		opts.Product = base
 		installReleasePkg = false
 	}
 
 	if err := api.RegisterOrKeepAlive(opts); err != nil {
-		return err
+		return out, out.Finish("", err)
 	}
 
-	if service, err := registerProduct(conn, opts, opts.Product, installReleasePkg); err == nil {
-		out.Products = append(out.Products, ProductService{
-			Product: ProductOut{
-				Name:       opts.Product.Name,
-				Identifier: opts.Product.Identifier,
-				Version:    opts.Product.Version,
-				Arch:       opts.Product.Arch,
-			},
-			Service: ServiceOut{
-				Id:   service.ID,
-				Name: service.Name,
-				Url:  service.URL,
-			},
-		})
-	} else {
-		return err
+	if _, err := registerProduct(conn, opts, opts.Product, installReleasePkg, out); err != nil {
+		return out, out.Finish("", err)
 	}
 
 	if opts.Product.IsBase {
 		p, err := registration.FetchProductInfo(conn, opts.Product.Identifier, opts.Product.Version, opts.Product.Arch)
 		if err != nil {
-			return err
+			return out, out.Finish("", err)
 		}
-		// BUG: `out` is then re-written afterwards.
 		if err := registerProductTree(conn, opts, p, out); err != nil {
-			return err
+			return out, out.Finish("", err)
 		}
 	}
 
-	switch opts.OutputKind {
-	case Text:
-		util.Info.Print(util.Bold(util.GreenText("\nSuccessfully registered system")))
-	case JSON:
-		out.Success = true
-		out.Message = "Successfully registered system"
-		out, err := json.Marshal(out)
-		if err != nil {
-			return err
-		}
-		util.Info.Println(string(out))
+	if opts.OutputKind == Text {
+		util.Info.Print(util.Bold(util.GreenText("\n" + i18n.T("Successfully registered system"))))
 	}
-	return nil
+	return out, out.Finish(i18n.T("Successfully registered system"), nil)
 }
 
 // registerProduct activates the product, adds the service and installs the
-// release package
-func registerProduct(conn connection.Connection, opts *Options, product registration.Product, installReleasePkg bool) (registration.Service, error) {
-	opts.Print(fmt.Sprintf("\nActivating %s %s %s ...\n", product.Identifier, product.Version, product.Arch))
+// release package. The attempt is recorded into the given result.
+func registerProduct(conn connection.Connection, opts *Options, product registration.Product, installReleasePkg bool, out *RegisterOut) (service registration.Service, err error) {
+	entry := out.start(product, ActionActivate)
+	defer func() { entry.finish(err) }()
+
+	// Products which are already installed (e.g. the base product) had their
+	// license accepted when they were installed.
+	if installReleasePkg {
+		if err := localAcceptEULA(conn, opts, product); err != nil {
+			return registration.Service{}, err
+		}
+	}
+
+	opts.Print(i18n.Tf("\nActivating %s %s %s ...\n", product.Identifier, product.Version, product.Arch))
 
-	service, err := ActivateProduct(conn, opts.Token, product)
+	service, err = ActivateProduct(conn, opts.Token, product)
 	if err != nil {
 		return registration.Service{}, err
 	}
+	entry.setService(service.ID, service.Name, service.URL)
+	if entry.Product.Name == "" {
+		entry.Product.Name = service.Product.Name
+	}
 
 	if !opts.SkipServiceInstall {
-		opts.Print("-> Adding service to system ...")
+		opts.Print(i18n.T("-> Adding service to system ..."))
 
 		if err := localAddService(service.URL, service.Name, !opts.NoZypperRefresh, opts.Insecure); err != nil {
 			return registration.Service{}, err
@@ -131,11 +101,13 @@ func registerProduct(conn connection.Connection, opts *Options, product registra
 	}
 
 	if installReleasePkg && !opts.SkipServiceInstall {
-		opts.Print("-> Installing release package ...")
+		opts.Print(i18n.T("-> Installing release package ..."))
 
 		if err := localInstallReleasePackage(product.Identifier, opts.AutoImportRepoKeys, true); err != nil {
+			entry.ReleasePackage = ReleasePackageFailed
 			return registration.Service{}, err
 		}
+		entry.ReleasePackage = ReleasePackageInstalled
 	}
 	return service, nil
 }
@@ -144,66 +116,58 @@ func registerProduct(conn connection.Connection, opts *Options, product registra
 // tree and registers the recommended and available products
 func registerProductTree(conn connection.Connection, opts *Options, product *registration.Product, out *RegisterOut) error {
 	for _, extension := range product.Extensions {
-		if extension.Recommended && extension.Available {
-			if service, err := registerProduct(conn, opts, extension, true); err == nil {
-				out.Products = append(out.Products, ProductService{
-					Product: ProductOut{
-						Name:       product.Name,
-						Identifier: product.Identifier,
-						Version:    product.Version,
-						Arch:       product.Arch,
-					},
-					Service: ServiceOut{
-						Id:   service.ID,
-						Name: service.Name,
-						Url:  service.URL,
-					},
-				})
-			} else {
-				return err
-			}
-			if err := registerProductTree(conn, opts, &extension, out); err != nil {
-				return err
-			}
+		if !extension.Recommended {
+			continue
+		}
+		if !extension.Available {
+			slog.Debug("Skipping recommended extension which is not available", "product", extension.ToTriplet())
+			out.skip(extension)
+			continue
+		}
+		if _, err := registerProduct(conn, opts, extension, true, out); err != nil {
+			return err
+		}
+		if err := registerProductTree(conn, opts, &extension, out); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// Deregister the current system.
-func Deregister(api WrappedAPI, opts *Options) error {
+// Deregister the current system. The returned result contains every product
+// which has been attempted, even on failure.
+func Deregister(api WrappedAPI, opts *Options) (*RegisterOut, error) {
 	conn := api.GetConnection()
+	out := newRegisterOut(OperationDeregister)
 
 	if util.FileExists("/usr/sbin/registercloudguest") && opts.Product.IsEmpty() {
-		return fmt.Errorf("SUSE::Connect::UnsupportedOperation: " +
-			"De-registration via SUSEConnect is disabled by registercloudguest." +
-			"Use `registercloudguest --clean` instead.")
+		return out, out.Finish("", ErrCloudGuestDeregistration)
 	}
 
 	if !api.IsRegistered() {
-		return ErrSystemNotRegistered
+		return out, out.Finish("", ErrSystemNotRegistered)
 	}
 
-	// BUG: this is largely ignored for trees.
-	out := &RegisterOut{}
-
-	printInformation(fmt.Sprintf("Deregistering system to %s", opts.ServerName()), opts)
+	printInformation(i18n.Tf("Deregistering system to %s", opts.ServerName()), opts)
 	if !opts.Product.IsEmpty() {
-		return deregisterProduct(conn, opts.Product, opts, out)
+		if err := deregisterProduct(conn, opts.Product, opts, out); err != nil {
+			return out, out.Finish("", err)
+		}
+		return out, out.Finish(i18n.T("Successfully deregistered product"), nil)
 	}
 	base, err := zypper.BaseProduct()
 	if err != nil {
-		return err
+		return out, out.Finish("", err)
 	}
 
 	baseMeta, tree, err := registration.Upgrade(conn, base.Identifier, base.Version, base.Arch)
 	if err != nil && !strings.Contains(err.Error(), "expired") {
-		return err
+		return out, out.Finish("", err)
 	}
 
 	installed, err := zypper.InstalledProducts()
 	if err != nil {
-		return err
+		return out, out.Finish("", err)
 	}
 
 	installedIDs := NewStringSet()
@@ -212,59 +176,68 @@ func Deregister(api WrappedAPI, opts *Options) error {
 	}
 
 	dependencies := make([]registration.Product, 0)
-	for _, e := range tree.ToExtensionsList() {
-		if installedIDs.Contains(e.Identifier) {
-			dependencies = append(dependencies, e)
+	if tree != nil {
+		for _, e := range tree.ToExtensionsList() {
+			if installedIDs.Contains(e.Identifier) {
+				dependencies = append(dependencies, e)
+			}
 		}
 	}
 
 	// reverse loop over dependencies
 	for i := len(dependencies) - 1; i >= 0; i-- {
 		if err := deregisterProduct(conn, dependencies[i], opts, out); err != nil {
-			return err
+			return out, out.Finish("", err)
 		}
 	}
 
 	// remove potential docker and podman configurations for our registry
 	creds, err := cred.ReadCredentials(cred.SystemCredentialsPath(opts.FsRoot))
 	if err == nil {
-		util.Debug.Print("\nRemoving SUSE registry system authentication configuration ...")
+		slog.Debug("Removing SUSE registry system authentication configuration")
 		removeRegistryAuthentication(creds.Username, creds.Password)
 	}
 
-	api = NewWrappedAPI(opts)
-	if err := registration.Deregister(api.GetConnection()); err != nil {
-		return err
+	if err := deregisterBaseProduct(base, baseMeta, opts, out); err != nil {
+		return out, out.Finish("", err)
 	}
 
-	if !opts.SkipServiceInstall && baseMeta != nil {
-		if err := localRemoveOrRefreshService(baseMeta.Name, opts); err != nil {
-			return err
-		}
+	opts.Print(i18n.T("\nCleaning up ..."))
+	if err := cleanup(opts.BaseURL, opts.FsRoot, out); err != nil {
+		return out, out.Finish("", err)
 	}
 
-	opts.Print("\nCleaning up ...")
-	if err := Cleanup(opts.BaseURL, opts.FsRoot); err != nil {
-		return err
+	if opts.OutputKind == Text {
+		util.Info.Print(util.Bold(util.GreenText(i18n.T("Successfully deregistered system"))))
 	}
+	return out, out.Finish(i18n.T("Successfully deregistered system"), nil)
+}
 
-	switch opts.OutputKind {
-	case Text:
-		util.Info.Print(util.Bold(util.GreenText("Successfully deregistered system")))
-	case JSON:
-		out.Success = true
-		out.Message = "Successfully deregistered system"
-		out, err := json.Marshal(out)
-		if err != nil {
-			return err
-		}
-		util.Info.Println(string(out))
+// deregisterBaseProduct removes the whole system from the server and removes
+// the service of the base product.
+func deregisterBaseProduct(base registration.Product, baseMeta *registration.Metadata, opts *Options, out *RegisterOut) (err error) {
+	entry := out.start(base, ActionDeactivate)
+	defer func() { entry.finish(err) }()
+
+	api := NewWrappedAPI(opts)
+	if err := registration.Deregister(api.GetConnection()); err != nil {
+		return err
 	}
 
+	if baseMeta == nil {
+		return nil
+	}
+	entry.setService(baseMeta.ID, baseMeta.Name, baseMeta.URL)
+	if !opts.SkipServiceInstall {
+		return localRemoveOrRefreshService(baseMeta.Name, opts)
+	}
 	return nil
 }
 
-func deregisterProduct(conn connection.Connection, product registration.Product, opts *Options, out *RegisterOut) error {
+func deregisterProduct(conn connection.Connection, product registration.Product, opts *Options, out *RegisterOut) (err error) {
+	entry := out.start(product, ActionDeactivate)
+	defer func() { entry.finish(err) }()
+
 	base, err := zypper.BaseProduct()
 	if err != nil {
 		return err
@@ -273,11 +246,12 @@ func deregisterProduct(conn connection.Connection, product registration.Product,
 		return ErrBaseProductDeactivation
 	}
 
-	opts.Print(fmt.Sprintf("\nDeactivating %s %s %s ...\n", product.Identifier, product.Version, product.Arch))
+	opts.Print(i18n.Tf("\nDeactivating %s %s %s ...\n", product.Identifier, product.Version, product.Arch))
 	metadata, _, err := registration.Deactivate(conn, product.Identifier, product.Version, product.Arch)
 	if err != nil {
 		return err
 	}
+	entry.setService(metadata.ID, metadata.Name, metadata.URL)
 
 	if opts.SkipServiceInstall {
 		return nil
@@ -287,25 +261,13 @@ func deregisterProduct(conn connection.Connection, product registration.Product,
 		return err
 	}
 
-	switch opts.OutputKind {
-	case Text:
-		util.Info.Print("-> Removing release package ...")
-	case JSON:
-		out.Products = append(out.Products, ProductService{
-			Product: ProductOut{
-				Name:       product.Name,
-				Identifier: product.Identifier,
-				Version:    product.Version,
-				Arch:       product.Arch,
-			},
-			Service: ServiceOut{
-				Id:   metadata.ID,
-				Name: metadata.Name,
-				Url:  metadata.URL,
-			},
-		})
-	}
-	return zypper.RemoveReleasePackage(product.Identifier)
+	opts.Print(i18n.T("-> Removing release package ..."))
+	if err := zypper.RemoveReleasePackage(product.Identifier); err != nil {
+		entry.ReleasePackage = ReleasePackageFailed
+		return err
+	}
+	entry.ReleasePackage = ReleasePackageRemoved
+	return nil
 }
 
 // SMT provides one service for all products, removing it would remove all
@@ -313,11 +275,11 @@ func deregisterProduct(conn connection.Connection, product registration.Product,
 // deregistered product.
 func removeOrRefreshService(serviceName string, opts *Options) error {
 	if serviceName == "SMT_DUMMY_NOREMOVE_SERVICE" {
-		opts.Print("-> Refreshing service ...")
+		opts.Print(i18n.T("-> Refreshing service ..."))
 		zypper.RefreshAllServices()
 		return nil
 	}
-	opts.Print("-> Removing service from system ...")
+	opts.Print(i18n.T("-> Removing service from system ..."))
 	return zypper.RemoveService(serviceName)
 }
 
@@ -356,10 +318,10 @@ func printInformation(msg string, opts *Options) {
 	opts.Print(msg)
 
 	if opts.FsRoot != "" {
-		opts.Print("Rooted at: " + opts.FsRoot)
+		opts.Print(i18n.Tf("Rooted at: %s", opts.FsRoot))
 	}
 	if opts.Email != "" {
-		opts.Print("Using E-Mail: " + opts.Email)
+		opts.Print(i18n.Tf("Using E-Mail: %s", opts.Email))
 	}
 }
 
@@ -1,33 +1,81 @@
 package connect
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
 	"github.com/SUSE/connect-ng/internal/collectors"
 	collectorsconfig "github.com/SUSE/connect-ng/pkg/collectors"
 	"github.com/SUSE/connect-ng/pkg/profiles"
 )
 
+// test method overwrites
+var localExternalCollectorsDir = collectors.ExternalCollectorsDir
+
+// registeredCollector is a built-in or external collector along with what
+// is known about it before it runs.
+type registeredCollector struct {
+	collectors.NamedCollector
+	Mandatory bool
+	External  bool
+	Sources   []string
+}
+
+// registeredCollectors returns all the built-in and external collectors of a
+// given type, enabled or not, sorted by name.
+func registeredCollectors(collectorType collectors.CollectorType, updateCache bool) []registeredCollector {
+	var registered []registeredCollector
+
+	for name, entry := range collectors.GetCollectorsByType(collectorType) {
+		var c collectors.Collector
+		if entry.CollectorFactory != nil {
+			c = entry.CollectorFactory(updateCache)
+		} else {
+			c = entry.Collector
+		}
+		if c != nil {
+			registered = append(registered, registeredCollector{
+				NamedCollector: collectors.NamedCollector{Name: name, Collector: c},
+				Mandatory:      entry.Metadata.Mandatory,
+				Sources:        entry.Sources,
+			})
+		}
+	}
+
+	for _, external := range collectors.DiscoverExternalCollectors(localExternalCollectorsDir) {
+		if external.Type == collectorType {
+			external.UpdateDataIDs = updateCache
+			registered = append(registered, registeredCollector{
+				NamedCollector: collectors.NamedCollector{Name: external.Name, Collector: external},
+				External:       true,
+				Sources:        []string{external.Path},
+			})
+		}
+	}
+
+	// Results are merged in a stable order.
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name < registered[j].Name })
+	return registered
+}
+
 // instantiateCollectors builds a list of collector instances for a given type.
 // Handles both direct collectors and factory-based instantiation, with runtime parameters.
-func instantiateCollectors(collectorType collectors.CollectorType, updateCache bool, collectorOpts collectorsconfig.CollectorOptions) []collectors.Collector {
-	var usedCollectors []collectors.Collector
+// External collectors are appended if enabled in the configuration.
+func instantiateCollectors(collectorType collectors.CollectorType, updateCache bool, collectorOpts collectorsconfig.CollectorOptions) []collectors.NamedCollector {
+	var usedCollectors []collectors.NamedCollector
 
-	for name, entry := range collectors.GetCollectorsByType(collectorType) {
-		if collectorOpts.IsCollectorEnabled(name) {
-			var c collectors.Collector
-			if entry.CollectorFactory != nil {
-				c = entry.CollectorFactory(updateCache)
-			} else {
-				c = entry.Collector
-			}
-			if c != nil {
-				usedCollectors = append(usedCollectors, c)
-			}
+	for _, c := range registeredCollectors(collectorType, updateCache) {
+		if collectorOpts.IsCollectorEnabled(c.Name) {
+			usedCollectors = append(usedCollectors, c.NamedCollector)
 		}
 	}
 	return usedCollectors
 }
 
 // FetchSystemInformation collects basic system information from enabled collectors.
+// It fails if a mandatory collector fails or times out.
 //
 // Parameters:
 //   - arch: system architecture (empty string to auto-detect)
@@ -43,7 +91,24 @@ func FetchSystemInformation(arch string, collectorOpts collectorsconfig.Collecto
 	if err != nil {
 		return collectors.NoResult, err
 	}
-	return collectors.CollectInformation(arch, usedCollectors)
+	result, runs := collectors.RunCollectors(arch, usedCollectors)
+	if err := failedMandatoryCollectors(runs); err != nil {
+		return collectors.NoResult, err
+	}
+	return result, nil
+}
+
+// failedMandatoryCollectors returns the errors of the mandatory collectors
+// which failed or timed out. Optional collectors are only missing from the
+// results.
+func failedMandatoryCollectors(runs []collectors.CollectorRun) error {
+	var errs []error
+	for _, run := range runs {
+		if run.Err != nil && collectors.IsMandatoryCollector(run.Name) {
+			errs = append(errs, fmt.Errorf("%s collector: %w", run.Name, run.Err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // FetchSystemProfiles collects system profile data from enabled profile collectors.
@@ -63,10 +128,74 @@ func FetchSystemProfiles(arch string, updateCache bool, collectorOpts collectors
 	if err != nil {
 		return collectors.NoResult, err
 	}
-	profile, err := collectors.CollectInformation(arch, usedCollectors)
+	profile, runs := collectors.RunCollectors(arch, usedCollectors)
+	for _, run := range runs {
+		// An abandoned collector may still update the cache of a profile
+		// which is not sent.
+		if errors.Is(run.Err, collectors.ErrCollectorTimeout) || errors.Is(run.Err, collectors.ErrCollectDeadline) {
+			profiles.DeleteProfileCache("*-profile-id")
+			break
+		}
+	}
+	return profile, nil
+}
+
+// RenderSystemInformation returns the information reported to the server by
+// the enabled collectors (as shown by `--info`) rendered with the given
+// format. The text format is a single JSON document.
+func RenderSystemInformation(opts *Options, format OutputFormat) (string, error) {
+	sysInfo, err := FetchSystemInformation("", opts.Collectors)
 	if err != nil {
-		profiles.DeleteProfileCache("*-profile-id")
-		return collectors.NoResult, err
+		return "", err
+	}
+
+	profileInfo, err := FetchSystemProfiles("", false, opts.Collectors)
+	if err != nil {
+		return "", err
+	}
+
+	// The output is expected to be a single blob, so copy contents of
+	// profileInfo to sysInfo.
+	for key, value := range profileInfo {
+		sysInfo[key] = value
+	}
+
+	info := systemInformation(sysInfo)
+	return render(info, format, func() (string, error) {
+		out, err := json.Marshal(info)
+		return string(out), err
+	})
+}
+
+// systemInformation renders collected information as a table.
+type systemInformation collectors.Result
+
+func (info systemInformation) tableHeader() []string {
+	return []string{"KEY", "VALUE"}
+}
+
+func (info systemInformation) tableRows() [][]string {
+	keys := make([]string, 0, len(info))
+	for key := range info {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := [][]string{}
+	for _, key := range keys {
+		value := ""
+		switch v := info[key].(type) {
+		case string:
+			value = v
+		case nil:
+		default:
+			if out, err := json.Marshal(v); err == nil {
+				value = string(out)
+			} else {
+				value = fmt.Sprint(v)
+			}
+		}
+		rows = append(rows, []string{key, value})
 	}
-	return profile, err
+	return rows
 }
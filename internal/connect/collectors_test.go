@@ -1,17 +1,23 @@
 package connect
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/SUSE/connect-ng/internal/collectors"
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 	collectorsconfig "github.com/SUSE/connect-ng/pkg/collectors"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestMain(m *testing.M) {
+	// Messages are checked in English, whatever the locale of the user.
+	i18n.SetLanguage("C")
+
 	// Mock system commands for all tests
 	util.Execute = func(cmd []string, _ []int) ([]byte, error) {
 		if len(cmd) == 0 {
@@ -33,6 +39,15 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// mockCollectorsEnvironment provides the tools the mandatory collectors rely
+// on, whatever the test system.
+func mockCollectorsEnvironment(t *testing.T) {
+	origExists := util.ExecutableExists
+	t.Cleanup(func() { util.ExecutableExists = origExists })
+	util.ExecutableExists = func(string) bool { return true }
+	collectors.MockSystemdClient(t, util.NewMockSystemdClient(""))
+}
+
 func TestFetchSystemInformation(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -71,6 +86,7 @@ func TestFetchSystemInformation(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			assert := assert.New(t)
 
+			mockCollectorsEnvironment(t)
 			collectorOpts := collectors.NewCollectorOptions(map[string]collectorsconfig.CollectorConfig{})
 
 			if tt.mockArchFunc != nil {
@@ -125,6 +141,7 @@ func TestFetchSystemInformationCollectorConfig(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			assert := assert.New(t)
 
+			mockCollectorsEnvironment(t)
 			collectorOpts := collectors.NewCollectorOptions(tt.config)
 
 			result, err := FetchSystemInformation(collectors.ARCHITECTURE_X86_64, collectorOpts)
@@ -138,6 +155,42 @@ func TestFetchSystemInformationCollectorConfig(t *testing.T) {
 	}
 }
 
+func TestFetchExternalCollectors(t *testing.T) {
+	assert := assert.New(t)
+
+	mockCollectorsEnvironment(t)
+	dir := t.TempDir()
+	origDir := localExternalCollectorsDir
+	t.Cleanup(func() { localExternalCollectorsDir = origDir })
+	localExternalCollectorsDir = dir
+
+	for name, content := range map[string]string{
+		"rack.json":         `{"rack": "B12"}`,
+		"owner.yaml":        "owner: team-a\n",
+		"cmdb.profile.json": `{"cost_center": "4711"}`,
+	} {
+		assert.NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		assert.NoError(os.Chmod(filepath.Join(dir, name), 0644))
+	}
+
+	collectorOpts := collectors.NewCollectorOptions(map[string]collectorsconfig.CollectorConfig{
+		"rack":  {State: collectors.StateEnabled},
+		"owner": {State: collectors.StateDisabled},
+		"cmdb":  {State: collectors.StateEnabled},
+	})
+
+	result, err := FetchSystemInformation(collectors.ARCHITECTURE_X86_64, collectorOpts)
+	assert.NoError(err)
+	assert.Equal(map[string]any{"rack": "B12"}, result["rack"])
+	assert.NotContains(result, "owner")
+	assert.NotContains(result, "cmdb")
+
+	result, err = FetchSystemProfiles(collectors.ARCHITECTURE_X86_64, false, collectorOpts)
+	assert.NoError(err)
+	assert.Contains(result, "cmdb")
+	assert.NotContains(result, "rack")
+}
+
 func TestFetchSystemProfiles(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -255,3 +308,22 @@ func TestFetchSystemProfilesCollectorConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestFailedMandatoryCollectors(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(failedMandatoryCollectors([]collectors.CollectorRun{
+		{Name: "uuid"},
+		{Name: "rack", Err: errors.New("no CMDB entry")},
+		{Name: "pci_data", Err: collectors.ErrCollectorTimeout},
+	}))
+
+	err := failedMandatoryCollectors([]collectors.CollectorRun{
+		{Name: "uuid", Err: errors.New("no uuid")},
+		{Name: "cpus", Err: collectors.ErrCollectDeadline},
+		{Name: "rack", Err: errors.New("no CMDB entry")},
+	})
+	assert.ErrorContains(err, "uuid collector: no uuid")
+	assert.ErrorIs(err, collectors.ErrCollectDeadline)
+	assert.NotContains(err.Error(), "rack")
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/SUSE/connect-ng/internal/collectors"
 	"github.com/SUSE/connect-ng/internal/util"
@@ -52,9 +54,11 @@ type Options struct {
 	Token                      string
 	Product                    registration.Product
 	InstanceDataFile           string
-	Email                      string `json:"email" yaml:"email"`
-	AutoAgreeEULA              bool   `yaml:"auto_agree_with_licenses"`
-	EnableSystemUptimeTracking bool   `yaml:"enable_system_uptime_tracking"`
+	Email                      string        `json:"email" yaml:"email"`
+	AutoAgreeEULA              bool          `yaml:"auto_agree_with_licenses"`
+	EnableSystemUptimeTracking bool          `yaml:"enable_system_uptime_tracking"`
+	MetricsDir                 string        `yaml:"metrics_dir"`
+	KeepAliveInterval          time.Duration `yaml:"keepalive_interval"`
 	ServerType                 ServerType
 	NoZypperRefresh            bool `yaml:"no_zypper_refs"`
 	AutoImportRepoKeys         bool
@@ -62,6 +66,11 @@ type Options struct {
 	OutputKind                 OutputKind
 	Collectors                 collectorsconfig.CollectorOptions `yaml:"-"`
 	CollectorsRaw              map[string]map[string]string      `yaml:"collectors,omitempty"`
+
+	// NonInteractive is set by callers which cannot prompt the user (e.g.
+	// the MCP server, which talks JSON-RPC over the standard streams).
+	// License agreements which have not been accepted are then declined.
+	NonInteractive bool `yaml:"-"`
 }
 
 // Returns the Options suitable for targeting the SCC reference server without a
@@ -73,6 +82,7 @@ func DefaultOptions() *Options {
 		Insecure:                   defaultInsecure,
 		SkipServiceInstall:         defaultSkip,
 		EnableSystemUptimeTracking: defaultEnableSystemUptimeTracking,
+		MetricsDir:                 DefaultMetricsDir,
 		ServerType:                 UnknownProvider,
 		Collectors:                 collectors.NewCollectorOptions(map[string]collectorsconfig.CollectorConfig{}),
 	}
@@ -86,13 +96,14 @@ func (opts *Options) ServerName() string {
 	return "registration proxy " + opts.BaseURL
 }
 
-// Prints the given message on `Info` or `Debug` depending on the OutputKind.
+// Prints the given message on `Info`, or as debugging output for JSON so the
+// JSON document stays the only output.
 func (opts *Options) Print(msg string) {
 	switch opts.OutputKind {
 	case Text:
 		util.Info.Print(msg)
 	case JSON:
-		util.Debug.Print(msg)
+		slog.Debug(msg)
 	}
 }
 
@@ -108,7 +119,7 @@ func ReadFromConfiguration(path string) (*Options, error) {
 	// to write to a non existing, non default root (/) location later on.
 	cfg.Path = path
 
-	util.Debug.Printf("Reading configuration from: %s\n", path)
+	slog.Debug("Reading configuration", "path", path)
 	if f, err := os.Open(path); err == nil {
 		defer f.Close()
 
@@ -173,8 +184,14 @@ func (opts *Options) SaveAsConfiguration() error {
 	}
 	fmt.Fprintf(&buf, "auto_agree_with_licenses: %v\n", opts.AutoAgreeEULA)
 	fmt.Fprintf(&buf, "enable_system_uptime_tracking: %v\n", opts.EnableSystemUptimeTracking)
+	if opts.MetricsDir != "" && opts.MetricsDir != DefaultMetricsDir {
+		fmt.Fprintf(&buf, "metrics_dir: %s\n", opts.MetricsDir)
+	}
+	if opts.KeepAliveInterval > 0 {
+		fmt.Fprintf(&buf, "keepalive_interval: %s\n", opts.KeepAliveInterval)
+	}
 
-	util.Debug.Printf("Writing configuration to: %s\n", opts.Path)
+	slog.Debug("Writing configuration", "path", opts.Path)
 	return os.WriteFile(opts.Path, buf.Bytes(), 0644)
 }
 
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,6 +37,7 @@ func TestSaveLoad(t *testing.T) {
 	c1.Path = path
 	c1.AutoAgreeEULA = true
 	c1.ServerType = UnknownProvider
+	c1.KeepAliveInterval = 12 * time.Hour
 	require.NoError(t, c1.SaveAsConfiguration())
 
 	c2, err := ReadFromConfiguration(path)
@@ -77,6 +79,7 @@ namespace: test-namespace
 email: user@example.com
 auto_agree_with_licenses: true
 enable_system_uptime_tracking: true
+keepalive_interval: 12h
 no_zypper_refs: true`
 
 	opts := DefaultOptions()
@@ -91,6 +94,7 @@ no_zypper_refs: true`
 	assert.True(t, result.AutoAgreeEULA)
 	assert.True(t, result.EnableSystemUptimeTracking)
 	assert.True(t, result.NoZypperRefresh)
+	assert.Equal(t, 12*time.Hour, result.KeepAliveInterval)
 }
 
 func TestParseInvalidConfigurations(t *testing.T) {
@@ -11,6 +11,10 @@ var (
 	ErrPingFromUnregistered       = errors.New("Keepalive ping not allowed from unregistered system.")
 	ErrBaseProductDeactivation    = errors.New("Unable to deactivate base product")
 	ErrListExtensionsUnregistered = errors.New("System not registered")
+	ErrLocalCleanupIncomplete     = errors.New("Local de-registration incomplete")
+	ErrCloudGuestDeregistration   = errors.New("SUSE::Connect::UnsupportedOperation: " +
+		"De-registration via SUSEConnect is disabled by registercloudguest." +
+		"Use `registercloudguest --clean` instead.")
 )
 
 // APIError is returned on failed HTTP requests
@@ -0,0 +1,364 @@
+package connect
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/i18n"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+const (
+	// AcceptedEULAsPath is the file keeping track of the license agreements
+	// which have already been accepted on this system.
+	AcceptedEULAsPath = "/var/lib/suseconnect/accepted-eulas.json"
+
+	// Directory where release packages install the license of their product.
+	productLicenseDir = "/usr/share/licenses/product"
+
+	// Index file listing all the license translations available on the
+	// location pointed by the EULA URL of a product.
+	eulaIndexFile   = "directory.yast"
+	defaultEULAFile = "license.txt"
+)
+
+var (
+	ErrEULADeclined = errors.New("license agreement declined")
+
+	// test method overwrites
+	localFetchEULA           = fetchEULA
+	eulaInput      io.Reader = os.Stdin
+	eulaOutput     io.Writer = os.Stdout
+)
+
+// EULA holds the license agreement of a product as it is presented to the
+// user.
+type EULA struct {
+	Product registration.Product
+
+	// The license text.
+	Text string
+
+	// Where the license text was taken from (URL or local path).
+	Source string
+}
+
+// Checksum identifies the version of the license text.
+func (e EULA) Checksum() string {
+	sum := sha256.Sum256([]byte(e.Text))
+	return hex.EncodeToString(sum[:])
+}
+
+// acceptedEULA is an entry of the accepted EULAs file.
+type acceptedEULA struct {
+	Checksum   string    `json:"checksum"`
+	Source     string    `json:"source"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// AcceptEULA makes sure that the license agreement of the given product has
+// been accepted before it is activated. If the product has no EULA, the user
+// passed --auto-agree-with-licenses, or this exact license text has already
+// been accepted on this system, then nothing is asked. Otherwise the license
+// is shown and the user is prompted for acceptance, unless the options are
+// non-interactive in which case the license is declined. An error wrapping
+// `ErrEULADeclined` is returned if the license was not accepted.
+func AcceptEULA(conn connection.Connection, opts *Options, product registration.Product) error {
+	if opts.AutoAgreeEULA {
+		slog.Debug("Automatically agreeing to the license", "product", product.ToTriplet())
+		return nil
+	}
+
+	// Products given through the command line only contain the triplet, fetch
+	// the rest of the information from the server.
+	if product.EULAURL == "" {
+		info, err := registration.FetchProductInfo(conn, product.Identifier, product.Version, product.Arch)
+		if err != nil {
+			return err
+		}
+		product.EULAURL = info.EULAURL
+		if product.FriendlyName == "" {
+			product.FriendlyName = info.FriendlyName
+		}
+	}
+	if product.EULAURL == "" {
+		slog.Debug("No license agreement", "product", product.ToTriplet())
+		return nil
+	}
+
+	eula, err := localFetchEULA(opts, product)
+	if err != nil {
+		return err
+	}
+
+	recordPath := filepath.Join(opts.FsRoot, AcceptedEULAsPath)
+	accepted, err := readAcceptedEULAs(recordPath)
+	if err != nil {
+		slog.Debug("Could not read accepted licenses", "path", recordPath, "error", err)
+		accepted = map[string]acceptedEULA{}
+	}
+	if entry, ok := accepted[product.ToTriplet()]; ok && entry.Checksum == eula.Checksum() {
+		slog.Debug("License has already been accepted", "product", product.ToTriplet(), "accepted_at", entry.AcceptedAt)
+		return nil
+	}
+
+	if opts.NonInteractive {
+		return fmt.Errorf("%w: the license of %s has to be accepted before activating it", ErrEULADeclined, eulaProductName(product))
+	}
+
+	ok, err := promptEULA(opts, eula)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: aborting activation of %s", ErrEULADeclined, eulaProductName(product))
+	}
+
+	accepted[product.ToTriplet()] = acceptedEULA{
+		Checksum:   eula.Checksum(),
+		Source:     eula.Source,
+		AcceptedAt: time.Now().UTC(),
+	}
+	if err := writeAcceptedEULAs(recordPath, accepted); err != nil {
+		// Not being able to persist the acceptance only means that the user
+		// will be asked again next time.
+		slog.Debug("Could not record accepted license", "path", recordPath, "error", err)
+	}
+	return nil
+}
+
+func eulaProductName(product registration.Product) string {
+	if product.FriendlyName != "" {
+		return product.FriendlyName
+	}
+	return product.ToTriplet()
+}
+
+// fetchEULA downloads the license text from the product's EULA URL. If this
+// is not possible, then the license installed by the product's release
+// package is used instead.
+func fetchEULA(opts *Options, product registration.Product) (EULA, error) {
+	eula := EULA{Product: product}
+
+	text, source, err := downloadEULA(product.EULAURL, opts.Language, opts.Insecure)
+	if err == nil {
+		eula.Text, eula.Source = text, source
+		return eula, nil
+	}
+	slog.Debug("Could not download license", "product", product.ToTriplet(), "url", product.EULAURL, "error", err)
+
+	text, source, localErr := localEULA(opts.FsRoot, product.Identifier, opts.Language)
+	if localErr != nil {
+		slog.Debug("Could not read local license", "product", product.ToTriplet(), "error", localErr)
+		return eula, fmt.Errorf("could not retrieve the license of %s: %v", eulaProductName(product), err)
+	}
+	eula.Text, eula.Source = text, source
+	return eula, nil
+}
+
+// eulaLanguages returns the candidate suffixes to look for a translated license
+// given a locale (e.g. "de_DE.UTF-8" returns ["de_DE", "de"]). The untranslated
+// license is not part of the result.
+func eulaLanguages(lang string) []string {
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	if lang == "" || lang == "C" || lang == "POSIX" {
+		return []string{}
+	}
+	languages := []string{lang}
+	if short, _, found := strings.Cut(lang, "_"); found {
+		languages = append(languages, short)
+	}
+	return languages
+}
+
+// eulaFileCandidates returns the license file names to be tried in order of
+// preference for the given locale.
+func eulaFileCandidates(lang string) []string {
+	candidates := []string{}
+	for _, l := range eulaLanguages(lang) {
+		candidates = append(candidates, "license."+l+".txt")
+	}
+	return append(candidates, defaultEULAFile)
+}
+
+// parseEULAIndex returns the file names listed in a directory.yast index.
+func parseEULAIndex(index string) []string {
+	files := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(index))
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// pickEULAFile selects the best license file from the given index for the
+// given locale.
+func pickEULAFile(files []string, lang string) string {
+	available := NewStringSet(files...)
+	for _, candidate := range eulaFileCandidates(lang) {
+		if available.Contains(candidate) {
+			return candidate
+		}
+	}
+	return defaultEULAFile
+}
+
+func downloadEULA(eulaURL, lang string, insecure bool) (string, string, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           proxyWithAuth,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+		},
+		Timeout: connection.DefaultTimeout,
+	}
+	base := strings.TrimRight(eulaURL, "/")
+
+	file := defaultEULAFile
+	if index, err := httpGetText(client, base+"/"+eulaIndexFile); err == nil {
+		file = pickEULAFile(parseEULAIndex(index), lang)
+	} else {
+		slog.Debug("No license index found", "url", base, "error", err)
+	}
+
+	source := base + "/" + file
+	text, err := httpGetText(client, source)
+	if err != nil {
+		return "", "", err
+	}
+	return text, source, nil
+}
+
+func httpGetText(client *http.Client, url string) (string, error) {
+	slog.Debug("Downloading", "url", url)
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// localEULA reads the license installed by the release package of the given
+// product.
+func localEULA(fsRoot, identifier, lang string) (string, string, error) {
+	dir := filepath.Join(fsRoot, productLicenseDir, identifier)
+	for _, candidate := range eulaFileCandidates(lang) {
+		path := filepath.Join(dir, candidate)
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data), path, nil
+		}
+	}
+	return "", "", fmt.Errorf("no license found in %s", dir)
+}
+
+func readAcceptedEULAs(path string) (map[string]acceptedEULA, error) {
+	accepted := map[string]acceptedEULA{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return accepted, nil
+	} else if err != nil {
+		return accepted, err
+	}
+	if err := json.Unmarshal(data, &accepted); err != nil {
+		return map[string]acceptedEULA{}, err
+	}
+	return accepted, nil
+}
+
+func writeAcceptedEULAs(path string, accepted map[string]acceptedEULA) error {
+	data, err := json.MarshalIndent(accepted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// promptEULA shows the license and asks the user to accept it. Returns true if
+// the user accepted the license.
+func promptEULA(opts *Options, eula EULA) (bool, error) {
+	out := eulaOutput
+	// Keep stdout clean for the JSON document.
+	if opts.OutputKind == JSON {
+		out = os.Stderr
+	}
+
+	fmt.Fprint(out, i18n.Tf("\nIn order to install '%s', you must agree to the terms of the following license agreement:\n\n", eulaProductName(eula.Product)))
+	showEULA(out, eula.Text)
+
+	scanner := bufio.NewScanner(eulaInput)
+	for {
+		fmt.Fprint(out, i18n.T("\nDo you agree with the terms of the license? [y/n]: "))
+		if !scanner.Scan() {
+			fmt.Fprint(out, "\n")
+			return false, fmt.Errorf("%w: standard input seems to be closed, please use the '--auto-agree-with-licenses' option", ErrEULADeclined)
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "y", "yes", i18n.T("y"), i18n.T("yes"):
+			return true, nil
+		case "n", "no", i18n.T("n"), i18n.T("no"):
+			return false, nil
+		}
+	}
+}
+
+// showEULA displays the license through the user's pager when writing to a
+// terminal, or prints it directly otherwise.
+func showEULA(out io.Writer, text string) {
+	if out == io.Writer(os.Stdout) && isTerminal(os.Stdout) && isTerminal(os.Stdin) {
+		// $PAGER may carry arguments, e.g. "less -R".
+		pager := strings.Fields(os.Getenv("PAGER"))
+		if len(pager) == 0 {
+			pager = []string{"less"}
+		}
+		if path, err := exec.LookPath(pager[0]); err == nil {
+			cmd := exec.Command(path, pager[1:]...)
+			cmd.Stdin = strings.NewReader(text)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err == nil {
+				return
+			}
+			slog.Debug("Pager failed, printing the license instead", "pager", pager[0])
+		} else {
+			slog.Debug("Pager not found, printing the license instead", "pager", pager[0])
+		}
+	}
+	fmt.Fprintln(out, text)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
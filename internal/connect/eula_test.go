@@ -0,0 +1,177 @@
+package connect
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockEULA(t *testing.T, text string, input string) *bytes.Buffer {
+	t.Helper()
+
+	output := &bytes.Buffer{}
+	origFetch, origInput, origOutput := localFetchEULA, eulaInput, eulaOutput
+	t.Cleanup(func() {
+		localFetchEULA, eulaInput, eulaOutput = origFetch, origInput, origOutput
+	})
+
+	localFetchEULA = func(_ *Options, product registration.Product) (EULA, error) {
+		return EULA{Product: product, Text: text, Source: product.EULAURL}, nil
+	}
+	eulaInput = strings.NewReader(input)
+	eulaOutput = output
+	return output
+}
+
+func eulaProduct() registration.Product {
+	return registration.Product{
+		Identifier:   "sle-module-live-patching",
+		Version:      "15.6",
+		Arch:         "x86_64",
+		FriendlyName: "SUSE Linux Enterprise Live Patching 15 SP6 x86_64",
+		EULAURL:      "https://updates.suse.com/live-patching.license/",
+	}
+}
+
+func TestEULALanguages(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"de_DE", "de"}, eulaLanguages("de_DE.UTF-8"))
+	assert.Equal([]string{"sr_RS", "sr"}, eulaLanguages("sr_RS@latin"))
+	assert.Equal([]string{"fr"}, eulaLanguages("fr"))
+	assert.Empty(eulaLanguages("C.UTF-8"))
+	assert.Empty(eulaLanguages(""))
+}
+
+func TestPickEULAFile(t *testing.T) {
+	assert := assert.New(t)
+
+	index := parseEULAIndex("directory.yast\nlicense.de.txt\nlicense.pt_BR.txt\n\nlicense.txt\n")
+	assert.Equal("license.de.txt", pickEULAFile(index, "de_AT.UTF-8"))
+	assert.Equal("license.pt_BR.txt", pickEULAFile(index, "pt_BR.UTF-8"))
+	assert.Equal("license.txt", pickEULAFile(index, "ja_JP.UTF-8"))
+	assert.Equal("license.txt", pickEULAFile(index, ""))
+}
+
+func TestDownloadEULA(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/product.license/directory.yast":
+			w.Write([]byte("license.txt\nlicense.de.txt\n"))
+		case "/product.license/license.de.txt":
+			w.Write([]byte("Lizenz"))
+		case "/product.license/license.txt":
+			w.Write([]byte("License"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	text, source, err := downloadEULA(server.URL+"/product.license/", "de_DE.UTF-8", false)
+	assert.NoError(err)
+	assert.Equal("Lizenz", text)
+	assert.Equal(server.URL+"/product.license/license.de.txt", source)
+
+	text, _, err = downloadEULA(server.URL+"/product.license", "", false)
+	assert.NoError(err)
+	assert.Equal("License", text)
+
+	_, _, err = downloadEULA(server.URL+"/missing.license", "", false)
+	assert.ErrorContains(err, "404")
+}
+
+func TestLocalEULA(t *testing.T) {
+	assert := assert.New(t)
+
+	root := t.TempDir()
+	dir := filepath.Join(root, productLicenseDir, "SLES")
+	assert.NoError(os.MkdirAll(dir, 0755))
+	assert.NoError(os.WriteFile(filepath.Join(dir, "license.txt"), []byte("local license"), 0644))
+
+	text, source, err := localEULA(root, "SLES", "de_DE.UTF-8")
+	assert.NoError(err)
+	assert.Equal("local license", text)
+	assert.Equal(filepath.Join(dir, "license.txt"), source)
+
+	_, _, err = localEULA(root, "sle-ha", "")
+	assert.Error(err)
+}
+
+func TestAcceptEULAAutoAgree(t *testing.T) {
+	assert := assert.New(t)
+	output := mockEULA(t, "license text", "")
+
+	opts := &Options{AutoAgreeEULA: true, FsRoot: t.TempDir()}
+	assert.NoError(AcceptEULA(nil, opts, eulaProduct()))
+	assert.Empty(output.String())
+}
+
+func TestAcceptEULAAcceptedAndRecorded(t *testing.T) {
+	assert := assert.New(t)
+	output := mockEULA(t, "license text", "maybe\ny\n")
+
+	opts := &Options{FsRoot: t.TempDir()}
+	assert.NoError(AcceptEULA(nil, opts, eulaProduct()))
+	assert.Contains(output.String(), "license text")
+	assert.Equal(2, strings.Count(output.String(), "Do you agree"))
+
+	accepted, err := readAcceptedEULAs(filepath.Join(opts.FsRoot, AcceptedEULAsPath))
+	assert.NoError(err)
+	assert.Equal(EULA{Text: "license text"}.Checksum(), accepted[eulaProduct().ToTriplet()].Checksum)
+
+	// The same license is not asked again.
+	output = mockEULA(t, "license text", "")
+	assert.NoError(AcceptEULA(nil, opts, eulaProduct()))
+	assert.Empty(output.String())
+
+	// An updated license has to be accepted again.
+	output = mockEULA(t, "updated license text", "n\n")
+	err = AcceptEULA(nil, opts, eulaProduct())
+	assert.ErrorIs(err, ErrEULADeclined)
+	assert.Contains(output.String(), "updated license text")
+}
+
+func TestAcceptEULAClosedInput(t *testing.T) {
+	assert := assert.New(t)
+	mockEULA(t, "license text", "")
+
+	opts := &Options{FsRoot: t.TempDir()}
+	err := AcceptEULA(nil, opts, eulaProduct())
+	assert.ErrorIs(err, ErrEULADeclined)
+	assert.ErrorContains(err, "--auto-agree-with-licenses")
+}
+
+func TestAcceptEULANonInteractive(t *testing.T) {
+	assert := assert.New(t)
+	output := mockEULA(t, "license text", "y\n")
+
+	opts := &Options{FsRoot: t.TempDir(), NonInteractive: true}
+	err := AcceptEULA(nil, opts, eulaProduct())
+	assert.ErrorIs(err, ErrEULADeclined)
+	assert.Empty(output.String())
+}
+
+func TestAcceptEULAFetchesProductInfo(t *testing.T) {
+	assert := assert.New(t)
+	output := mockEULA(t, "license text", "")
+
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	conn.On("Do", mock.Anything).Return([]byte(`{"identifier": "sle-module-basesystem", "version": "15.6", "arch": "x86_64"}`), nil)
+
+	opts := &Options{FsRoot: t.TempDir()}
+	product := registration.Product{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"}
+	assert.NoError(AcceptEULA(conn, opts, product))
+	assert.Empty(output.String())
+}
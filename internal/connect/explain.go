@@ -0,0 +1,185 @@
+package connect
+
+import (
+	"bytes"
+	_ "embed"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/collectors"
+	"github.com/SUSE/connect-ng/pkg/profiles"
+)
+
+var (
+	//go:embed collectors-explain.tmpl
+	explainTemplate string
+)
+
+// How a profile is sent to the server
+const (
+	profileFull         = "full"
+	profileDeduplicated = "deduplicated"
+	profileNotSent      = "not_sent"
+)
+
+// collectorExplanation describes a collector as shown by `--info --explain`.
+type collectorExplanation struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Mandatory bool   `json:"mandatory"`
+	External  bool   `json:"external"`
+	Enabled   bool   `json:"enabled"`
+	// Why the collector is enabled or not: mandatory, config or default.
+	EnabledBy  string   `json:"enabled_by"`
+	ConfigPath string   `json:"config_path,omitempty"`
+	Sources    []string `json:"sources"`
+	Runtime    string   `json:"runtime,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	// Keys of the system information or profiles the collector contributed.
+	Keys []string `json:"keys"`
+	// Whether the profile of a profile collector is sent in full, only by
+	// its identifier as the profile cache tells it was sent already, or not
+	// at all.
+	Profile string `json:"profile,omitempty"`
+}
+
+// collectorsExplanation is rendered by `--info --explain`.
+type collectorsExplanation []collectorExplanation
+
+// ExplainSystemInformation runs the enabled collectors as `--info` does and
+// returns, for each built-in and external collector, why it is enabled or
+// not and what it contributed, rendered with the given format.
+func ExplainSystemInformation(opts *Options, format OutputFormat) (string, error) {
+	arch, err := collectors.DetectArchitecture()
+	if err != nil {
+		return "", err
+	}
+
+	explanation := collectorsExplanation{}
+	for _, collectorType := range []collectors.CollectorType{collectors.SystemInfoCollector, collectors.ProfileCollector} {
+		explanation = append(explanation, explainCollectors(arch, collectorType, opts)...)
+	}
+
+	return render(explanation, format, func() (string, error) {
+		t, err := template.New("collectors-explain").Funcs(templateFuncs).Parse(explainTemplate)
+		if err != nil {
+			return "", err
+		}
+		var output bytes.Buffer
+		err = t.Execute(&output, explanation)
+		return output.String(), err
+	})
+}
+
+func explainCollectors(arch string, collectorType collectors.CollectorType, opts *Options) collectorsExplanation {
+	registered := registeredCollectors(collectorType, false)
+
+	explanation := make(collectorsExplanation, 0, len(registered))
+	used := []collectors.NamedCollector{}
+	for _, c := range registered {
+		e := collectorExplanation{
+			Name:      c.Name,
+			Type:      "system_info",
+			Mandatory: c.Mandatory,
+			External:  c.External,
+			Enabled:   opts.Collectors.IsCollectorEnabled(c.Name),
+			EnabledBy: "unknown",
+			Sources:   c.Sources,
+			Keys:      []string{},
+		}
+		if collectorType == collectors.ProfileCollector {
+			e.Type = "profile"
+		}
+		if source, ok := opts.Collectors.(interface{ EnabledSource(string) string }); ok {
+			e.EnabledBy = source.EnabledSource(c.Name)
+		}
+		if e.EnabledBy == collectors.SourceConfig {
+			e.ConfigPath = opts.Path
+		}
+		if e.Enabled {
+			used = append(used, c.NamedCollector)
+		}
+		explanation = append(explanation, e)
+	}
+
+	_, runs := collectors.RunCollectors(arch, used)
+	for _, run := range runs {
+		for i := range explanation {
+			if explanation[i].Name == run.Name {
+				explanation[i].explainRun(run)
+			}
+		}
+	}
+	return explanation
+}
+
+func (e *collectorExplanation) explainRun(run collectors.CollectorRun) {
+	e.Runtime = run.Duration.Round(time.Microsecond).String()
+	if run.Err != nil {
+		e.Error = run.Err.Error()
+	}
+	for key := range run.Result {
+		e.Keys = append(e.Keys, key)
+	}
+	sort.Strings(e.Keys)
+
+	if e.Type != "profile" {
+		return
+	}
+	e.Profile = profileNotSent
+	for _, value := range run.Result {
+		if profile, ok := value.(profiles.Profile); ok {
+			if profile.Data == nil {
+				e.Profile = profileDeduplicated
+			} else {
+				e.Profile = profileFull
+			}
+		}
+	}
+}
+
+// TypeText is the type of the collector in the text output.
+func (e collectorExplanation) TypeText() string {
+	if e.Type == "profile" {
+		return "profile"
+	}
+	return "system information"
+}
+
+// ProfileText describes how the profile is sent in the text output.
+func (e collectorExplanation) ProfileText() string {
+	switch e.Profile {
+	case profileFull:
+		return "sent in full"
+	case profileDeduplicated:
+		return "deduplicated by the profile cache, only its identifier is sent"
+	case profileNotSent:
+		return "not sent"
+	}
+	return ""
+}
+
+func (explanation collectorsExplanation) tableHeader() []string {
+	return []string{"COLLECTOR", "TYPE", "MANDATORY", "ENABLED", "ENABLED BY", "SOURCES", "RUNTIME", "KEYS", "PROFILE", "ERROR"}
+}
+
+func (explanation collectorsExplanation) tableRows() [][]string {
+	rows := [][]string{}
+	for _, e := range explanation {
+		rows = append(rows, []string{
+			e.Name,
+			e.Type,
+			yesNo(e.Mandatory),
+			yesNo(e.Enabled),
+			e.EnabledBy,
+			strings.Join(e.Sources, ", "),
+			e.Runtime,
+			strings.Join(e.Keys, ", "),
+			e.Profile,
+			e.Error,
+		})
+	}
+	return rows
+}
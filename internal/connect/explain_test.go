@@ -0,0 +1,121 @@
+package connect
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SUSE/connect-ng/internal/collectors"
+	collectorsconfig "github.com/SUSE/connect-ng/pkg/collectors"
+	"github.com/SUSE/connect-ng/pkg/profiles"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockExplainEnvironment(t *testing.T) *Options {
+	dir := t.TempDir()
+	origDir, origArch := localExternalCollectorsDir, collectors.DetectArchitecture
+	t.Cleanup(func() {
+		localExternalCollectorsDir, collectors.DetectArchitecture = origDir, origArch
+		profiles.SetProfileFilePath("/run/suseconnect/")
+	})
+	localExternalCollectorsDir = dir
+	collectors.DetectArchitecture = func() (string, error) { return collectors.ARCHITECTURE_X86_64, nil }
+	profiles.SetProfileFilePath(t.TempDir())
+
+	rack := filepath.Join(dir, "rack.json")
+	if err := os.WriteFile(rack, []byte(`{"rack": "B12"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(rack, 0644)
+
+	opts := DefaultOptions()
+	opts.Collectors = collectors.NewCollectorOptions(map[string]collectorsconfig.CollectorConfig{
+		"rack":     {State: collectors.StateEnabled},
+		"mod_list": {State: collectors.StateDisabled},
+	})
+	return opts
+}
+
+func explainByName(t *testing.T, opts *Options) map[string]collectorExplanation {
+	out, err := ExplainSystemInformation(opts, OutputFormat{Kind: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var explanation []collectorExplanation
+	if err := json.Unmarshal([]byte(out), &explanation); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]collectorExplanation{}
+	for _, e := range explanation {
+		byName[e.Name] = e
+	}
+	return byName
+}
+
+func TestExplainSystemInformation(t *testing.T) {
+	assert := assert.New(t)
+	opts := mockExplainEnvironment(t)
+
+	explanation := explainByName(t, opts)
+
+	cpus := explanation["cpus"]
+	assert.Equal("system_info", cpus.Type)
+	assert.True(cpus.Mandatory)
+	assert.True(cpus.Enabled)
+	assert.Equal(collectors.SourceMandatory, cpus.EnabledBy)
+	assert.Contains(cpus.Sources, "lscpu -p=cpu,socket")
+	assert.NotEmpty(cpus.Runtime)
+	assert.Contains(cpus.Keys, "cpus")
+
+	modList := explanation["mod_list"]
+	assert.Equal("profile", modList.Type)
+	assert.False(modList.Enabled)
+	assert.Equal(collectors.SourceConfig, modList.EnabledBy)
+	assert.Equal(DefaultConfigPath, modList.ConfigPath)
+	assert.Empty(modList.Runtime)
+	assert.Empty(modList.Keys)
+
+	rpmPackages := explanation["rpm_packages"]
+	assert.False(rpmPackages.Enabled)
+	assert.Equal(collectors.SourceDefault, rpmPackages.EnabledBy)
+
+	rack := explanation["rack"]
+	assert.True(rack.External)
+	assert.True(rack.Enabled)
+	assert.Equal(collectors.SourceConfig, rack.EnabledBy)
+	assert.Equal([]string{filepath.Join(localExternalCollectorsDir, "rack.json")}, rack.Sources)
+	assert.Equal([]string{"rack"}, rack.Keys)
+
+	pci := explanation["pci_data"]
+	assert.Equal([]string{"pci_data"}, pci.Keys)
+	assert.Equal(profileFull, pci.Profile)
+	assert.Empty(pci.Error)
+}
+
+func TestExplainSystemInformationDeduplicatedProfile(t *testing.T) {
+	assert := assert.New(t)
+	opts := mockExplainEnvironment(t)
+
+	// The profile cache is updated as on registration.
+	_, err := FetchSystemProfiles(collectors.ARCHITECTURE_X86_64, true, opts.Collectors)
+	assert.NoError(err)
+
+	explanation := explainByName(t, opts)
+	assert.Equal(profileDeduplicated, explanation["pci_data"].Profile)
+}
+
+func TestExplainSystemInformationText(t *testing.T) {
+	assert := assert.New(t)
+	opts := mockExplainEnvironment(t)
+
+	out, err := ExplainSystemInformation(opts, OutputFormat{Kind: FormatText})
+	assert.NoError(err)
+	assert.Contains(out, "rack (system information, external, optional)\n  Enabled: yes (config, set in /etc/SUSEConnect)\n")
+	assert.Contains(out, "rpm_packages (profile, optional)\n  Enabled: no (default)\n  Sources: rpm -qa\n\n")
+	assert.Contains(out, "  Profile: sent in full\n")
+
+	out, err = ExplainSystemInformation(opts, OutputFormat{Kind: FormatTable})
+	assert.NoError(err)
+	assert.Contains(out, "COLLECTOR")
+}
@@ -3,7 +3,6 @@ package connect
 import (
 	"bytes"
 	_ "embed"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"text/template"
@@ -33,7 +32,13 @@ type extension struct {
 	Activated    bool         `json:"activated"`
 	Available    bool         `json:"available"`
 	Free         bool         `json:"free"`
+	Recommended  bool         `json:"recommended"`
+	ProductType  string       `json:"product_type,omitempty"`
+	ReleaseStage string       `json:"release_stage,omitempty"`
 	Extensions   []*extension `json:"extensions"`
+
+	// Type of the subscription the extension has been activated with.
+	SubscriptionType string `json:"subscription_type,omitempty"`
 }
 
 func extensionTree(as []*registration.Activation, p *registration.Product) *extension {
@@ -46,7 +51,7 @@ func extensionTree(as []*registration.Activation, p *registration.Product) *exte
 }
 
 func productToExtension(as []*registration.Activation, p *registration.Product) *extension {
-	return &extension{
+	ext := &extension{
 		Identifier:   p.Identifier,
 		Version:      p.Version,
 		Arch:         p.Arch,
@@ -54,11 +59,59 @@ func productToExtension(as []*registration.Activation, p *registration.Product)
 		Activated:    registration.ProductInActivations(p, as),
 		Available:    p.Available,
 		Free:         p.Free,
+		Recommended:  p.Recommended,
+		ProductType:  p.ProductType,
+		ReleaseStage: p.ReleaseStage,
 		Extensions:   []*extension{},
 	}
+	for _, activation := range as {
+		if activation.ToTriplet() == p.ToTriplet() {
+			ext.SubscriptionType = activation.Type
+		}
+	}
+	return ext
+}
+
+func (ext *extension) tableHeader() []string {
+	return []string{"EXTENSION", "NAME", "ACTIVATED", "AVAILABLE", "FREE", "SUBSCRIPTION TYPE", "RELEASE STAGE"}
+}
+
+// tableRows lists all the extensions of the tree, indenting their
+// identifiers to show the hierarchy.
+func (ext *extension) tableRows() [][]string {
+	return extensionRows(ext.Extensions, 0)
+}
+
+func extensionRows(exts []*extension, depth int) [][]string {
+	rows := [][]string{}
+	for _, ext := range exts {
+		code := strings.Repeat("  ", depth) + ext.Identifier + "/" + ext.Version + "/" + ext.Arch
+		rows = append(rows, []string{code, ext.FriendlyName, yesNo(ext.Activated), yesNo(ext.Available),
+			yesNo(ext.Free), ext.SubscriptionType, ext.ReleaseStage})
+		rows = append(rows, extensionRows(ext.Extensions, depth+1)...)
+	}
+	return rows
 }
 
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// RenderExtensionTree returns the extensions available for the base product
+// of this system, as JSON or as the text shown by `--list-extensions`.
 func RenderExtensionTree(api WrappedAPI, outputJson bool) (string, error) {
+	if outputJson {
+		return RenderExtensions(api, OutputFormat{Kind: FormatJSON})
+	}
+	return RenderExtensions(api, OutputFormat{Kind: FormatText})
+}
+
+// RenderExtensions returns the extensions available for the base product of
+// this system rendered with the given format.
+func RenderExtensions(api WrappedAPI, format OutputFormat) (string, error) {
 	conn := api.GetConnection()
 
 	// The system is registered remotely
@@ -83,12 +136,9 @@ func RenderExtensionTree(api WrappedAPI, outputJson bool) (string, error) {
 
 	tree := extensionTree(as, product)
 
-	if outputJson {
-		result, err := json.Marshal(tree)
-		return string(result), err
-	}
-
-	return renderText(tree, localRootWritable())
+	return render(tree, format, func() (string, error) {
+		return renderText(tree, localRootWritable())
+	})
 }
 
 func indentBlock(spaces int, block string) string {
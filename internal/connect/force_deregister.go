@@ -0,0 +1,193 @@
+package connect
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/i18n"
+	"github.com/SUSE/connect-ng/internal/util"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+var (
+	// test method overwrites
+	localInstalledProducts     = zypper.InstalledProducts
+	localInstalledServices     = zypper.InstalledServices
+	localRemoveService         = zypper.RemoveService
+	localRemoveReleasePackage  = zypper.RemoveReleasePackage
+	localRemoveRegistryAuth    = removeRegistryAuthentication
+	localDeregisterFromServer  = deregisterFromServer
+	localFetchExtensionsTree   = fetchExtensionsTree
+	localRemoveCredentialsFile = util.RemoveFile
+)
+
+// ForceDeregister removes the registration of the system locally, even if the
+// registration server does not know about this system anymore (e.g. it has
+// been deleted on SCC). Errors returned by the server are reported but do not
+// stop the process. Then SUSE services, release packages of extensions,
+// registry authentication and system credentials are removed from the system.
+// Every step is attempted, and an error wrapping `ErrLocalCleanupIncomplete`
+// listing whatever could not be cleaned is returned at the end.
+func ForceDeregister(api WrappedAPI, opts *Options) (*RegisterOut, error) {
+	out := newRegisterOut(OperationDeregister)
+	failures := []string{}
+
+	if util.FileExists("/usr/sbin/registercloudguest") {
+		return out, out.Finish("", ErrCloudGuestDeregistration)
+	}
+
+	printInformation(i18n.T("Forcing local de-registration of this system"), opts)
+
+	installed, err := localInstalledProducts()
+	if err != nil {
+		return out, out.Finish("", err)
+	}
+	base := registration.Product{}
+	for _, p := range installed {
+		if p.IsBase {
+			base = p
+			break
+		}
+	}
+
+	// Everything which requires the credentials has to happen before they are
+	// removed.
+	creds, credsErr := cred.ReadCredentials(cred.SystemCredentialsPath(opts.FsRoot))
+	if credsErr == nil {
+		if err := localDeregisterFromServer(api, base, out); err != nil {
+			opts.Print(i18n.Tf("-> Ignoring failed de-registration on %s: %v", opts.ServerName(), err))
+		}
+	} else {
+		slog.Debug("No system credentials found, skipping de-registration on the server", "error", credsErr)
+	}
+
+	extensions := dependencyOrder(api, base, installed)
+	for i := len(extensions) - 1; i >= 0; i-- {
+		if err := removeExtensionLocally(extensions[i], opts, out); err != nil {
+			failures = append(failures, fmt.Sprintf("release package of %s: %v", extensions[i].ToTriplet(), err))
+		}
+	}
+
+	opts.Print(i18n.T("\nRemoving services ..."))
+	failures = append(failures, removeServicesLocally(opts, out)...)
+
+	if credsErr == nil {
+		slog.Debug("Removing SUSE registry system authentication configuration")
+		if err := localRemoveRegistryAuth(creds.Username, creds.Password); err != nil {
+			failures = append(failures, fmt.Sprintf("registry authentication: %v", err))
+		}
+	}
+
+	credsPath := cred.SystemCredentialsPath(opts.FsRoot)
+	if err := localRemoveCredentialsFile(credsPath); err != nil {
+		failures = append(failures, fmt.Sprintf("system credentials %s: %v", credsPath, err))
+	}
+
+	if len(failures) > 0 {
+		err := fmt.Errorf("%w. The following could not be cleaned:\n  - %s",
+			ErrLocalCleanupIncomplete, strings.Join(failures, "\n  - "))
+		return out, out.Finish("", err)
+	}
+
+	if opts.OutputKind == Text {
+		util.Info.Print(util.Bold(util.GreenText("\n" + i18n.T("Successfully deregistered system locally"))))
+	}
+	return out, out.Finish(i18n.T("Successfully deregistered system locally"), nil)
+}
+
+// deregisterFromServer tries to remove the system from the registration
+// server. The result is recorded on the entry of the base product.
+func deregisterFromServer(api WrappedAPI, base registration.Product, out *RegisterOut) (err error) {
+	entry := out.start(base, ActionDeactivate)
+	defer func() { entry.finish(err) }()
+
+	return registration.Deregister(api.GetConnection())
+}
+
+// fetchExtensionsTree returns the extensions of the given base product as
+// known by the registration server.
+func fetchExtensionsTree(api WrappedAPI, base registration.Product) ([]registration.Product, error) {
+	_, tree, err := registration.Upgrade(api.GetConnection(), base.Identifier, base.Version, base.Arch)
+	if err != nil {
+		return nil, err
+	}
+	return tree.ToExtensionsList(), nil
+}
+
+// dependencyOrder returns the installed extensions of the given base product
+// so that every extension comes after the ones it depends on. The product
+// tree is fetched from the server if still possible, otherwise the order in
+// which the products are installed is used.
+func dependencyOrder(api WrappedAPI, base registration.Product, installed []registration.Product) []registration.Product {
+	installedIDs := NewStringSet()
+	for _, p := range installed {
+		if !p.IsBase {
+			installedIDs.Add(p.Identifier)
+		}
+	}
+
+	ordered := make([]registration.Product, 0)
+	tree, err := localFetchExtensionsTree(api, base)
+	if err != nil {
+		slog.Debug("Could not fetch product tree, using installed products order", "error", err)
+	}
+	for _, e := range tree {
+		if installedIDs.Contains(e.Identifier) {
+			ordered = append(ordered, e)
+			installedIDs.Delete(e.Identifier)
+		}
+	}
+
+	// Extensions unknown to the server (or all of them if the server could not
+	// be reached) come last.
+	for _, p := range installed {
+		if installedIDs.Contains(p.Identifier) {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered
+}
+
+func removeExtensionLocally(product registration.Product, opts *Options, out *RegisterOut) (err error) {
+	entry := out.start(product, ActionRemove)
+	defer func() { entry.finish(err) }()
+
+	if opts.SkipServiceInstall {
+		return nil
+	}
+
+	opts.Print(i18n.Tf("\nRemoving release package of %s ...", product.ToTriplet()))
+	if err := localRemoveReleasePackage(product.Identifier); err != nil {
+		entry.ReleasePackage = ReleasePackageFailed
+		return err
+	}
+	entry.ReleasePackage = ReleasePackageRemoved
+	return nil
+}
+
+// removeServicesLocally removes all the services pointing to the registration
+// server. Returns the description of every service which could not be
+// removed.
+func removeServicesLocally(opts *Options, out *RegisterOut) []string {
+	services, err := localInstalledServices()
+	if err != nil {
+		return []string{fmt.Sprintf("services: %v", err)}
+	}
+
+	failures := []string{}
+	for _, service := range services {
+		if !strings.Contains(service.URL, opts.BaseURL) {
+			slog.Debug("Service does not belong to the registration server", "server", opts.BaseURL, "service", service.Name, "url", service.URL)
+			continue
+		}
+		entry := out.start(registration.Product{}, ActionRemove)
+		entry.setService(0, service.Name, service.URL)
+		if err := entry.finish(localRemoveService(service.Name)); err != nil {
+			failures = append(failures, fmt.Sprintf("service %s: %v", service.Name, err))
+		}
+	}
+	return failures
+}
@@ -0,0 +1,140 @@
+package connect
+
+import (
+	"errors"
+	"testing"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+)
+
+type forceDeregisterMock struct {
+	removedPackages []string
+	removedServices []string
+	removedFiles    []string
+	registryRemoved bool
+}
+
+func mockForceDeregister(t *testing.T, serverErr, treeErr error) *forceDeregisterMock {
+	t.Helper()
+
+	m := &forceDeregisterMock{}
+	origProducts, origServices := localInstalledProducts, localInstalledServices
+	origRemoveService, origRemovePackage := localRemoveService, localRemoveReleasePackage
+	origRegistry, origServer := localRemoveRegistryAuth, localDeregisterFromServer
+	origTree, origRemoveFile := localFetchExtensionsTree, localRemoveCredentialsFile
+	t.Cleanup(func() {
+		localInstalledProducts, localInstalledServices = origProducts, origServices
+		localRemoveService, localRemoveReleasePackage = origRemoveService, origRemovePackage
+		localRemoveRegistryAuth, localDeregisterFromServer = origRegistry, origServer
+		localFetchExtensionsTree, localRemoveCredentialsFile = origTree, origRemoveFile
+	})
+
+	localInstalledProducts = func() ([]registration.Product, error) {
+		return []registration.Product{
+			{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true},
+			{Identifier: "sle-module-python3", Version: "15.6", Arch: "x86_64"},
+			{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"},
+			{Identifier: "sle-module-server-applications", Version: "15.6", Arch: "x86_64"},
+		}, nil
+	}
+	localFetchExtensionsTree = func(WrappedAPI, registration.Product) ([]registration.Product, error) {
+		if treeErr != nil {
+			return nil, treeErr
+		}
+		return []registration.Product{
+			{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"},
+			{Identifier: "sle-module-server-applications", Version: "15.6", Arch: "x86_64"},
+			{Identifier: "sle-module-python3", Version: "15.6", Arch: "x86_64"},
+		}, nil
+	}
+	localDeregisterFromServer = func(_ WrappedAPI, base registration.Product, out *RegisterOut) error {
+		return out.start(base, ActionDeactivate).finish(serverErr)
+	}
+	localInstalledServices = func() ([]zypper.ZypperService, error) {
+		return []zypper.ZypperService{
+			{Name: "SUSE_Linux_Enterprise_Server_15_SP6_x86_64", URL: "https://scc.suse.com/access/services/1"},
+			{Name: "Basesystem_Module_15_SP6_x86_64", URL: "https://scc.suse.com/access/services/2"},
+			{Name: "custom", URL: "https://example.com/service"},
+		}, nil
+	}
+	localRemoveService = func(name string) error {
+		m.removedServices = append(m.removedServices, name)
+		return nil
+	}
+	localRemoveReleasePackage = func(identifier string) error {
+		m.removedPackages = append(m.removedPackages, identifier)
+		return nil
+	}
+	localRemoveRegistryAuth = func(string, string) error {
+		m.registryRemoved = true
+		return nil
+	}
+	localRemoveCredentialsFile = func(path string) error {
+		m.removedFiles = append(m.removedFiles, path)
+		return nil
+	}
+	return m
+}
+
+func forceDeregisterOptions(t *testing.T) *Options {
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	path := cred.SystemCredentialsPath(opts.FsRoot)
+	if err := cred.CreateCredentials("SCC_login", "password", "", path); err != nil {
+		t.Fatalf("could not create credentials: %v", err)
+	}
+	return opts
+}
+
+func TestForceDeregisterServerUnknown(t *testing.T) {
+	assert := assert.New(t)
+
+	m := mockForceDeregister(t, errors.New("Invalid system credentials"), errors.New("Invalid system credentials"))
+	opts := forceDeregisterOptions(t)
+
+	out, err := ForceDeregister(nil, opts)
+	assert.NoError(err)
+	assert.True(out.Success)
+
+	// Without the product tree the installed order is reversed.
+	assert.Equal([]string{"sle-module-server-applications", "sle-module-basesystem", "sle-module-python3"}, m.removedPackages)
+	assert.Equal([]string{"SUSE_Linux_Enterprise_Server_15_SP6_x86_64", "Basesystem_Module_15_SP6_x86_64"}, m.removedServices)
+	assert.True(m.registryRemoved)
+	assert.Equal([]string{cred.SystemCredentialsPath(opts.FsRoot)}, m.removedFiles)
+
+	assert.Equal(ActionDeactivate, out.Products[0].Action)
+	assert.False(out.Products[0].Success)
+}
+
+func TestForceDeregisterDependencyOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	m := mockForceDeregister(t, nil, nil)
+	_, err := ForceDeregister(nil, forceDeregisterOptions(t))
+	assert.NoError(err)
+	assert.Equal([]string{"sle-module-python3", "sle-module-server-applications", "sle-module-basesystem"}, m.removedPackages)
+}
+
+func TestForceDeregisterIncomplete(t *testing.T) {
+	assert := assert.New(t)
+
+	m := mockForceDeregister(t, nil, nil)
+	localRemoveService = func(name string) error {
+		if name == "Basesystem_Module_15_SP6_x86_64" {
+			return errors.New("zypper is locked")
+		}
+		return nil
+	}
+
+	out, err := ForceDeregister(nil, forceDeregisterOptions(t))
+	assert.ErrorIs(err, ErrLocalCleanupIncomplete)
+	assert.ErrorContains(err, "service Basesystem_Module_15_SP6_x86_64: zypper is locked")
+	assert.Equal(ErrorCodeCleanupIncomplete, out.ErrorCode)
+
+	// Everything else is still cleaned up.
+	assert.True(m.registryRemoved)
+	assert.Len(m.removedFiles, 1)
+}
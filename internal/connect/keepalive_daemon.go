@@ -0,0 +1,243 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/util"
+)
+
+const (
+	// DefaultKeepAliveInterval is the time between two keepalive calls of the
+	// daemon, unless `keepalive_interval` is set in the configuration.
+	DefaultKeepAliveInterval = 24 * time.Hour
+
+	// DefaultKeepAliveBackoff is the delay before retrying a failed keepalive
+	// call. It is doubled after each consecutive failure, up to the keepalive
+	// interval.
+	DefaultKeepAliveBackoff = 5 * time.Minute
+
+	// DefaultKeepAliveSplay is the maximum delay of the first keepalive call
+	// of the daemon when no call has been recorded yet, so systems booted at
+	// the same time do not hit the registration server at the same moment.
+	DefaultKeepAliveSplay = time.Hour
+
+	// Every delay is randomized by up to this fraction in both directions.
+	keepAliveJitter = 0.1
+)
+
+var (
+	// test method overwrites
+	localRandFloat          = rand.Float64
+	localSdNotify           = util.SdNotify
+	localSdWatchdogInterval = util.SdWatchdogInterval
+	localNewWrappedAPI      = NewWrappedAPI
+)
+
+// KeepAliveDaemon sends keepalive calls to the registration server at a
+// regular interval, see `SUSEConnect --keepalive --daemon`. Failed calls are
+// retried with an exponential backoff. The outcome of each call and the time
+// of the next one are stored in `KeepAliveStatePath`.
+type KeepAliveDaemon struct {
+	// Load reads the options of the daemon. It is called on startup and on
+	// each reload.
+	Load func() (*Options, error)
+
+	// Reload receives a value whenever the configuration has to be read
+	// again (i.e. on SIGHUP).
+	Reload <-chan struct{}
+
+	// Backoff and Splay default to DefaultKeepAliveBackoff and
+	// DefaultKeepAliveSplay.
+	Backoff time.Duration
+	Splay   time.Duration
+
+	opts        *Options
+	failures    int
+	callTimeout time.Duration
+}
+
+// Run sends keepalive calls until the given context is cancelled. The
+// service manager is notified when the daemon is ready, reloading or
+// stopping, and pinged if its watchdog is enabled. An error is only returned
+// if the options cannot be loaded on startup.
+func (d *KeepAliveDaemon) Run(ctx context.Context) error {
+	if d.Backoff <= 0 {
+		d.Backoff = DefaultKeepAliveBackoff
+	}
+	if d.Splay <= 0 {
+		d.Splay = DefaultKeepAliveSplay
+	}
+
+	opts, err := d.Load()
+	if err != nil {
+		return err
+	}
+	d.opts = opts
+	next := d.firstAttempt()
+	d.schedule(next)
+	d.notify(util.SdNotifyReady, next)
+
+	// The watchdog is pinged from the main loop, so a stuck daemon gets
+	// restarted. Keepalive calls are bounded to leave enough time for it.
+	var watchdog <-chan time.Time
+	if interval := localSdWatchdogInterval(); interval > 0 {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		watchdog = ticker.C
+		d.callTimeout = interval / 4
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.notify(util.SdNotifyStopping, time.Time{})
+			return nil
+		case <-watchdog:
+			timer.Stop()
+			d.notify(util.SdNotifyWatchdog, time.Time{})
+		case <-d.Reload:
+			timer.Stop()
+			d.notify(util.SdNotifyReloading, time.Time{})
+			if opts, err := d.Load(); err != nil {
+				slog.Warn("Could not reload the configuration, keeping the current one", "error", err)
+			} else {
+				d.opts = opts
+				next = d.firstAttempt()
+				d.schedule(next)
+			}
+			d.notify(util.SdNotifyReady, next)
+		case <-timer.C:
+			next = localNow().Add(d.keepAlive())
+			d.schedule(next)
+			state := ""
+			if watchdog != nil {
+				state = util.SdNotifyWatchdog
+			}
+			d.notify(state, next)
+		}
+	}
+}
+
+// firstAttempt returns the time of the first keepalive call after starting
+// or reloading: one interval after the last successful call, or a random
+// time within the splay if there was none.
+func (d *KeepAliveDaemon) firstAttempt() time.Time {
+	now := localNow()
+	state, err := ReadKeepAliveState(d.opts)
+	if err != nil {
+		slog.Debug("Could not read keepalive state", "error", err)
+	}
+	if state != nil {
+		d.failures = state.ConsecutiveFailures
+	}
+	if state != nil && !state.LastSuccess.IsZero() {
+		next := state.LastSuccess.Add(jitter(d.interval()))
+		if next.After(now) {
+			return next
+		}
+	}
+	return now.Add(time.Duration(localRandFloat() * float64(d.Splay)))
+}
+
+// keepAlive sends a keepalive call and returns the delay until the next one.
+func (d *KeepAliveDaemon) keepAlive() time.Duration {
+	api := localNewWrappedAPI(d.opts)
+	if !api.IsRegistered() {
+		slog.Info("System is not registered, skipping keepalive")
+		d.failures = 0
+		return jitter(d.interval())
+	}
+
+	err := d.call(func() error { return api.KeepAlive(d.opts.EnableSystemUptimeTracking) })
+	if recordErr := RecordKeepAlive(d.opts, err); recordErr != nil {
+		slog.Warn("Could not record keepalive", "path", KeepAliveStatePath, "error", recordErr)
+	}
+	switch {
+	case err == nil:
+		slog.Info("Sent keepalive", "server", d.opts.BaseURL)
+		d.failures = 0
+		return jitter(d.interval())
+	case errors.Is(err, ErrPingFromUnregistered):
+		slog.Warn("The registration server does not know this system, skipping keepalive", "server", d.opts.BaseURL)
+		d.failures = 0
+		return jitter(d.interval())
+	}
+
+	d.failures++
+	delay := d.backoff()
+	slog.Warn("Keepalive failed", "server", d.opts.BaseURL, "failures", d.failures, "retry_in", delay.Round(time.Second), "error", err)
+	return delay
+}
+
+// call runs the given function until it returns or the call timeout of the
+// daemon expires. A function which does not return in time is left behind,
+// it is bounded by the timeout of the connection to the registration server.
+func (d *KeepAliveDaemon) call(fn func() error) error {
+	if d.callTimeout <= 0 {
+		return fn()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d.callTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("keepalive call did not finish in time: %w", ctx.Err())
+	}
+}
+
+// backoff returns the delay before retrying after the current number of
+// consecutive failures.
+func (d *KeepAliveDaemon) backoff() time.Duration {
+	delay := d.Backoff
+	for i := 1; i < d.failures && delay < d.interval(); i++ {
+		delay *= 2
+	}
+	return jitter(min(delay, d.interval()))
+}
+
+func (d *KeepAliveDaemon) interval() time.Duration {
+	if d.opts.KeepAliveInterval > 0 {
+		return d.opts.KeepAliveInterval
+	}
+	return DefaultKeepAliveInterval
+}
+
+func (d *KeepAliveDaemon) schedule(next time.Time) {
+	if err := RecordKeepAliveSchedule(d.opts, next, d.failures); err != nil {
+		slog.Warn("Could not record keepalive", "path", KeepAliveStatePath, "error", err)
+	}
+}
+
+// notify sends the given state to the service manager, along with the time
+// of the next keepalive call if given.
+func (d *KeepAliveDaemon) notify(state string, next time.Time) {
+	if !next.IsZero() {
+		status := fmt.Sprintf("STATUS=Next keepalive at %s", next.Local().Format(time.DateTime))
+		if d.failures > 0 {
+			status += fmt.Sprintf(" (%d consecutive failures)", d.failures)
+		}
+		if state != "" {
+			state += "\n"
+		}
+		state += status
+	}
+	if err := localSdNotify(state); err != nil {
+		slog.Debug("Could not notify the service manager", "error", err)
+	}
+}
+
+// jitter randomizes the given delay by up to `keepAliveJitter` in both
+// directions.
+func jitter(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * (1 + keepAliveJitter*(2*localRandFloat()-1)))
+}
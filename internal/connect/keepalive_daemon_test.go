@@ -0,0 +1,210 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func mockKeepAliveDaemon(t *testing.T, api WrappedAPI) *[]string {
+	origRand, origNotify, origWatchdog, origAPI := localRandFloat, localSdNotify, localSdWatchdogInterval, localNewWrappedAPI
+	t.Cleanup(func() {
+		localRandFloat, localSdNotify, localSdWatchdogInterval, localNewWrappedAPI = origRand, origNotify, origWatchdog, origAPI
+	})
+
+	// No jitter.
+	localRandFloat = func() float64 { return 0.5 }
+	localNewWrappedAPI = func(*Options) WrappedAPI { return api }
+	localSdWatchdogInterval = func() time.Duration { return 0 }
+	notifications := []string{}
+	var mu sync.Mutex
+	localSdNotify = func(state string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		notifications = append(notifications, state)
+		return nil
+	}
+	return &notifications
+}
+
+func TestKeepAliveDaemon(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(true)
+	api.On("KeepAlive", false).Return(errors.New("boom")).Twice()
+	api.On("KeepAlive", false).Return(nil).Once().Run(func(mock.Arguments) { cancel() })
+	notifications := mockKeepAliveDaemon(t, api)
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	opts.KeepAliveInterval = time.Hour
+	daemon := &KeepAliveDaemon{
+		Load:    func() (*Options, error) { return opts, nil },
+		Backoff: 5 * time.Millisecond,
+		Splay:   2 * time.Millisecond,
+	}
+
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	select {
+	case err := <-done:
+		assert.NoError(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the keepalive daemon did not stop")
+	}
+	api.AssertExpectations(t)
+
+	state, err := ReadKeepAliveState(opts)
+	assert.NoError(err)
+	assert.Equal(KeepAliveSuccess, state.Result)
+	assert.Equal(0, state.ConsecutiveFailures)
+	assert.WithinDuration(time.Now().Add(time.Hour), state.NextAttempt, time.Minute)
+
+	assert.True(strings.HasPrefix((*notifications)[0], "READY=1\nSTATUS=Next keepalive at "))
+	assert.Contains((*notifications)[2], "(2 consecutive failures)")
+	assert.Equal("STOPPING=1", (*notifications)[len(*notifications)-1])
+}
+
+func TestKeepAliveDaemonWatchdog(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first keepalive call hangs, it is given up on so the main loop
+	// keeps pinging the watchdog and the call is retried.
+	hang := make(chan struct{})
+	defer close(hang)
+	var pings atomic.Int32
+	retried := make(chan struct{})
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(true)
+	api.On("KeepAlive", false).Return(nil).Once().Run(func(mock.Arguments) { <-hang })
+	api.On("KeepAlive", false).Return(nil).Once().Run(func(mock.Arguments) { close(retried) })
+	notifications := mockKeepAliveDaemon(t, api)
+	notify := localSdNotify
+	localSdNotify = func(state string) error {
+		if strings.HasPrefix(state, "WATCHDOG=1") {
+			pings.Add(1)
+		}
+		return notify(state)
+	}
+	localSdWatchdogInterval = func() time.Duration { return 8 * time.Millisecond }
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	daemon := &KeepAliveDaemon{
+		Load:    func() (*Options, error) { return opts, nil },
+		Backoff: time.Millisecond,
+		Splay:   time.Millisecond,
+	}
+
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	select {
+	case <-retried:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hanging keepalive call was not given up on")
+	}
+
+	// Pinged while waiting for the next call.
+	pinged := pings.Load()
+	for pings.Load() < pinged+3 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.NoError(<-done)
+	api.AssertExpectations(t)
+	assert.Equal("STOPPING=1", (*notifications)[len(*notifications)-1])
+}
+
+func TestKeepAliveDaemonReload(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifications := mockKeepAliveDaemon(t, NewMockWrappedAPI())
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	reload := make(chan struct{})
+	loads := 0
+	daemon := &KeepAliveDaemon{
+		Load: func() (*Options, error) {
+			loads++
+			if loads == 3 {
+				return nil, errors.New("invalid configuration")
+			}
+			return opts, nil
+		},
+		Reload: reload,
+	}
+
+	done := make(chan error)
+	go func() { done <- daemon.Run(ctx) }()
+	reload <- struct{}{}
+	reload <- struct{}{}
+	cancel()
+	assert.NoError(<-done)
+
+	assert.Equal(3, loads)
+	// A failed reload keeps the current configuration and schedule.
+	assert.Equal("RELOADING=1", (*notifications)[3])
+	assert.True(strings.HasPrefix((*notifications)[4], "READY=1\nSTATUS=Next keepalive at "))
+}
+
+func TestKeepAliveDaemonFirstAttempt(t *testing.T) {
+	assert := assert.New(t)
+	mockKeepAliveDaemon(t, NewMockWrappedAPI())
+
+	origNow := localNow
+	t.Cleanup(func() { localNow = origNow })
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	localNow = func() time.Time { return now }
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	daemon := &KeepAliveDaemon{opts: opts, Splay: time.Hour}
+
+	// No keepalive call has been recorded yet.
+	assert.Equal(now.Add(30*time.Minute), daemon.firstAttempt())
+
+	localNow = func() time.Time { return now.Add(-6 * time.Hour) }
+	assert.NoError(RecordKeepAlive(opts, nil))
+	localNow = func() time.Time { return now }
+	assert.Equal(now.Add(18*time.Hour), daemon.firstAttempt())
+
+	// The last successful call is older than the interval.
+	opts.KeepAliveInterval = 2 * time.Hour
+	assert.Equal(now.Add(30*time.Minute), daemon.firstAttempt())
+}
+
+func TestKeepAliveDaemonBackoff(t *testing.T) {
+	assert := assert.New(t)
+	mockKeepAliveDaemon(t, NewMockWrappedAPI())
+
+	daemon := &KeepAliveDaemon{opts: DefaultOptions(), Backoff: 5 * time.Minute}
+	expected := []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute, 40 * time.Minute}
+	for i, delay := range expected {
+		daemon.failures = i + 1
+		assert.Equal(delay, daemon.backoff())
+	}
+
+	daemon.failures = 20
+	assert.Equal(DefaultKeepAliveInterval, daemon.backoff())
+
+	localRandFloat = func() float64 { return 0 }
+	daemon.failures = 1
+	assert.Equal(4*time.Minute+30*time.Second, daemon.backoff())
+}
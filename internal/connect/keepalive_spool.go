@@ -0,0 +1,157 @@
+package connect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/util"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+const (
+	// KeepAliveSpoolDir holds the keepalive calls which could not be sent
+	// because the registration server was unreachable. They are replayed in
+	// order before the next keepalive call.
+	KeepAliveSpoolDir = "/var/spool/suseconnect/keepalive"
+
+	// Limits of the spool. The oldest calls are dropped first.
+	keepAliveSpoolMaxEntries = 500
+	keepAliveSpoolMaxSize    = 4 << 20
+)
+
+// SpooledKeepAlive is a keepalive call queued in `KeepAliveSpoolDir`. Only
+// the uptime log is kept, since the system information and profiles are sent
+// again in full by the next successful keepalive call.
+type SpooledKeepAlive struct {
+	QueuedAt time.Time `json:"queued_at"`
+	Hostname string    `json:"hostname"`
+	OnlineAt []string  `json:"online_at,omitempty"`
+
+	path string
+	size int64
+}
+
+// KeepAliveBacklog returns the keepalive calls queued in `KeepAliveSpoolDir`,
+// oldest first.
+func KeepAliveBacklog(opts *Options) ([]SpooledKeepAlive, error) {
+	dir := filepath.Join(opts.FsRoot, KeepAliveSpoolDir)
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// File names are zero padded timestamps.
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	entries := []SpooledKeepAlive{}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		entry := SpooledKeepAlive{path: path, size: int64(len(data))}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			slog.Warn("Dropping corrupted queued keepalive", "path", path, "error", err)
+			os.Remove(path)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// spoolKeepAlive queues a keepalive call which could not be sent. It is
+// skipped if it does not differ from the last queued one.
+func spoolKeepAlive(opts *Options, hostname string, onlineAt []string) error {
+	entries, err := KeepAliveBacklog(opts)
+	if err != nil {
+		return err
+	}
+	if n := len(entries); n > 0 && entries[n-1].Hostname == hostname && slices.Equal(entries[n-1].OnlineAt, onlineAt) {
+		slog.Debug("Keepalive already queued", "path", entries[n-1].path)
+		return nil
+	}
+
+	entry := SpooledKeepAlive{QueuedAt: localNow().UTC(), Hostname: hostname, OnlineAt: onlineAt}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(opts.FsRoot, KeepAliveSpoolDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	stamp := entry.QueuedAt.UnixNano()
+	path := filepath.Join(dir, fmt.Sprintf("%020d.json", stamp))
+	for util.FileExists(path) {
+		stamp++
+		path = filepath.Join(dir, fmt.Sprintf("%020d.json", stamp))
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	slog.Info("Queued keepalive until the registration server is reachable", "path", path)
+
+	// Enforce the limits of the spool, oldest calls first.
+	entries = append(entries, SpooledKeepAlive{path: path, size: int64(len(data))})
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+	for len(entries) > keepAliveSpoolMaxEntries || size > keepAliveSpoolMaxSize {
+		slog.Warn("Keepalive queue is full, dropping the oldest call", "queued_at", entries[0].QueuedAt)
+		if err := os.Remove(entries[0].path); err != nil {
+			return err
+		}
+		size -= entries[0].size
+		entries = entries[1:]
+	}
+	return nil
+}
+
+// replayKeepAliveSpool sends the queued keepalive calls in order. Calls
+// rejected by the server are dropped. It stops and returns the error if the
+// server is still unreachable.
+func replayKeepAliveSpool(conn connection.Connection, opts *Options) error {
+	entries, err := KeepAliveBacklog(opts)
+	if err != nil {
+		slog.Warn("Could not read the keepalive queue", "path", KeepAliveSpoolDir, "error", err)
+		return nil
+	}
+
+	for _, entry := range entries {
+		extraData := registration.ExtraData{}
+		if entry.OnlineAt != nil {
+			extraData["online_at"] = entry.OnlineAt
+		}
+		code, err := registration.Status(conn, entry.Hostname, nil, nil, extraData)
+		if connection.IsUnreachable(err) {
+			return err
+		}
+		if err != nil || code != registration.Registered {
+			slog.Warn("Dropping queued keepalive rejected by the registration server", "queued_at", entry.QueuedAt, "error", err)
+		} else {
+			slog.Info("Sent queued keepalive", "queued_at", entry.QueuedAt)
+		}
+		if err := os.Remove(entry.path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
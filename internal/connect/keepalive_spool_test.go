@@ -0,0 +1,160 @@
+package connect
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSpoolKeepAlive(t *testing.T) {
+	assert := assert.New(t)
+
+	origNow := localNow
+	t.Cleanup(func() { localNow = origNow })
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	localNow = func() time.Time { return now }
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+
+	backlog, err := KeepAliveBacklog(opts)
+	assert.NoError(err)
+	assert.Empty(backlog)
+
+	assert.NoError(spoolKeepAlive(opts, "host", []string{"2025-06-01:100000000000000000000000"}))
+	// Same payload as the last queued call.
+	assert.NoError(spoolKeepAlive(opts, "host", []string{"2025-06-01:100000000000000000000000"}))
+	assert.NoError(spoolKeepAlive(opts, "host", []string{"2025-06-01:110000000000000000000000"}))
+
+	backlog, err = KeepAliveBacklog(opts)
+	assert.NoError(err)
+	assert.Len(backlog, 2)
+	assert.Equal(now, backlog[0].QueuedAt)
+	assert.Equal([]string{"2025-06-01:100000000000000000000000"}, backlog[0].OnlineAt)
+	assert.Equal([]string{"2025-06-01:110000000000000000000000"}, backlog[1].OnlineAt)
+}
+
+func TestSpoolKeepAliveLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	origNow := localNow
+	t.Cleanup(func() { localNow = origNow })
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	for i := 0; i <= keepAliveSpoolMaxEntries; i++ {
+		localNow = func() time.Time { return start.Add(time.Duration(i) * time.Hour) }
+		assert.NoError(spoolKeepAlive(opts, "host", []string{start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)}))
+	}
+
+	backlog, err := KeepAliveBacklog(opts)
+	assert.NoError(err)
+	assert.Len(backlog, keepAliveSpoolMaxEntries)
+	// The oldest call has been dropped.
+	assert.Equal(start.Add(time.Hour), backlog[0].QueuedAt)
+}
+
+func TestReplayKeepAliveSpool(t *testing.T) {
+	assert := assert.New(t)
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	assert.NoError(spoolKeepAlive(opts, "host", []string{"2025-06-01:100000000000000000000000"}))
+	assert.NoError(spoolKeepAlive(opts, "host", []string{"2025-06-01:110000000000000000000000"}))
+
+	// The server is still down.
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	conn.On("Do", mock.Anything).Return([]byte(""), &connection.ApiError{Code: http.StatusBadGateway}).Once()
+	assert.Error(replayKeepAliveSpool(conn, opts))
+	backlog, _ := KeepAliveBacklog(opts)
+	assert.Len(backlog, 2)
+
+	sent := [][]string{}
+	conn.On("Do", mock.Anything).Return([]byte(""), nil).Run(func(args mock.Arguments) {
+		body, _ := io.ReadAll(args.Get(0).(*http.Request).Body)
+		payload := map[string]any{}
+		assert.NoError(json.Unmarshal(body, &payload))
+		assert.NotContains(payload, "hwinfo")
+
+		onlineAt := []string{}
+		for _, day := range payload["online_at"].([]any) {
+			onlineAt = append(onlineAt, day.(string))
+		}
+		sent = append(sent, onlineAt)
+	})
+	assert.NoError(replayKeepAliveSpool(conn, opts))
+	assert.Equal([][]string{
+		{"2025-06-01:100000000000000000000000"},
+		{"2025-06-01:110000000000000000000000"},
+	}, sent)
+
+	backlog, _ = KeepAliveBacklog(opts)
+	assert.Empty(backlog)
+}
+
+func TestApplyKeepAliveBacklog(t *testing.T) {
+	assert := assert.New(t)
+
+	products := []registration.Product{
+		{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"},
+		{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true},
+	}
+	statuses := buildStatuses(products, map[string]*registration.Activation{})
+	backlog := []SpooledKeepAlive{
+		{QueuedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{QueuedAt: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	applyKeepAliveBacklog(statuses, products, backlog)
+
+	assert.Zero(statuses[0].QueuedKeepAlives)
+	assert.Equal(2, statuses[1].QueuedKeepAlives)
+	assert.Equal("2025-06-01 00:00:00 UTC", statuses[1].QueuedKeepAlivesSince)
+
+	text, err := getStatusText(statuses)
+	assert.NoError(err)
+	assert.Contains(text, "Queued keepalive calls: 2 (since 2025-06-01 00:00:00 UTC)")
+}
+
+func TestStatusesWhileUnreachable(t *testing.T) {
+	assert := assert.New(t)
+
+	origProducts, origActivations, origAPI := localInstalledProducts, localFetchActivations, localNewWrappedAPI
+	t.Cleanup(func() {
+		localInstalledProducts, localFetchActivations, localNewWrappedAPI = origProducts, origActivations, origAPI
+	})
+	localInstalledProducts = func() ([]registration.Product, error) {
+		return []registration.Product{{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true}}, nil
+	}
+	localFetchActivations = func(connection.Connection) ([]*registration.Activation, error) {
+		return nil, &connection.ApiError{Code: http.StatusServiceUnavailable}
+	}
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(true)
+	api.On("GetConnection").Return(conn)
+	localNewWrappedAPI = func(*Options) WrappedAPI { return api }
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	assert.NoError(spoolKeepAlive(opts, "host", []string{"2025-06-01:100000000000000000000000"}))
+
+	statuses, err := getStatuses(opts)
+	assert.NoError(err)
+	assert.Equal(serverUnreachable, statuses[0].Status)
+	assert.Equal(1, statuses[0].QueuedKeepAlives)
+
+	// Requests rejected by the server are still reported as errors.
+	localFetchActivations = func(connection.Connection) ([]*registration.Activation, error) {
+		return nil, &connection.ApiError{Code: http.StatusUnauthorized}
+	}
+	_, err = getStatuses(opts)
+	assert.Error(err)
+}
@@ -0,0 +1,91 @@
+package connect
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// KeepAliveStatePath keeps track of the last keepalive call sent to the
+	// registration server.
+	KeepAliveStatePath = "/var/lib/suseconnect/keepalive.json"
+
+	// Result recorded for successful keepalive calls. Failures are recorded
+	// with their `ErrorCode`.
+	KeepAliveSuccess = "success"
+)
+
+// KeepAliveState describes the outcome of the last keepalive calls.
+type KeepAliveState struct {
+	LastAttempt         time.Time `json:"last_attempt"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	Result              string    `json:"result"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+
+	// Time of the next call scheduled by the keepalive daemon.
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+}
+
+// RecordKeepAlive stores the outcome of a keepalive call into
+// `KeepAliveStatePath`.
+func RecordKeepAlive(opts *Options, keepAliveErr error) error {
+	state, err := ReadKeepAliveState(opts)
+	if err != nil || state == nil {
+		state = &KeepAliveState{}
+	}
+
+	state.LastAttempt = localNow().UTC()
+	if keepAliveErr == nil {
+		state.LastSuccess = state.LastAttempt
+		state.Result = KeepAliveSuccess
+		state.ConsecutiveFailures = 0
+	} else {
+		state.Result = string(ErrorCodeFor(keepAliveErr))
+		state.ConsecutiveFailures++
+	}
+	return writeKeepAliveState(opts, state)
+}
+
+// RecordKeepAliveSchedule stores the time of the next keepalive call and the
+// number of consecutive failures into `KeepAliveStatePath`.
+func RecordKeepAliveSchedule(opts *Options, next time.Time, failures int) error {
+	state, err := ReadKeepAliveState(opts)
+	if err != nil || state == nil {
+		state = &KeepAliveState{}
+	}
+	state.NextAttempt = next.UTC()
+	state.ConsecutiveFailures = failures
+	return writeKeepAliveState(opts, state)
+}
+
+func writeKeepAliveState(opts *Options, state *KeepAliveState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(opts.FsRoot, KeepAliveStatePath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadKeepAliveState returns the outcome of the last keepalive calls, or nil
+// if no keepalive call has been recorded yet.
+func ReadKeepAliveState(opts *Options) (*KeepAliveState, error) {
+	data, err := os.ReadFile(filepath.Join(opts.FsRoot, KeepAliveStatePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	state := &KeepAliveState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
@@ -0,0 +1,51 @@
+package connect
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordKeepAlive(t *testing.T) {
+	assert := assert.New(t)
+
+	origNow := localNow
+	t.Cleanup(func() { localNow = origNow })
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+
+	state, err := ReadKeepAliveState(opts)
+	assert.NoError(err)
+	assert.Nil(state)
+
+	success := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	localNow = func() time.Time { return success }
+	assert.NoError(RecordKeepAlive(opts, nil))
+
+	// Failures keep the time of the last successful call.
+	failure := success.Add(24 * time.Hour)
+	localNow = func() time.Time { return failure }
+	assert.NoError(RecordKeepAlive(opts, ErrSystemNotRegistered))
+
+	state, err = ReadKeepAliveState(opts)
+	assert.NoError(err)
+	assert.Equal(failure, state.LastAttempt)
+	assert.Equal(success, state.LastSuccess)
+	assert.Equal(string(ErrorCodeSystemNotRegistered), state.Result)
+
+	assert.NoError(RecordKeepAlive(opts, errors.New("boom")))
+	state, _ = ReadKeepAliveState(opts)
+	assert.Equal(string(ErrorCodeGeneric), state.Result)
+	assert.Equal(2, state.ConsecutiveFailures)
+
+	next := failure.Add(5 * time.Minute)
+	assert.NoError(RecordKeepAliveSchedule(opts, next, 2))
+	assert.NoError(RecordKeepAlive(opts, nil))
+	state, _ = ReadKeepAliveState(opts)
+	assert.Equal(0, state.ConsecutiveFailures)
+	assert.Equal(next, state.NextAttempt)
+	assert.Equal(failure, state.LastSuccess)
+}
@@ -0,0 +1,260 @@
+package connect
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/profiles"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+const (
+	// DefaultMetricsDir is the directory read by the textfile collector of
+	// the Prometheus node exporter.
+	DefaultMetricsDir = "/var/lib/prometheus/node-exporter"
+
+	// MetricsFileName is the name of the file written into the metrics
+	// directory.
+	MetricsFileName = "suseconnect.prom"
+)
+
+var (
+	// test method overwrites
+	localFailedProfileUpdates = profiles.FailedProfileUpdates
+)
+
+// metricSample is a single value of a metric with its labels, in order.
+type metricSample struct {
+	labels [][2]string
+	value  float64
+}
+
+// metricFamily is a metric as written in the Prometheus text format.
+type metricFamily struct {
+	name    string
+	help    string
+	samples []metricSample
+}
+
+type metricSet struct {
+	families []*metricFamily
+}
+
+// gauge adds a sample to the metric with the given name, creating it if
+// needed. Labels are given as name and value pairs.
+func (m *metricSet) gauge(name, help string, value float64, labels ...string) {
+	var family *metricFamily
+	for _, f := range m.families {
+		if f.name == name {
+			family = f
+		}
+	}
+	if family == nil {
+		family = &metricFamily{name: name, help: help}
+		m.families = append(m.families, family)
+	}
+
+	sample := metricSample{value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		sample.labels = append(sample.labels, [2]string{labels[i], labels[i+1]})
+	}
+	family.samples = append(family.samples, sample)
+}
+
+func (m *metricSet) timestamp(name, help string, t time.Time, labels ...string) {
+	if t.IsZero() {
+		return
+	}
+	m.gauge(name, help, float64(t.Unix()), labels...)
+}
+
+// String renders the metrics in the Prometheus text exposition format.
+func (m *metricSet) String() string {
+	var b strings.Builder
+	for _, family := range m.families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(&b, "# TYPE %s gauge\n", family.name)
+		for _, sample := range family.samples {
+			b.WriteString(family.name)
+			if len(sample.labels) > 0 {
+				pairs := make([]string, 0, len(sample.labels))
+				for _, label := range sample.labels {
+					pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", label[0], escapeLabelValue(label[1])))
+				}
+				fmt.Fprintf(&b, "{%s}", strings.Join(pairs, ","))
+			}
+			fmt.Fprintf(&b, " %s\n", strconv.FormatFloat(sample.value, 'f', -1, 64))
+		}
+	}
+	return b.String()
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// WriteMetrics writes the registration state of this system in the
+// Prometheus text format into `MetricsFileName` inside of the given
+// directory, so it is picked up by the textfile collector of the node
+// exporter. The file is replaced atomically.
+func WriteMetrics(api WrappedAPI, opts *Options, dir string) error {
+	metrics := collectMetrics(api, opts)
+
+	dir = filepath.Join(opts.FsRoot, dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// The node exporter ignores files not ending in ".prom".
+	tmp, err := os.CreateTemp(dir, "."+MetricsFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(metrics.String()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, MetricsFileName)
+	slog.Debug("Writing metrics", "path", target)
+	return os.Rename(tmp.Name(), target)
+}
+
+func collectMetrics(api WrappedAPI, opts *Options) *metricSet {
+	m := &metricSet{}
+
+	registered := api.IsRegistered()
+	m.gauge("suseconnect_registered", "Whether the system is registered against a registration server.", boolValue(registered))
+
+	serverType := "rmt"
+	if opts.IsScc() {
+		serverType = "scc"
+	}
+	m.gauge("suseconnect_server_info", "Registration server configured on this system.", 1, "url", opts.BaseURL, "type", serverType)
+
+	activations := map[string]*registration.Activation{}
+	if registered {
+		fetched, err := localFetchActivations(api.GetConnection())
+		m.gauge("suseconnect_server_reachable", "Whether the activations could be fetched from the registration server.", boolValue(err == nil))
+		if err != nil {
+			slog.Debug("Could not fetch activations", "server", opts.BaseURL, "error", err)
+			fetched = nil
+		}
+		for _, activation := range fetched {
+			activations[activation.ToTriplet()] = activation
+		}
+	}
+
+	products, err := localInstalledProducts()
+	if err != nil {
+		slog.Debug("Could not read installed products", "error", err)
+	}
+	for _, product := range products {
+		_, ok := activations[product.ToTriplet()]
+		m.gauge("suseconnect_product_activated", "Whether the installed product is activated on the registration server.", boolValue(ok),
+			"identifier", product.Identifier, "version", product.Version, "arch", product.Arch, "base", strconv.FormatBool(product.IsBase))
+	}
+
+	triplets := make([]string, 0, len(activations))
+	for triplet := range activations {
+		triplets = append(triplets, triplet)
+	}
+	sort.Strings(triplets)
+	for _, triplet := range triplets {
+		activation := activations[triplet]
+		if activation.RegistrationCode == "" {
+			continue
+		}
+		m.timestamp("suseconnect_subscription_expiry_timestamp_seconds", "Expiration date of the subscription covering the product.", activation.ExpiresAt,
+			"identifier", activation.Product.Identifier, "version", activation.Product.Version, "arch", activation.Product.Arch,
+			"subscription", activation.Name, "source", SourceActivation)
+	}
+
+	if cert, err := StoredOfflineCertificate(opts); err != nil {
+		slog.Debug("Could not read offline registration certificate", "error", err)
+	} else if cert != nil {
+		m.gauge("suseconnect_offline_certificate_valid", "Whether the offline registration certificate can be used on this system.", boolValue(cert.Valid()),
+			"state", cert.State)
+		for _, product := range products {
+			if product.IsBase {
+				m.timestamp("suseconnect_subscription_expiry_timestamp_seconds", "Expiration date of the subscription covering the product.", cert.ExpiresAt,
+					"identifier", product.Identifier, "version", product.Version, "arch", product.Arch,
+					"subscription", cert.Subscription, "source", SourceOfflineCertificate)
+			}
+		}
+	}
+
+	if state, err := ReadKeepAliveState(opts); err != nil {
+		slog.Debug("Could not read keepalive state", "error", err)
+	} else if state != nil {
+		m.timestamp("suseconnect_keepalive_last_attempt_timestamp_seconds", "Time of the last keepalive call.", state.LastAttempt)
+		m.timestamp("suseconnect_keepalive_last_success_timestamp_seconds", "Time of the last successful keepalive call.", state.LastSuccess)
+		m.gauge("suseconnect_keepalive_last_result", "Result of the last keepalive call (\"success\" or an error code).", 1, "result", state.Result)
+		m.gauge("suseconnect_keepalive_consecutive_failures", "Keepalive calls which failed since the last successful one.", float64(state.ConsecutiveFailures))
+		m.timestamp("suseconnect_keepalive_next_attempt_timestamp_seconds", "Time of the next keepalive call scheduled by the keepalive daemon.", state.NextAttempt)
+		if backlog, err := KeepAliveBacklog(opts); err != nil {
+			slog.Debug("Could not read the keepalive queue", "error", err)
+		} else {
+			m.gauge("suseconnect_keepalive_queued_calls", "Keepalive calls queued until the registration server is reachable again.", float64(len(backlog)))
+		}
+	}
+
+	m.gauge("suseconnect_profile_cache_update_failures", "Consecutive failures to send the system profiles (clear-cache-count).", float64(localFailedProfileUpdates()))
+
+	collectUptimeMetrics(m, opts)
+	return m
+}
+
+// collectUptimeMetrics reports how much of the period kept in the uptime log
+// has been covered by the uptime tracker.
+func collectUptimeMetrics(m *metricSet, opts *Options) {
+	m.gauge("suseconnect_uptime_tracking_enabled", "Whether the uptime log is sent to the registration server.", boolValue(opts.EnableSystemUptimeTracking))
+
+	path := filepath.Join(opts.FsRoot, UptimeLogFilePath)
+	entries, err := readUptimeLogFile(path)
+	if err != nil {
+		slog.Debug("Could not read uptime log", "path", path, "error", err)
+		return
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	hours := 0
+	for _, entry := range entries {
+		if _, bits, ok := strings.Cut(entry, ":"); ok {
+			hours += strings.Count(bits, "1")
+		}
+	}
+	m.gauge("suseconnect_uptime_log_days", "Days kept in the uptime log.", float64(len(entries)))
+	m.gauge("suseconnect_uptime_log_tracked_hours", "Hours in which the uptime tracker has seen the system running.", float64(hours))
+	m.gauge("suseconnect_uptime_log_coverage_ratio", "Ratio of the hours kept in the uptime log in which the system has been seen running.", float64(hours)/float64(len(entries)*24))
+	if info, err := os.Stat(path); err == nil {
+		m.timestamp("suseconnect_uptime_log_last_update_timestamp_seconds", "Last time the uptime log has been updated.", info.ModTime())
+	}
+}
@@ -0,0 +1,120 @@
+package connect
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockMetrics(t *testing.T, registered bool, fetchErr error) (*Options, *MockWrappedAPI) {
+	t.Helper()
+
+	origActivations, origProducts := localFetchActivations, localInstalledProducts
+	origFailures, origNow := localFailedProfileUpdates, localNow
+	t.Cleanup(func() {
+		localFetchActivations, localInstalledProducts = origActivations, origProducts
+		localFailedProfileUpdates, localNow = origFailures, origNow
+	})
+
+	localNow = func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC) }
+	localFetchActivations = func(connection.Connection) ([]*registration.Activation, error) {
+		return []*registration.Activation{
+			{
+				Name:             "SUSE Linux Enterprise Server",
+				RegistrationCode: "regcode",
+				ExpiresAt:        time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				Product:          &registration.Product{Identifier: "SLES", Version: "15.6", Arch: "x86_64"},
+			},
+			{Product: &registration.Product{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"}},
+		}, fetchErr
+	}
+	localInstalledProducts = func() ([]registration.Product, error) {
+		return []registration.Product{
+			{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true},
+			{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"},
+			{Identifier: "sle-module-python3", Version: "15.6", Arch: "x86_64"},
+		}, nil
+	}
+	localFailedProfileUpdates = func() int { return 2 }
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(registered)
+	api.On("GetConnection").Return(conn)
+	return opts, api
+}
+
+func TestWriteMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := mockMetrics(t, true, nil)
+	assert.NoError(RecordKeepAlive(opts, nil))
+
+	uptimeLog := filepath.Join(opts.FsRoot, UptimeLogFilePath)
+	assert.NoError(os.MkdirAll(filepath.Dir(uptimeLog), 0755))
+	assert.NoError(os.WriteFile(uptimeLog, []byte("2025-05-31:111111111111111111111111\n2025-06-01:111111111111000000000000\n"), 0644))
+
+	assert.NoError(WriteMetrics(api, opts, DefaultMetricsDir))
+
+	dir := filepath.Join(opts.FsRoot, DefaultMetricsDir)
+	files, _ := os.ReadDir(dir)
+	assert.Len(files, 1, "temporary files are cleaned up")
+	data, err := os.ReadFile(filepath.Join(dir, MetricsFileName))
+	assert.NoError(err)
+	metrics := string(data)
+
+	expected := []string{
+		"# TYPE suseconnect_registered gauge",
+		"suseconnect_registered 1",
+		`suseconnect_server_info{url="https://scc.suse.com",type="scc"} 1`,
+		"suseconnect_server_reachable 1",
+		`suseconnect_product_activated{identifier="SLES",version="15.6",arch="x86_64",base="true"} 1`,
+		`suseconnect_product_activated{identifier="sle-module-python3",version="15.6",arch="x86_64",base="false"} 0`,
+		`suseconnect_subscription_expiry_timestamp_seconds{identifier="SLES",version="15.6",arch="x86_64",subscription="SUSE Linux Enterprise Server",source="activation"} 1767225600`,
+		"suseconnect_keepalive_last_success_timestamp_seconds 1748736000",
+		`suseconnect_keepalive_last_result{result="success"} 1`,
+		"suseconnect_profile_cache_update_failures 2",
+		"suseconnect_uptime_tracking_enabled 0",
+		"suseconnect_uptime_log_days 2",
+		"suseconnect_uptime_log_tracked_hours 36",
+		"suseconnect_uptime_log_coverage_ratio 0.75",
+	}
+	for _, line := range expected {
+		assert.Contains(metrics, line+"\n")
+	}
+	assert.Equal(1, strings.Count(metrics, "# HELP suseconnect_product_activated "))
+	assert.NotContains(metrics, `subscription=""`)
+}
+
+func TestWriteMetricsUnreachable(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := mockMetrics(t, true, errors.New("connection refused"))
+	assert.NoError(WriteMetrics(api, opts, "/metrics"))
+
+	data, err := os.ReadFile(filepath.Join(opts.FsRoot, "metrics", MetricsFileName))
+	assert.NoError(err)
+	metrics := string(data)
+	assert.Contains(metrics, "suseconnect_server_reachable 0\n")
+	assert.Contains(metrics, `suseconnect_product_activated{identifier="SLES",version="15.6",arch="x86_64",base="true"} 0`)
+	assert.NotContains(metrics, "suseconnect_keepalive")
+	assert.NotContains(metrics, "suseconnect_uptime_log_days")
+}
+
+func TestMetricLabelEscaping(t *testing.T) {
+	assert := assert.New(t)
+
+	m := &metricSet{}
+	m.gauge("test_metric", "Help.", 1, "name", "a \"quoted\"\\value\n")
+	assert.Equal("# HELP test_metric Help.\n# TYPE test_metric gauge\n"+
+		`test_metric{name="a \"quoted\"\\value\n"} 1`+"\n", m.String())
+}
@@ -3,6 +3,7 @@ package connect
 import (
 	"path/filepath"
 
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/internal/zypper"
 	"github.com/SUSE/connect-ng/pkg/connection"
@@ -12,28 +13,26 @@ import (
 // MigrationPath holds a list of products
 type MigrationPath []registration.Product
 
-// Rollback restores system state to before failed migration
-func Rollback(conn connection.Connection, opts *Options) error {
-	util.Info.Print("Starting to sync system product activations to the server. This can take some time...")
+// Rollback restores system state to before failed migration. The returned
+// result contains every product which has been rolled back, even on failure.
+func Rollback(conn connection.Connection, opts *Options) (*RegisterOut, error) {
+	out := newRegisterOut(OperationRollback)
+	opts.Print(i18n.T("Starting to sync system product activations to the server. This can take some time..."))
 
 	base, err := zypper.BaseProduct()
 	if err != nil {
-		return err
+		return out, out.Finish("", err)
 	}
 
 	// First rollback the base_product
-	meta, _, err := registration.Upgrade(conn, base.Identifier, base.Version, base.Arch)
-	if err != nil {
-		return err
-	}
-	if err = migrationRefreshService(meta, opts.Insecure); err != nil {
-		return err
+	if err := rollbackProduct(conn, base, opts, out); err != nil {
+		return out, out.Finish("", err)
 	}
 
 	// Fetch the product tree
 	installed, err := zypper.InstalledProducts()
 	if err != nil {
-		return err
+		return out, out.Finish("", err)
 	}
 	installedIDs := NewStringSet()
 	for _, prod := range installed {
@@ -42,7 +41,7 @@ func Rollback(conn connection.Connection, opts *Options) error {
 
 	tree, err := registration.FetchProductInfo(conn, base.Identifier, base.Version, base.Arch)
 	if err != nil {
-		return err
+		return out, out.Finish("", err)
 	}
 
 	// Get all installed products in right order
@@ -55,23 +54,36 @@ func Rollback(conn connection.Connection, opts *Options) error {
 
 	// Rollback all extensions
 	for _, e := range extensions {
-		meta, _, err := registration.Upgrade(conn, e.Identifier, e.Version, e.Arch)
-		if err != nil {
-			return err
-		}
-		if err := migrationRefreshService(meta, opts.Insecure); err != nil {
-			return err
+		if err := rollbackProduct(conn, e, opts, out); err != nil {
+			return out, out.Finish("", err)
 		}
 	}
 
 	// Synchronize installed products with SCC activations (removes obsolete
 	// activations)
 	if _, err := SyncProducts(conn, installed); err != nil {
-		return err
+		return out, out.Finish("", err)
 	}
 
 	// Set releasever to the new baseproduct version
-	return zypper.SetReleaseVersion(base.Version)
+	if err := zypper.SetReleaseVersion(base.Version); err != nil {
+		return out, out.Finish("", err)
+	}
+	return out, out.Finish(i18n.T("Successfully rolled back system"), nil)
+}
+
+// rollbackProduct activates the installed version of the given product again
+// and refreshes its service.
+func rollbackProduct(conn connection.Connection, product registration.Product, opts *Options, out *RegisterOut) (err error) {
+	entry := out.start(product, ActionActivate)
+	defer func() { entry.finish(err) }()
+
+	meta, _, err := registration.Upgrade(conn, product.Identifier, product.Version, product.Arch)
+	if err != nil {
+		return err
+	}
+	entry.setService(meta.ID, meta.Name, meta.URL)
+	return migrationRefreshService(meta, opts.Insecure)
 }
 
 // MigrationAddService adds zypper service in migration context
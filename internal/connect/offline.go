@@ -0,0 +1,245 @@
+package connect
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/collectors"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+const (
+	// OfflineCertificatePath is where the imported offline registration
+	// certificate is stored, next to the configuration file.
+	OfflineCertificatePath = "/etc/SUSEConnect.offline-certificate"
+
+	// OfflineKeysDir holds additional PEM encoded public keys (e.g. of a
+	// registration proxy) trusted to sign offline registration certificates.
+	OfflineKeysDir = "/etc/SUSEConnect.offline-keys.d"
+)
+
+// Possible states of an offline registration certificate.
+const (
+	OfflineCertificateValid           = "valid"
+	OfflineCertificateExpired         = "expired"
+	OfflineCertificateNotYetValid     = "not yet valid"
+	OfflineCertificateInvalid         = "invalid signature"
+	OfflineCertificateUnsupported     = "unsupported"
+	OfflineCertificateUUIDMismatch    = "issued for another system"
+	OfflineCertificateRegcodeMismatch = "issued for another registration code"
+)
+
+var (
+	ErrOfflineCertificate        = errors.New("Invalid offline registration certificate")
+	ErrOfflineCertificateRegcode = errors.New("A registration code (--regcode) is required to import an offline registration certificate")
+
+	// test method overwrites
+	localSystemInformation = FetchSystemInformation
+	localSystemUUID        = systemUUID
+	localNow               = time.Now
+)
+
+// OfflineCertificateStatus describes the offline registration certificate
+// stored on the system.
+type OfflineCertificateStatus struct {
+	State        string    `json:"state"`
+	Subscription string    `json:"subscription"`
+	Kind         string    `json:"kind"`
+	StartsAt     time.Time `json:"starts_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+
+	// Notifications setting of the subscription ("all", "silent", ...).
+	Notifications string `json:"notifications,omitempty"`
+}
+
+// Valid returns true if the certificate can be used on this system.
+func (s OfflineCertificateStatus) Valid() bool {
+	return s.State == OfflineCertificateValid
+}
+
+// WriteOfflineRequest builds an offline registration request for the base
+// product of this system (or the product given in the options) with the
+// information of the local collectors, and writes it base64 encoded into the
+// given writer. The result can be uploaded to SCC from a system which has
+// network access in order to obtain an offline registration certificate.
+func WriteOfflineRequest(opts *Options, w io.Writer) error {
+	product := opts.Product
+	if product.IsEmpty() {
+		base, err := zypper.BaseProduct()
+		if err != nil {
+			return err
+		}
+		product = base
+	}
+
+	hwinfo, err := localSystemInformation(product.Arch, opts.Collectors)
+	if err != nil {
+		return fmt.Errorf("could not fetch system's information: %v", err)
+	}
+
+	request := registration.BuildOfflineRequest(product.Identifier, product.Version, product.Arch, hwinfo)
+	blob, err := request.Base64Encoded()
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, blob); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// ImportOfflineCertificate reads the offline registration certificate at the
+// given path, validates it against this system and the registration code in
+// the options and stores it into `OfflineCertificatePath`. An error wrapping
+// `ErrOfflineCertificate` is returned if the certificate cannot be used on
+// this system.
+func ImportOfflineCertificate(opts *Options, path string) (OfflineCertificateStatus, error) {
+	if opts.Token == "" {
+		return OfflineCertificateStatus{}, ErrOfflineCertificateRegcode
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return OfflineCertificateStatus{}, err
+	}
+	cert, err := registration.OfflineCertificateFrom(bytes.NewReader(data), true)
+	if err != nil {
+		return OfflineCertificateStatus{}, fmt.Errorf("%w: %v", ErrOfflineCertificate, err)
+	}
+
+	status, err := offlineCertificateStatus(cert, opts)
+	if err != nil {
+		return status, err
+	}
+	if status.Valid() {
+		if matches, err := cert.RegcodeMatches(opts.Token); err != nil {
+			return status, fmt.Errorf("%w: %v", ErrOfflineCertificate, err)
+		} else if !matches {
+			status.State = OfflineCertificateRegcodeMismatch
+		}
+	}
+	if !status.Valid() {
+		return status, fmt.Errorf("%w: %s", ErrOfflineCertificate, status.State)
+	}
+
+	target := filepath.Join(opts.FsRoot, OfflineCertificatePath)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return status, err
+	}
+	slog.Debug("Storing offline registration certificate", "path", target)
+	return status, os.WriteFile(target, data, 0600)
+}
+
+// StoredOfflineCertificate returns the status of the offline registration
+// certificate stored on this system, or nil if there is none.
+func StoredOfflineCertificate(opts *Options) (*OfflineCertificateStatus, error) {
+	path := filepath.Join(opts.FsRoot, OfflineCertificatePath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	cert, err := registration.OfflineCertificateFrom(bytes.NewReader(data), true)
+	if err != nil {
+		return &OfflineCertificateStatus{State: OfflineCertificateInvalid}, nil
+	}
+	status, err := offlineCertificateStatus(cert, opts)
+	if err != nil {
+		return &OfflineCertificateStatus{State: OfflineCertificateInvalid}, nil
+	}
+	return &status, nil
+}
+
+// systemUUID returns the UUID of this system. Only the uuid collector is run
+// since the other ones are not needed to check a certificate.
+func systemUUID() (string, error) {
+	arch, err := collectors.DetectArchitecture()
+	if err != nil {
+		return "", err
+	}
+	result, err := collectors.CollectInformation(arch, []collectors.Collector{collectors.UUID{}})
+	if err != nil {
+		return "", err
+	}
+	return collectors.FromResult(result, "uuid", ""), nil
+}
+
+// offlineCertificateStatus checks the signature, the system it has been issued
+// for and the expiration date of the given certificate.
+func offlineCertificateStatus(cert *registration.OfflineCertificate, opts *Options) (OfflineCertificateStatus, error) {
+	status := OfflineCertificateStatus{State: OfflineCertificateInvalid}
+
+	ring, err := offlineKeyRing(opts)
+	if err != nil {
+		return status, err
+	}
+	valid, err := cert.IsValidWith(ring)
+	if errors.Is(err, registration.ErrUnsupportedCertificate) || errors.Is(err, registration.ErrUnknownKey) {
+		slog.Debug("Offline registration certificate rejected", "error", err)
+		status.State = OfflineCertificateUnsupported
+		return status, nil
+	} else if err != nil {
+		return status, fmt.Errorf("%w: %v", ErrOfflineCertificate, err)
+	}
+	if !valid {
+		return status, nil
+	}
+
+	payload, err := cert.ExtractPayload()
+	if err != nil {
+		return status, fmt.Errorf("%w: %v", ErrOfflineCertificate, err)
+	}
+	status.Subscription = payload.SubscriptionInfo.Name
+	status.Kind = payload.SubscriptionInfo.Kind
+	status.StartsAt = payload.SubscriptionInfo.StartsAt
+	status.ExpiresAt = payload.SubscriptionInfo.ExpiresAt
+	status.Notifications = payload.SubscriptionInfo.Notifications
+
+	uuid, err := localSystemUUID()
+	if err != nil {
+		return status, fmt.Errorf("could not fetch system's UUID: %v", err)
+	}
+	if matches, err := cert.UUIDMatches(uuid); err != nil {
+		return status, fmt.Errorf("%w: %v", ErrOfflineCertificate, err)
+	} else if !matches {
+		status.State = OfflineCertificateUUIDMismatch
+		return status, nil
+	}
+
+	if err := cert.ValidateAt(localNow()); errors.Is(err, registration.ErrCertificateNotYetValid) {
+		status.State = OfflineCertificateNotYetValid
+		return status, nil
+	} else if errors.Is(err, registration.ErrCertificateExpired) {
+		status.State = OfflineCertificateExpired
+		return status, nil
+	} else if err != nil {
+		return status, fmt.Errorf("%w: %v", ErrOfflineCertificate, err)
+	}
+
+	status.State = OfflineCertificateValid
+	return status, nil
+}
+
+// offlineKeyRing returns the keys trusted to sign offline registration
+// certificates: the ones from SCC plus the ones supplied by the administrator
+// in `OfflineKeysDir`.
+func offlineKeyRing(opts *Options) (*registration.KeyRing, error) {
+	ring, err := registration.DefaultKeyRing()
+	if err != nil {
+		return nil, err
+	}
+	if err := ring.AddFromDir(filepath.Join(opts.FsRoot, OfflineKeysDir)); err != nil {
+		return nil, fmt.Errorf("could not load offline certificate keys: %w", err)
+	}
+	return ring, nil
+}
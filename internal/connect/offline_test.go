@@ -0,0 +1,158 @@
+package connect
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/collectors"
+	"github.com/SUSE/connect-ng/internal/testutil"
+	collectorsconfig "github.com/SUSE/connect-ng/pkg/collectors"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+)
+
+const offlineCertificateUUID = "3a4d46b4-0b06-488f-8d20-a931d398d357"
+
+func mockOfflineSystem(t *testing.T, uuid string, now time.Time) {
+	t.Helper()
+
+	origInfo, origUUID, origNow := localSystemInformation, localSystemUUID, localNow
+	t.Cleanup(func() {
+		localSystemInformation, localSystemUUID, localNow = origInfo, origUUID, origNow
+	})
+	localSystemInformation = func(string, collectorsconfig.CollectorOptions) (collectors.Result, error) {
+		return collectors.Result{"uuid": uuid, "hostname": "air-gapped", "cpus": 4}, nil
+	}
+	localSystemUUID = func() (string, error) { return uuid, nil }
+	localNow = func() time.Time { return now }
+}
+
+func offlineCertificateFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "offline.cert")
+	data := testutil.Fixture(t, "pkg/registration/offline_certificate/valid.cert")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("could not write certificate: %v", err)
+	}
+	return path
+}
+
+func TestWriteOfflineRequest(t *testing.T) {
+	assert := assert.New(t)
+	mockOfflineSystem(t, offlineCertificateUUID, time.Now())
+
+	opts := DefaultOptions()
+	opts.Product = registration.Product{Identifier: "SLES", Version: "15.6", Arch: "x86_64"}
+
+	buf := bytes.Buffer{}
+	assert.NoError(WriteOfflineRequest(opts, &buf))
+
+	data, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(buf.Bytes())))
+	assert.NoError(err)
+	request := registration.OfflineRequest{}
+	assert.NoError(json.Unmarshal(data, &request))
+	assert.Equal("SLES", request.Product.Identifier)
+	assert.Equal("15.6", request.Product.Version)
+	assert.Equal("air-gapped", request.SystemInformation["hostname"])
+}
+
+func TestImportOfflineCertificate(t *testing.T) {
+	assert := assert.New(t)
+	mockOfflineSystem(t, offlineCertificateUUID, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	opts.Token = "some-scc-regcode"
+
+	status, err := ImportOfflineCertificate(opts, offlineCertificateFile(t))
+	assert.NoError(err)
+	assert.Equal(OfflineCertificateValid, status.State)
+	assert.Equal("Rancher PRIME unlimited", status.Subscription)
+	assert.FileExists(filepath.Join(opts.FsRoot, OfflineCertificatePath))
+
+	// The stored certificate is checked against the running system, without
+	// running all the collectors.
+	localSystemInformation = func(string, collectorsconfig.CollectorOptions) (collectors.Result, error) {
+		t.Fatal("collectors should not run")
+		return nil, nil
+	}
+	stored, err := StoredOfflineCertificate(opts)
+	assert.NoError(err)
+	assert.True(stored.Valid())
+
+	// E.g. the certificate has been copied to another system.
+	localSystemUUID = func() (string, error) { return "another-uuid", nil }
+	stored, err = StoredOfflineCertificate(opts)
+	assert.NoError(err)
+	assert.Equal(OfflineCertificateUUIDMismatch, stored.State)
+}
+
+func TestImportOfflineCertificateRejected(t *testing.T) {
+	assert := assert.New(t)
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	path := offlineCertificateFile(t)
+
+	_, err := ImportOfflineCertificate(opts, path)
+	assert.ErrorIs(err, ErrOfflineCertificateRegcode)
+
+	opts.Token = "another-regcode"
+	mockOfflineSystem(t, offlineCertificateUUID, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	status, err := ImportOfflineCertificate(opts, path)
+	assert.ErrorIs(err, ErrOfflineCertificate)
+	assert.Equal(OfflineCertificateRegcodeMismatch, status.State)
+
+	opts.Token = "some-scc-regcode"
+	mockOfflineSystem(t, "another-uuid", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	status, err = ImportOfflineCertificate(opts, path)
+	assert.ErrorIs(err, ErrOfflineCertificate)
+	assert.Equal(OfflineCertificateUUIDMismatch, status.State)
+
+	mockOfflineSystem(t, offlineCertificateUUID, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC))
+	status, err = ImportOfflineCertificate(opts, path)
+	assert.ErrorIs(err, ErrOfflineCertificate)
+	assert.Equal(OfflineCertificateExpired, status.State)
+
+	mockOfflineSystem(t, offlineCertificateUUID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	status, err = ImportOfflineCertificate(opts, path)
+	assert.ErrorIs(err, ErrOfflineCertificate)
+	assert.Equal(OfflineCertificateNotYetValid, status.State)
+
+	assert.NoFileExists(filepath.Join(opts.FsRoot, OfflineCertificatePath))
+
+	invalid := filepath.Join(t.TempDir(), "invalid.cert")
+	assert.NoError(os.WriteFile(invalid, testutil.Fixture(t, "pkg/registration/offline_certificate/invalid.cert"), 0600))
+	status, err = ImportOfflineCertificate(opts, invalid)
+	assert.ErrorIs(err, ErrOfflineCertificate)
+	assert.Equal(OfflineCertificateInvalid, status.State)
+}
+
+func TestApplyOfflineCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	products := []registration.Product{
+		{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"},
+		{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true},
+	}
+	statuses := buildStatuses(products, map[string]*registration.Activation{})
+	cert := &OfflineCertificateStatus{
+		State:        OfflineCertificateValid,
+		Subscription: "SUSE Linux Enterprise Server",
+		ExpiresAt:    time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	applyOfflineCertificate(statuses, products, cert)
+
+	assert.Equal(notRegistered, statuses[0].Status)
+	assert.Empty(statuses[0].OfflineCertificate)
+	assert.Equal(registeredOffline, statuses[1].Status)
+	assert.Equal(OfflineCertificateValid, statuses[1].OfflineCertificate)
+	assert.Equal("SUSE Linux Enterprise Server", statuses[1].Name)
+
+	text, err := getStatusText(statuses)
+	assert.NoError(err)
+	assert.Contains(text, "Offline registration certificate: valid")
+}
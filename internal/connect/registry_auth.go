@@ -4,11 +4,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/SUSE/connect-ng/internal/util"
 )
 
 // NOTE: Podman will read the same configuration file as the docker cli.
@@ -104,7 +103,7 @@ func (cfg *RegistryAuthConfig) isConfigured(registry string) bool {
 
 func (cfg *RegistryAuthConfig) Set(registry string, login string, password string) {
 	if cfg.isConfigured(registry) {
-		util.Debug.Printf("`%s` is already configured. Skipping", registry)
+		slog.Debug("Registry is already configured, skipping", "registry", registry)
 		return
 	}
 
@@ -148,33 +147,33 @@ func setupRegistryAuthentication(login string, password string) {
 		// This also fails if the file does not yet exist
 		// so we continue to create it.
 		if err := config.LoadFrom(path); err != nil {
-			util.Debug.Printf("Could not load `%s`: %s", path, err)
+			slog.Debug("Could not load registry configuration", "path", path, "error", err)
 		}
 		config.Set(DEFAULT_SUSE_REGISTRY, login, password)
 
 		if err := mkDirAll(dir, 0755); err != nil {
-			util.Debug.Printf("Could not create directory `%s`: %s", dir, err)
+			slog.Debug("Could not create directory", "path", dir, "error", err)
 			return
 		}
 
 		if err := config.SaveTo(path); err != nil {
-			util.Debug.Printf("Could not save config to `%s`: %s", path, err)
+			slog.Debug("Could not save registry configuration", "path", path, "error", err)
 			return
 		}
 
-		util.Debug.Printf("SUSE registry system authentication written to `%s`", path)
+		slog.Debug("SUSE registry system authentication written", "path", path)
 	}
 }
 
-func removeRegistryAuthentication(login string, password string) {
+func removeRegistryAuthentication(login string, password string) error {
 	config := newRegistryAuthConfig()
 
 	if home, err := userHome(); err == nil {
 		path := filepath.Join(home, DEFAULT_DOCKER_CLIENT_CONFIG)
 
 		if err := config.LoadFrom(path); err != nil {
-			util.Debug.Printf("Could not load `%s`: %s", path, err)
-			return
+			slog.Debug("Could not load registry configuration", "path", path, "error", err)
+			return nil
 		}
 		l, p, found := config.Get(DEFAULT_SUSE_REGISTRY)
 
@@ -185,11 +184,12 @@ func removeRegistryAuthentication(login string, password string) {
 			config.Remove(DEFAULT_SUSE_REGISTRY)
 
 			if err := config.SaveTo(path); err != nil {
-				util.Debug.Printf("Could not save config to `%s`: %s", path, err)
+				slog.Debug("Could not save registry configuration", "path", path, "error", err)
+				return err
 			}
 
-			util.Debug.Printf("SUSE registry system authentication removed from `%s`", path)
+			slog.Debug("SUSE registry system authentication removed", "path", path)
 		}
 	}
-
+	return nil
 }
@@ -0,0 +1,163 @@
+package connect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FormatKind is the kind of output requested through `--format`.
+type FormatKind int
+
+const (
+	// The default human readable output of each command.
+	FormatText FormatKind = iota
+	FormatJSON
+	FormatYAML
+	FormatTable
+	// A user supplied Go template (see text/template).
+	FormatTemplate
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown output format, use one of: text, json, yaml, table or template=<go-template>")
+)
+
+// OutputFormat describes how the output of a command should be rendered.
+type OutputFormat struct {
+	Kind FormatKind
+
+	// The template to be executed for FormatTemplate.
+	Template string
+}
+
+// ParseOutputFormat parses the value given to `--format`: "text", "json",
+// "yaml", "table" or "template=<go-template>".
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	if tpl, ok := strings.CutPrefix(value, "template="); ok {
+		if _, err := template.New("output").Funcs(templateFuncs).Parse(tpl); err != nil {
+			return OutputFormat{}, fmt.Errorf("invalid template: %w", err)
+		}
+		return OutputFormat{Kind: FormatTemplate, Template: tpl}, nil
+	}
+
+	switch strings.ToLower(value) {
+	case "text":
+		return OutputFormat{Kind: FormatText}, nil
+	case "json":
+		return OutputFormat{Kind: FormatJSON}, nil
+	case "yaml":
+		return OutputFormat{Kind: FormatYAML}, nil
+	case "table":
+		return OutputFormat{Kind: FormatTable}, nil
+	}
+	return OutputFormat{}, ErrUnknownFormat
+}
+
+// tabular is implemented by the data which can be rendered as a table.
+type tabular interface {
+	tableHeader() []string
+	tableRows() [][]string
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"join": strings.Join,
+}
+
+// render renders the given data with the given format. The `text` function
+// provides the default human readable output of the command. JSON and YAML
+// documents use the keys of the JSON representation of the data, so all
+// formats expose the same fields.
+func render(data any, format OutputFormat, text func() (string, error)) (string, error) {
+	switch format.Kind {
+	case FormatText:
+		return text()
+	case FormatJSON:
+		out, err := json.Marshal(data)
+		return string(out), err
+	case FormatYAML:
+		return renderYAML(data)
+	case FormatTable:
+		t, ok := data.(tabular)
+		if !ok {
+			return "", fmt.Errorf("the table format is not supported by this command")
+		}
+		return renderTable(t), nil
+	case FormatTemplate:
+		tpl, err := template.New("output").Funcs(templateFuncs).Parse(format.Template)
+		if err != nil {
+			return "", err
+		}
+		output := bytes.Buffer{}
+		if err := tpl.Execute(&output, data); err != nil {
+			return "", err
+		}
+		return output.String(), nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// renderYAML converts the JSON representation of the data into YAML, keeping
+// the order of the keys.
+func renderYAML(data any) (string, error) {
+	jsn, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	node := yaml.Node{}
+	if err := yaml.Unmarshal(jsn, &node); err != nil {
+		return "", err
+	}
+	blockStyle(&node)
+
+	out, err := yaml.Marshal(&node)
+	return strings.TrimSuffix(string(out), "\n"), err
+}
+
+// blockStyle drops the flow style of the JSON input, so the YAML output uses
+// the block style. Scalars are quoted only when required.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func renderTable(t tabular) string {
+	output := bytes.Buffer{}
+	w := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.tableHeader(), "\t"))
+	for _, row := range t.tableRows() {
+		for i, cell := range row {
+			if cell == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	return strings.TrimSuffix(output.String(), "\n")
+}
+
+// WriteOutput prints the output of a command into the standard output, or
+// into the file at the given path if not empty. New files are only readable by
+// the owner since the output may include registration codes, existing files
+// keep their mode.
+func WriteOutput(output, path string) error {
+	if path == "" {
+		fmt.Println(output)
+		return nil
+	}
+	return os.WriteFile(path, []byte(output+"\n"), 0600)
+}
@@ -0,0 +1,126 @@
+package connect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+)
+
+func renderStatuses() statusList {
+	return statusList{
+		{Identifier: "SLES", Version: "15.6", Arch: "x86_64", Status: registered, Name: "SUSE Linux Enterprise Server",
+			Type: "full", ProductLine: "sles", ReleaseStage: "released", ExpiresAt: "2027-01-01 00:00:00 UTC"},
+		{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64", Status: notRegistered},
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	for value, kind := range map[string]FormatKind{"text": FormatText, "json": FormatJSON, "YAML": FormatYAML, "table": FormatTable} {
+		format, err := ParseOutputFormat(value)
+		assert.NoError(err)
+		assert.Equal(kind, format.Kind)
+	}
+
+	format, err := ParseOutputFormat("template={{ .Identifier }}")
+	assert.NoError(err)
+	assert.Equal(OutputFormat{Kind: FormatTemplate, Template: "{{ .Identifier }}"}, format)
+
+	_, err = ParseOutputFormat("template={{ .Identifier")
+	assert.ErrorContains(err, "invalid template")
+
+	_, err = ParseOutputFormat("xml")
+	assert.ErrorIs(err, ErrUnknownFormat)
+}
+
+func TestRenderFormats(t *testing.T) {
+	assert := assert.New(t)
+	text := func() (string, error) { return "text output", nil }
+
+	out, err := render(renderStatuses(), OutputFormat{Kind: FormatText}, text)
+	assert.NoError(err)
+	assert.Equal("text output", out)
+
+	out, err = render(renderStatuses()[1:], OutputFormat{Kind: FormatJSON}, text)
+	assert.NoError(err)
+	assert.Equal(`[{"identifier":"sle-module-basesystem","version":"15.6","arch":"x86_64","status":"Not Registered"}]`, out)
+
+	// Keys keep the order of the JSON document, versions stay strings.
+	out, err = render(renderStatuses()[1:], OutputFormat{Kind: FormatYAML}, text)
+	assert.NoError(err)
+	assert.Equal("- identifier: sle-module-basesystem\n  version: \"15.6\"\n  arch: x86_64\n  status: Not Registered", out)
+
+	out, err = render(renderStatuses(), OutputFormat{Kind: FormatTemplate, Template: `{{ range . }}{{ .Identifier }}={{ .Status }};{{ end }}`}, text)
+	assert.NoError(err)
+	assert.Equal("SLES=Registered;sle-module-basesystem=Not Registered;", out)
+
+	out, err = render(renderStatuses(), OutputFormat{Kind: FormatTemplate, Template: `{{ json (index . 1).Version }}`}, text)
+	assert.NoError(err)
+	assert.Equal(`"15.6"`, out)
+
+	_, err = render(map[string]string{}, OutputFormat{Kind: FormatTable}, text)
+	assert.ErrorContains(err, "table format is not supported")
+}
+
+func TestRenderStatusTable(t *testing.T) {
+	assert := assert.New(t)
+
+	out, err := render(renderStatuses(), OutputFormat{Kind: FormatTable}, nil)
+	assert.NoError(err)
+	assert.Equal(""+
+		"PRODUCT                            STATUS          SUBSCRIPTION                  TYPE  PRODUCT LINE  RELEASE STAGE  EXPIRES AT\n"+
+		"SLES/15.6/x86_64                   Registered      SUSE Linux Enterprise Server  full  sles          released       2027-01-01 00:00:00 UTC\n"+
+		"sle-module-basesystem/15.6/x86_64  Not Registered  -                             -     -             -              -", out)
+}
+
+func TestRenderExtensionTable(t *testing.T) {
+	assert := assert.New(t)
+
+	activations := []*registration.Activation{
+		{Type: "full", Product: &registration.Product{Identifier: "sle-ha", Version: "15.6", Arch: "x86_64"}},
+	}
+	product := &registration.Product{Identifier: "SLES", Version: "15.6", Arch: "x86_64", Extensions: []registration.Product{
+		{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64", FriendlyName: "Basesystem", Free: true, Available: true, Extensions: []registration.Product{
+			{Identifier: "sle-ha", Version: "15.6", Arch: "x86_64", FriendlyName: "HA", Available: true, ReleaseStage: "released"},
+		}},
+	}}
+	tree := extensionTree(activations, product)
+	assert.Equal("full", tree.Extensions[0].Extensions[0].SubscriptionType)
+
+	assert.Equal([][]string{
+		{"sle-module-basesystem/15.6/x86_64", "Basesystem", "no", "yes", "yes", "", ""},
+		{"  sle-ha/15.6/x86_64", "HA", "yes", "yes", "no", "full", "released"},
+	}, tree.tableRows())
+}
+
+func TestSystemInformationTable(t *testing.T) {
+	assert := assert.New(t)
+
+	info := systemInformation{"hostname": "host", "cpus": 4, "mod_list": []string{"a", "b"}, "empty": nil}
+	assert.Equal([][]string{
+		{"cpus", "4"},
+		{"empty", ""},
+		{"hostname", "host"},
+		{"mod_list", `["a","b"]`},
+	}, info.tableRows())
+}
+
+func TestWriteOutputMode(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "status.json")
+	assert.NoError(WriteOutput(`{"regcode":"secret"}`, path))
+	info, err := os.Stat(path)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	assert.NoError(os.Chmod(path, 0640))
+	assert.NoError(WriteOutput("again", path))
+	info, err = os.Stat(path)
+	assert.NoError(err)
+	assert.Equal(os.FileMode(0640), info.Mode().Perm())
+}
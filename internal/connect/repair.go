@@ -0,0 +1,230 @@
+package connect
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/i18n"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+var (
+	ErrRepairIncomplete  = errors.New("Some issues could not be repaired")
+	ErrRepairInteractive = errors.New("Standard input seems to be closed, please use the '--non-interactive' option")
+
+	// test method overwrites
+	localSyncProducts           = SyncProducts
+	repairInput       io.Reader = os.Stdin
+	repairOutput      io.Writer = os.Stdout
+)
+
+// repairStep is a single change proposed by `Repair`.
+type repairStep struct {
+	description string
+	product     registration.Product
+	action      ProductAction
+	service     ServiceOut
+	apply       func() error
+
+	// Whether the step installs the release package of the product.
+	releasePackage bool
+}
+
+// Repair fixes the drift found by `Verify`: orphaned services and the ones
+// pointing to a previous registration server are removed, missing services
+// are added again from the activations, service credentials diverging from
+// the system credentials are rewritten and the release packages of activated
+// products are installed again. Finally the installed products are
+// synchronized with the registration server. Unless `nonInteractive` is set,
+// each step is confirmed by the user first. Issues which cannot be repaired
+// automatically (e.g. products which are not activated) are left untouched.
+// An error wrapping `ErrRepairIncomplete` is returned if any step failed.
+func Repair(api WrappedAPI, opts *Options, nonInteractive bool) (*RegisterOut, error) {
+	out := newRegisterOut(OperationRepair)
+
+	result, err := Verify(api, opts)
+	if err != nil {
+		return out, out.Finish("", err)
+	}
+	if !result.Drift {
+		return out, out.Finish(i18n.T("No drift detected, nothing to repair"), nil)
+	}
+
+	steps, manual := repairSteps(opts, result)
+	steps = append(steps, syncProductsStep(api))
+
+	var scanner *bufio.Scanner
+	if !nonInteractive {
+		scanner = bufio.NewScanner(repairInput)
+	}
+
+	failed := 0
+	for _, step := range steps {
+		if scanner != nil {
+			ok, err := confirmRepair(opts, scanner, step.description)
+			if err != nil {
+				return out, out.Finish("", err)
+			}
+			if !ok {
+				entry := out.start(step.product, ActionSkip)
+				entry.Service = step.service
+				entry.finish(nil)
+				continue
+			}
+		}
+
+		opts.Print(fmt.Sprintf("\n%s ...", step.description))
+		entry := out.start(step.product, step.action)
+		entry.Service = step.service
+		err := step.apply()
+		if step.releasePackage {
+			entry.ReleasePackage = ReleasePackageInstalled
+			if err != nil {
+				entry.ReleasePackage = ReleasePackageFailed
+			}
+		}
+		if err := entry.finish(err); err != nil {
+			opts.Print(fmt.Sprintf("-> %v", err))
+			failed++
+		}
+	}
+
+	for _, finding := range manual {
+		opts.Print(i18n.Tf("\nCannot be repaired automatically: %s", finding.Message))
+	}
+	if failed > 0 {
+		return out, out.Finish("", fmt.Errorf("%w: %d step(s) failed", ErrRepairIncomplete, failed))
+	}
+	if len(manual) > 0 {
+		return out, out.Finish(i18n.Tf("Repaired system, %d issue(s) require manual intervention", len(manual)), nil)
+	}
+	return out, out.Finish(i18n.T("Successfully repaired system"), nil)
+}
+
+// repairSteps returns the steps fixing the given findings, in the order they
+// have to be applied, plus the findings which cannot be repaired.
+func repairSteps(opts *Options, result *VerifyResult) ([]repairStep, []DriftFinding) {
+	removals, services, credentials, packages := []repairStep{}, []repairStep{}, []repairStep{}, []repairStep{}
+	manual := []DriftFinding{}
+	readded := NewStringSet()
+
+	for _, f := range result.Findings {
+		switch f.Kind {
+		case DriftServiceOrphaned, DriftServiceOldServer:
+			removals = append(removals, repairStep{
+				description: i18n.Tf("Remove service %s (%s)", f.Service, f.URL),
+				action:      ActionRemove,
+				service:     ServiceOut{Name: f.Service, Url: f.URL},
+				apply:       func() error { return localRemoveService(f.Service) },
+			})
+		case DriftServiceMissing, DriftServiceURLMismatch:
+			// Adding the service also writes its credentials.
+			readded.Add(f.Service)
+			services = append(services, repairStep{
+				description: i18n.Tf("Add service %s of %s", f.Service, f.Product),
+				product:     tripletProduct(f.Product),
+				action:      ActionRepair,
+				service:     ServiceOut{Name: f.Service, Url: f.URL},
+				apply: func() error {
+					return localAddService(f.URL, f.Service, !opts.NoZypperRefresh, opts.Insecure)
+				},
+			})
+		case DriftCredentialsMissing, DriftCredentialsInvalid, DriftCredentialsStale:
+			credentials = append(credentials, repairStep{
+				description: i18n.Tf("Rewrite the credentials of service %s from %s", f.Service, cred.GlobalCredentialsFile),
+				product:     tripletProduct(f.Product),
+				action:      ActionRepair,
+				service:     ServiceOut{Name: f.Service},
+				apply:       func() error { return rewriteServiceCredentials(opts, f.Service) },
+			})
+		case DriftProductNotInstalled:
+			product := tripletProduct(f.Product)
+			packages = append(packages, repairStep{
+				description: i18n.Tf("Install the release package of %s", f.Product),
+				product:     product,
+				action:      ActionRepair,
+				apply: func() error {
+					return localInstallReleasePackage(product.Identifier, opts.AutoImportRepoKeys, true)
+				},
+				releasePackage: true,
+			})
+		default:
+			manual = append(manual, f)
+		}
+	}
+
+	filtered := []repairStep{}
+	for _, step := range credentials {
+		if !readded.Contains(step.service.Name) {
+			filtered = append(filtered, step)
+		}
+	}
+
+	steps := append(removals, services...)
+	steps = append(steps, filtered...)
+	return append(steps, packages...), manual
+}
+
+// syncProductsStep synchronizes the products installed after repairing with
+// the activations on the registration server.
+func syncProductsStep(api WrappedAPI) repairStep {
+	return repairStep{
+		description: i18n.T("Synchronize the installed products with the registration server"),
+		action:      ActionRepair,
+		apply: func() error {
+			products, err := localInstalledProducts()
+			if err != nil {
+				return err
+			}
+			_, err = localSyncProducts(api.GetConnection(), products)
+			return err
+		},
+	}
+}
+
+func rewriteServiceCredentials(opts *Options, service string) error {
+	system, err := cred.ReadCredentials(cred.SystemCredentialsPath(opts.FsRoot))
+	if err != nil {
+		return err
+	}
+	path := cred.ServiceCredentialsPath(service, opts.FsRoot)
+	slog.Debug("Rewriting credentials", "service", service, "path", path)
+	return cred.CreateCredentials(system.Username, system.Password, system.SystemToken, path)
+}
+
+func tripletProduct(triplet string) registration.Product {
+	product, err := registration.FromTriplet(triplet)
+	if err != nil {
+		return registration.Product{}
+	}
+	return product
+}
+
+// confirmRepair asks the user whether the given step should be applied.
+func confirmRepair(opts *Options, scanner *bufio.Scanner, description string) (bool, error) {
+	out := repairOutput
+	// Keep stdout clean for the JSON document.
+	if opts.OutputKind == JSON {
+		out = os.Stderr
+	}
+
+	for {
+		fmt.Fprint(out, "\n"+i18n.Tf("%s? [y/n]: ", description))
+		if !scanner.Scan() {
+			fmt.Fprint(out, "\n")
+			return false, ErrRepairInteractive
+		}
+		switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+		case "y", "yes", i18n.T("y"), i18n.T("yes"):
+			return true, nil
+		case "n", "no", i18n.T("n"), i18n.T("no"):
+			return false, nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package connect
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockRepair sets up a system where the service of the basesystem module is
+// missing, the credentials of SLES are stale, the release package of the
+// legacy module has been removed and a service of a previous RMT server is
+// left behind. All the changes are recorded in the returned slice.
+func mockRepair(t *testing.T, input string) (*Options, *MockWrappedAPI, *[]string) {
+	t.Helper()
+
+	sles := verifyActivation("SLES", true)
+	basesystem := verifyActivation("sle-module-basesystem", false)
+	legacy := verifyActivation("sle-module-legacy", false)
+	opts, api := mockVerify(t,
+		[]*registration.Activation{sles, basesystem, legacy},
+		[]registration.Product{*sles.Product, *basesystem.Product},
+		[]zypper.ZypperService{
+			{Name: sles.Metadata.Name, URL: sles.Metadata.URL},
+			{Name: legacy.Metadata.Name, URL: legacy.Metadata.URL},
+			{Name: "old-rmt", URL: "https://rmt.example.com/services/4"},
+		})
+	writeServiceCredentials(t, opts, sles.Metadata.Name, "SCC_other")
+	writeServiceCredentials(t, opts, legacy.Metadata.Name, "SCC_login")
+	writeServiceCredentials(t, opts, "old-rmt", "SCC_login")
+
+	origAdd, origRemove, origInstall, origSync := localAddService, localRemoveService, localInstallReleasePackage, localSyncProducts
+	origInput, origOutput := repairInput, repairOutput
+	t.Cleanup(func() {
+		localAddService, localRemoveService, localInstallReleasePackage, localSyncProducts = origAdd, origRemove, origInstall, origSync
+		repairInput, repairOutput = origInput, origOutput
+	})
+
+	changes := []string{}
+	localAddService = func(url, name string, _ bool, _ bool) error {
+		changes = append(changes, "add "+name+" "+url)
+		return nil
+	}
+	localRemoveService = func(name string) error {
+		changes = append(changes, "remove "+name)
+		return nil
+	}
+	localInstallReleasePackage = func(identifier string, _ bool, _ bool) error {
+		changes = append(changes, "install "+identifier)
+		return nil
+	}
+	localSyncProducts = func(_ connection.Connection, products []registration.Product) ([]registration.Product, error) {
+		changes = append(changes, "sync")
+		return products, nil
+	}
+	repairInput = strings.NewReader(input)
+	repairOutput = io.Discard
+	return opts, api, &changes
+}
+
+func TestRepairNonInteractive(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api, changes := mockRepair(t, "")
+	out, err := Repair(api, opts, true)
+	assert.NoError(err)
+	assert.True(out.Success)
+	assert.Equal(OperationRepair, out.Operation)
+
+	assert.Equal([]string{
+		"remove old-rmt",
+		"add sle-module-basesystem_15.6_x86_64 https://scc.suse.com/access/services/1?credentials=sle-module-basesystem_15.6_x86_64",
+		"install sle-module-legacy",
+		"sync",
+	}, *changes)
+
+	creds, err := cred.ReadCredentials(cred.ServiceCredentialsPath("SLES_15.6_x86_64", opts.FsRoot))
+	assert.NoError(err)
+	assert.Equal("SCC_login", creds.Username)
+
+	// remove, add, credentials, install and sync
+	assert.Len(out.Products, 5)
+	for _, entry := range out.Products {
+		assert.True(entry.Success)
+	}
+	assert.Equal(ActionRemove, out.Products[0].Action)
+	assert.Equal("old-rmt", out.Products[0].Service.Name)
+	assert.Equal("sle-module-basesystem", out.Products[1].Product.Identifier)
+	assert.Equal(ReleasePackageInstalled, out.Products[3].ReleasePackage)
+}
+
+func TestRepairInteractive(t *testing.T) {
+	assert := assert.New(t)
+
+	// decline removing the old service and installing the release package
+	opts, api, changes := mockRepair(t, "n\nmaybe\ny\ny\nno\nyes\n")
+	output := bytes.Buffer{}
+	repairOutput = &output
+
+	out, err := Repair(api, opts, false)
+	assert.NoError(err)
+	assert.Equal([]string{
+		"add sle-module-basesystem_15.6_x86_64 https://scc.suse.com/access/services/1?credentials=sle-module-basesystem_15.6_x86_64",
+		"sync",
+	}, *changes)
+	assert.Equal(ActionSkip, out.Products[0].Action)
+	assert.Equal(ActionSkip, out.Products[3].Action)
+	assert.Contains(output.String(), "Remove service old-rmt (https://rmt.example.com/services/4)? [y/n]: ")
+}
+
+func TestRepairClosedInput(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api, changes := mockRepair(t, "y\n")
+	out, err := Repair(api, opts, false)
+	assert.ErrorIs(err, ErrRepairInteractive)
+	assert.False(out.Success)
+	assert.Equal([]string{"remove old-rmt"}, *changes)
+}
+
+func TestRepairFailedStep(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api, changes := mockRepair(t, "")
+	localInstallReleasePackage = func(string, bool, bool) error {
+		return errors.New("no provider of sle-module-legacy-release found")
+	}
+
+	out, err := Repair(api, opts, true)
+	assert.ErrorIs(err, ErrRepairIncomplete)
+	assert.Equal(ErrorCodeRepairIncomplete, out.ErrorCode)
+	// the remaining steps are still applied
+	assert.Equal("sync", (*changes)[len(*changes)-1])
+	assert.False(out.Products[3].Success)
+	assert.Equal(ReleasePackageFailed, out.Products[3].ReleasePackage)
+	assert.Contains(out.Products[3].Error, "no provider")
+}
+
+func TestRepairNoDrift(t *testing.T) {
+	assert := assert.New(t)
+
+	sles := verifyActivation("SLES", true)
+	opts, api := mockVerify(t,
+		[]*registration.Activation{sles},
+		[]registration.Product{*sles.Product},
+		[]zypper.ZypperService{{Name: sles.Metadata.Name, URL: sles.Metadata.URL}})
+	writeServiceCredentials(t, opts, sles.Metadata.Name, "SCC_login")
+
+	out, err := Repair(api, opts, false)
+	assert.NoError(err)
+	assert.Equal("No drift detected, nothing to repair", out.Message)
+	assert.Empty(out.Products)
+}
+
+func TestRepairManualIssues(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := mockVerify(t, []*registration.Activation{},
+		[]registration.Product{{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true}},
+		[]zypper.ZypperService{})
+	origSync := localSyncProducts
+	t.Cleanup(func() { localSyncProducts = origSync })
+	localSyncProducts = func(_ connection.Connection, products []registration.Product) ([]registration.Product, error) {
+		return products, nil
+	}
+
+	out, err := Repair(api, opts, true)
+	assert.NoError(err)
+	assert.Equal("Repaired system, 1 issue(s) require manual intervention", out.Message)
+}
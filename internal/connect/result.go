@@ -0,0 +1,317 @@
+package connect
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+// ProductAction describes what has been attempted on a product.
+type ProductAction string
+
+const (
+	// The product has been activated on the server (this includes re-activating
+	// the installed version of a product on rollback).
+	ActionActivate ProductAction = "activate"
+
+	// The product was considered but nothing has been done with it.
+	ActionSkip ProductAction = "skip"
+
+	// The product has been deactivated on the server.
+	ActionDeactivate ProductAction = "deactivate"
+
+	// The service of the product has been removed locally without contacting
+	// the server.
+	ActionRemove ProductAction = "remove"
+
+	// The local setup of the product (service, credentials or release
+	// package) has been fixed.
+	ActionRepair ProductAction = "repair"
+
+	// The product has been upgraded on the server by a migration.
+	ActionUpgrade ProductAction = "upgrade"
+)
+
+// ReleasePackageOutcome describes what happened with the release package of a
+// product.
+type ReleasePackageOutcome string
+
+const (
+	ReleasePackageInstalled ReleasePackageOutcome = "installed"
+	ReleasePackageRemoved   ReleasePackageOutcome = "removed"
+	ReleasePackageSkipped   ReleasePackageOutcome = "skipped"
+	ReleasePackageFailed    ReleasePackageOutcome = "failed"
+)
+
+// ErrorCode is a machine-readable identifier for the errors reported by
+// SUSEConnect.
+type ErrorCode string
+
+const (
+	ErrorCodeNone                    ErrorCode = ""
+	ErrorCodeGeneric                 ErrorCode = "error"
+	ErrorCodeZypper                  ErrorCode = "zypper_error"
+	ErrorCodeConnectionRefused       ErrorCode = "connection_refused"
+	ErrorCodeConnection              ErrorCode = "connection_error"
+	ErrorCodeInvalidResponse         ErrorCode = "invalid_response"
+	ErrorCodeUnauthorized            ErrorCode = "unauthorized"
+	ErrorCodeAPI                     ErrorCode = "api_error"
+	ErrorCodeSystemNotRegistered     ErrorCode = "system_not_registered"
+	ErrorCodeBaseProductDeactivation ErrorCode = "base_product_deactivation"
+	ErrorCodeBaseProductNotFound     ErrorCode = "base_product_not_found"
+	ErrorCodeEULADeclined            ErrorCode = "eula_declined"
+	ErrorCodeCleanupIncomplete       ErrorCode = "cleanup_incomplete"
+	ErrorCodeSwitchRolledBack        ErrorCode = "switch_rolled_back"
+	ErrorCodeRepairIncomplete        ErrorCode = "repair_incomplete"
+	ErrorCodeInvalidUsage            ErrorCode = "invalid_usage"
+	ErrorCodePermissionDenied        ErrorCode = "permission_denied"
+	ErrorCodeManagedSystem           ErrorCode = "managed_system"
+	ErrorCodeSubscriptionCheck       ErrorCode = "subscription_check"
+	ErrorCodeDriftDetected           ErrorCode = "drift_detected"
+)
+
+// ErrorCodeFor returns the machine-readable error code for the given error.
+func ErrorCodeFor(err error) ErrorCode {
+	if err == nil {
+		return ErrorCodeNone
+	}
+
+	var ze zypper.ZypperError
+	var ue *url.Error
+	var je JSONError
+	var ae APIError
+	var cae *connection.ApiError
+
+	switch {
+	case errors.As(err, &ze):
+		return ErrorCodeZypper
+	case errors.As(err, &ue):
+		if errors.Is(ue, syscall.ECONNREFUSED) {
+			return ErrorCodeConnectionRefused
+		}
+		return ErrorCodeConnection
+	case errors.As(err, &je):
+		return ErrorCodeInvalidResponse
+	case errors.As(err, &cae):
+		return apiErrorCode(cae.Code)
+	case errors.As(err, &ae):
+		return apiErrorCode(ae.Code)
+	case errors.Is(err, ErrSystemNotRegistered),
+		errors.Is(err, ErrListExtensionsUnregistered),
+		errors.Is(err, ErrPingFromUnregistered):
+		return ErrorCodeSystemNotRegistered
+	case errors.Is(err, ErrBaseProductDeactivation):
+		return ErrorCodeBaseProductDeactivation
+	case errors.Is(err, zypper.ErrCannotDetectBaseProduct):
+		return ErrorCodeBaseProductNotFound
+	case errors.Is(err, ErrEULADeclined):
+		return ErrorCodeEULADeclined
+	case errors.Is(err, ErrLocalCleanupIncomplete):
+		return ErrorCodeCleanupIncomplete
+	case errors.Is(err, ErrSwitchRolledBack):
+		return ErrorCodeSwitchRolledBack
+	case errors.Is(err, ErrRepairIncomplete):
+		return ErrorCodeRepairIncomplete
+	}
+	return ErrorCodeGeneric
+}
+
+func apiErrorCode(code int) ErrorCode {
+	if code == http.StatusUnauthorized {
+		return ErrorCodeUnauthorized
+	}
+	return ErrorCodeAPI
+}
+
+// RegisterOut is the result of an operation which changes the registration
+// state of the system (register, de-register, rollback and cleanup). It is
+// what SUSEConnect prints when called with `--json`, as a JSONResult whose
+// data holds the operation and the products. See the "JSON OUTPUT" section of
+// SUSEConnect(8) for the description of this schema. Up to 1.23 the products
+// were printed at the top level of the document instead.
+type RegisterOut struct {
+	Success   bool
+	Operation string
+	Products  []*ProductService
+	Message   string
+	ErrorCode ErrorCode
+}
+
+// ProductService is the result for a single product of an operation.
+type ProductService struct {
+	Product        ProductOut            `json:"product"`
+	Service        ServiceOut            `json:"service"`
+	Action         ProductAction         `json:"action"`
+	ReleasePackage ReleasePackageOutcome `json:"release_package"`
+	Success        bool                  `json:"success"`
+	ErrorCode      ErrorCode             `json:"error_code,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	StartedAt      time.Time             `json:"started_at"`
+	DurationMS     int64                 `json:"duration_ms"`
+
+	// Operation this entry belongs to, for the log records.
+	operation string
+}
+
+type ProductOut struct {
+	Name       string `json:"name"`
+	Identifier string `json:"identifier"`
+	Version    string `json:"version"`
+	Arch       string `json:"arch"`
+}
+
+type ServiceOut struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+// Operation names as reported in RegisterOut.Operation.
+const (
+	OperationRegister     = "register"
+	OperationDeregister   = "de-register"
+	OperationRollback     = "rollback"
+	OperationCleanup      = "cleanup"
+	OperationSwitchServer = "switch-server"
+	OperationRepair       = "repair"
+)
+
+func newRegisterOut(operation string) *RegisterOut {
+	return &RegisterOut{
+		Operation: operation,
+		Products:  []*ProductService{},
+	}
+}
+
+// Starts tracking the given action on the given product. Call `finish` on the
+// returned entry once the action is done.
+func (out *RegisterOut) start(product registration.Product, action ProductAction) *ProductService {
+	entry := &ProductService{
+		Product:        productToOut(product),
+		Action:         action,
+		ReleasePackage: ReleasePackageSkipped,
+		StartedAt:      time.Now().UTC(),
+		operation:      out.Operation,
+	}
+	out.Products = append(out.Products, entry)
+	slog.Debug("Starting", "operation", out.Operation, "product", product.ToTriplet(), "action", action)
+	return entry
+}
+
+// Records that the given product has been skipped.
+func (out *RegisterOut) skip(product registration.Product) {
+	entry := out.start(product, ActionSkip)
+	entry.finish(nil)
+}
+
+// Marks the operation as finished with the given error (if any) and returns
+// the error back.
+func (out *RegisterOut) Finish(message string, err error) error {
+	out.Success = err == nil
+	out.ErrorCode = ErrorCodeFor(err)
+	if err != nil {
+		out.Message = err.Error()
+	} else {
+		out.Message = message
+	}
+	return err
+}
+
+// Returns the JSON representation of this result.
+func (out *RegisterOut) JSON() string {
+	data, err := json.Marshal(out)
+	if err != nil {
+		return encodingFailure
+	}
+	return string(data)
+}
+
+// registerOutData is the "data" of a RegisterOut.
+type registerOutData struct {
+	Operation string            `json:"operation"`
+	Products  []*ProductService `json:"products"`
+}
+
+func (out *RegisterOut) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&JSONResult{
+		Success:   out.Success,
+		Message:   out.Message,
+		Data:      registerOutData{Operation: out.Operation, Products: out.Products},
+		ErrorCode: out.ErrorCode,
+	})
+}
+
+const encodingFailure = `{"success":false,"message":"could not encode the result","data":null,"error_code":"error"}`
+
+// JSONResult is the document printed by every SUSEConnect command when called
+// with `--json`, both on success and on failure. The output of each command
+// goes into Data. See the "JSON OUTPUT" section of SUSEConnect(8) and keep it
+// backwards compatible.
+type JSONResult struct {
+	Success   bool      `json:"success"`
+	Message   string    `json:"message"`
+	Data      any       `json:"data"`
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
+}
+
+// NewJSONResult returns the result of a command which succeeded.
+func NewJSONResult(message string, data any) *JSONResult {
+	return &JSONResult{Success: true, Message: message, Data: data}
+}
+
+// NewJSONError returns the result of a command which failed with the given
+// error. The message defaults to the one of the error.
+func NewJSONError(err error, message string, data any) *JSONResult {
+	if message == "" {
+		message = err.Error()
+	}
+	return &JSONResult{Message: message, Data: data, ErrorCode: ErrorCodeFor(err)}
+}
+
+// NewJSONFailure returns the result of a command which failed for the given
+// reason.
+func NewJSONFailure(code ErrorCode, message string, data any) *JSONResult {
+	return &JSONResult{Message: message, Data: data, ErrorCode: code}
+}
+
+// Returns the JSON representation of this result.
+func (r *JSONResult) JSON() string {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return encodingFailure
+	}
+	return string(data)
+}
+
+func (ps *ProductService) finish(err error) error {
+	ps.DurationMS = time.Since(ps.StartedAt).Milliseconds()
+	ps.Success = err == nil
+	ps.ErrorCode = ErrorCodeFor(err)
+	if err != nil {
+		ps.Error = err.Error()
+	}
+	slog.Debug("Finished", "operation", ps.operation, "product", ps.Product.Identifier+"/"+ps.Product.Version+"/"+ps.Product.Arch,
+		"action", ps.Action, "duration_ms", ps.DurationMS, "error", err)
+	return err
+}
+
+func (ps *ProductService) setService(id int, name, url string) {
+	ps.Service = ServiceOut{Id: id, Name: name, Url: url}
+}
+
+func productToOut(product registration.Product) ProductOut {
+	return ProductOut{
+		Name:       product.Name,
+		Identifier: product.Identifier,
+		Version:    product.Version,
+		Arch:       product.Arch,
+	}
+}
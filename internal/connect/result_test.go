@@ -0,0 +1,153 @@
+package connect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"syscall"
+	"testing"
+
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestErrorCodeFor(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(ErrorCodeNone, ErrorCodeFor(nil))
+	assert.Equal(ErrorCodeGeneric, ErrorCodeFor(errors.New("boom")))
+	assert.Equal(ErrorCodeZypper, ErrorCodeFor(zypper.ZypperError{ExitCode: 4}))
+	assert.Equal(ErrorCodeConnectionRefused, ErrorCodeFor(&url.Error{Op: "Get", URL: "https://scc.suse.com", Err: syscall.ECONNREFUSED}))
+	assert.Equal(ErrorCodeConnection, ErrorCodeFor(&url.Error{Op: "Get", URL: "https://scc.suse.com", Err: errors.New("timeout")}))
+	assert.Equal(ErrorCodeUnauthorized, ErrorCodeFor(&connection.ApiError{Code: 401}))
+	assert.Equal(ErrorCodeAPI, ErrorCodeFor(&connection.ApiError{Code: 422}))
+	assert.Equal(ErrorCodeSystemNotRegistered, ErrorCodeFor(ErrSystemNotRegistered))
+	assert.Equal(ErrorCodeBaseProductDeactivation, ErrorCodeFor(ErrBaseProductDeactivation))
+	assert.Equal(ErrorCodeEULADeclined, ErrorCodeFor(fmt.Errorf("%w: nope", ErrEULADeclined)))
+}
+
+func TestRegisterOutFinish(t *testing.T) {
+	assert := assert.New(t)
+
+	out := newRegisterOut(OperationRegister)
+	out.start(registration.Product{Identifier: "SLES", Version: "15.6", Arch: "x86_64"}, ActionActivate).finish(nil)
+	out.start(registration.Product{Identifier: "sle-ha", Version: "15.6", Arch: "x86_64"}, ActionActivate).finish(ErrEULADeclined)
+
+	err := out.Finish("Successfully registered system", ErrEULADeclined)
+	assert.ErrorIs(err, ErrEULADeclined)
+	assert.False(out.Success)
+	assert.Equal(ErrEULADeclined.Error(), out.Message)
+	assert.Equal(ErrorCodeEULADeclined, out.ErrorCode)
+
+	assert.True(out.Products[0].Success)
+	assert.Empty(out.Products[0].Error)
+	assert.False(out.Products[1].Success)
+	assert.Equal(ErrorCodeEULADeclined, out.Products[1].ErrorCode)
+
+	assert.NoError(out.Finish("Successfully registered system", nil))
+	assert.True(out.Success)
+	assert.Equal("Successfully registered system", out.Message)
+	assert.Equal(ErrorCodeNone, out.ErrorCode)
+}
+
+func TestRegisterOutJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	out := newRegisterOut(OperationDeregister)
+	out.Finish("", ErrSystemNotRegistered)
+
+	data := map[string]any{}
+	assert.NoError(json.Unmarshal([]byte(out.JSON()), &data))
+	assert.Equal(false, data["success"])
+	assert.Equal("system_not_registered", data["error_code"])
+	assert.Equal(map[string]any{"operation": "de-register", "products": []any{}}, data["data"])
+	assert.NotContains(data, "operation")
+	assert.NotContains(data, "products")
+
+	out = newRegisterOut(OperationRegister)
+	out.Finish("Successfully registered system", nil)
+	assert.NotContains(out.JSON(), "error_code")
+}
+
+func TestJSONResult(t *testing.T) {
+	assert := assert.New(t)
+
+	data := map[string]any{}
+	out := NewJSONResult("Successfully updated system", map[string]int{"count": 1})
+	assert.NoError(json.Unmarshal([]byte(out.JSON()), &data))
+	assert.Equal(map[string]any{
+		"success": true,
+		"message": "Successfully updated system",
+		"data":    map[string]any{"count": float64(1)},
+	}, data)
+
+	data = map[string]any{}
+	out = NewJSONError(ErrPingFromUnregistered, "", nil)
+	assert.NoError(json.Unmarshal([]byte(out.JSON()), &data))
+	assert.Equal(map[string]any{
+		"success":    false,
+		"message":    ErrPingFromUnregistered.Error(),
+		"data":       nil,
+		"error_code": "system_not_registered",
+	}, data)
+
+	out = NewJSONFailure(ErrorCodeInvalidUsage, "Unexpected argument 'foo'", nil)
+	assert.JSONEq(`{"success":false,"message":"Unexpected argument 'foo'","data":null,"error_code":"invalid_usage"}`, out.JSON())
+
+	out = NewJSONResult("", func() {})
+	assert.JSONEq(encodingFailure, out.JSON())
+}
+
+func TestRegisterProductTreeResult(t *testing.T) {
+	assert := assert.New(t)
+
+	origAdd, origInstall, origEULA := localAddService, localInstallReleasePackage, localAcceptEULA
+	t.Cleanup(func() {
+		localAddService, localInstallReleasePackage, localAcceptEULA = origAdd, origInstall, origEULA
+	})
+	localAddService = func(string, string, bool, bool) error { return nil }
+	localAcceptEULA = func(connection.Connection, *Options, registration.Product) error { return nil }
+	localInstallReleasePackage = func(identifier string, _ bool, _ bool) error {
+		if identifier == "sle-module-python3" {
+			return zypper.ZypperError{ExitCode: 8}
+		}
+		return nil
+	}
+
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	conn.On("Do", mock.Anything).Return([]byte(`{"id": 42, "name": "Basesystem_Module_x86_64", "url": "https://scc.suse.com/service", "product": {"name": "Basesystem Module"}}`), nil)
+
+	base := &registration.Product{
+		Extensions: []registration.Product{
+			{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64", Recommended: true, Available: true},
+			{Identifier: "sle-module-legacy", Version: "15.6", Arch: "x86_64", Recommended: false, Available: true},
+			{Identifier: "sle-module-live-patching", Version: "15.6", Arch: "x86_64", Recommended: true, Available: false},
+			{Identifier: "sle-module-python3", Version: "15.6", Arch: "x86_64", Recommended: true, Available: true},
+		},
+	}
+
+	out := newRegisterOut(OperationRegister)
+	err := registerProductTree(conn, &Options{}, base, out)
+	assert.Error(err)
+	assert.Len(out.Products, 3)
+
+	assert.Equal("sle-module-basesystem", out.Products[0].Product.Identifier)
+	assert.Equal("Basesystem Module", out.Products[0].Product.Name)
+	assert.Equal(ActionActivate, out.Products[0].Action)
+	assert.Equal(ReleasePackageInstalled, out.Products[0].ReleasePackage)
+	assert.Equal(42, out.Products[0].Service.Id)
+	assert.True(out.Products[0].Success)
+
+	assert.Equal("sle-module-live-patching", out.Products[1].Product.Identifier)
+	assert.Equal(ActionSkip, out.Products[1].Action)
+	assert.True(out.Products[1].Success)
+
+	assert.Equal("sle-module-python3", out.Products[2].Product.Identifier)
+	assert.Equal(ReleasePackageFailed, out.Products[2].ReleasePackage)
+	assert.False(out.Products[2].Success)
+	assert.Equal(ErrorCodeZypper, out.Products[2].ErrorCode)
+}
@@ -2,6 +2,7 @@ package connect
 
 import (
 	"bufio"
+	"log/slog"
 	"strconv"
 	"strings"
 
@@ -17,7 +18,7 @@ func createSnapshot(snapshotType, desc string, args []string) (int, error) {
 		"--cleanup-algorithm", "number", "--print-number",
 		"--userdata", "important=yes", "--description", desc}
 	cmd = append(cmd, args...)
-	util.QuietOut.Printf("\nExecuting '%s'\n\n", strings.Join(cmd, " "))
+	slog.Info("Executing", "command", strings.Join(cmd, " "))
 	output, err := util.Execute(cmd, []int{0})
 	if err != nil {
 		return 0, err
@@ -3,11 +3,12 @@ package connect
 import (
 	"bytes"
 	_ "embed" //golint
-	"encoding/json"
 	"fmt"
+	"log/slog"
 	"text/template"
 
 	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/connection"
 	"github.com/SUSE/connect-ng/pkg/registration"
 )
 
@@ -19,8 +20,13 @@ const (
 )
 
 const (
-	registered    = "Registered"
-	notRegistered = "Not Registered"
+	registered        = "Registered"
+	registeredOffline = "Registered (offline)"
+	notRegistered     = "Not Registered"
+
+	// The registration server could not be reached to tell whether the
+	// product is activated.
+	serverUnreachable = "Unknown (registration server unreachable)"
 )
 
 var (
@@ -42,6 +48,18 @@ type Status struct {
 	ExpiresAt  string `json:"expires_at,omitempty"`
 	SubStatus  string `json:"subscription_status,omitempty"`
 	Type       string `json:"type,omitempty"`
+
+	ProductLine  string `json:"product_line,omitempty"`
+	ReleaseStage string `json:"release_stage,omitempty"`
+
+	// State of the offline registration certificate covering this product,
+	// if any.
+	OfflineCertificate string `json:"offline_certificate,omitempty"`
+
+	// Keepalive calls of this system waiting for the registration server to
+	// be reachable again, reported on the base product.
+	QueuedKeepAlives      int    `json:"queued_keepalives,omitempty"`
+	QueuedKeepAlivesSince string `json:"queued_keepalives_since,omitempty"`
 }
 
 func PrintProductStatuses(opts *Options, format StatusFormat) error {
@@ -55,41 +73,62 @@ func PrintProductStatuses(opts *Options, format StatusFormat) error {
 
 // GetProductStatuses returns statuses of installed products
 func GetProductStatuses(opts *Options, format StatusFormat) (string, error) {
-	statuses, err := getStatuses(opts)
-	if err != nil {
-		return "", err
-	}
-
 	switch format {
 	case StatusJSON:
-		jsn, err := json.Marshal(statuses)
-		if err != nil {
-			return "", err
-		}
-		return string(jsn), nil
+		return RenderProductStatuses(opts, OutputFormat{Kind: FormatJSON})
 	case StatusText:
-		text, err := getStatusText(statuses)
-		if err != nil {
-			return "", err
-		}
-		return text, nil
+		return RenderProductStatuses(opts, OutputFormat{Kind: FormatText})
 	}
 	// Never happens. Hooray for Go's enums and branch exhaustion!
 	return "", nil
 }
 
+// RenderProductStatuses returns statuses of installed products rendered with
+// the given format. The text format uses the `--status-text` template.
+func RenderProductStatuses(opts *Options, format OutputFormat) (string, error) {
+	statuses, err := getStatuses(opts)
+	if err != nil {
+		return "", err
+	}
+	return render(statusList(statuses), format, func() (string, error) {
+		return getStatusText(statuses)
+	})
+}
+
+// statusList renders statuses as a table.
+type statusList []Status
+
+func (l statusList) tableHeader() []string {
+	return []string{"PRODUCT", "STATUS", "SUBSCRIPTION", "TYPE", "PRODUCT LINE", "RELEASE STAGE", "EXPIRES AT"}
+}
+
+func (l statusList) tableRows() [][]string {
+	rows := [][]string{}
+	for _, s := range l {
+		product := s.Identifier + "/" + s.Version + "/" + s.Arch
+		rows = append(rows, []string{product, s.Status, s.Name, s.Type, s.ProductLine, s.ReleaseStage, s.ExpiresAt})
+	}
+	return rows
+}
+
 func getStatuses(opts *Options) ([]Status, error) {
-	installed, err := zypper.InstalledProducts()
+	installed, err := localInstalledProducts()
 	if err != nil {
 		return nil, err
 	}
 
-	api := NewWrappedAPI(opts)
+	api := localNewWrappedAPI(opts)
 
 	activations := make(map[string]*registration.Activation) // default empty map
+	unreachable := false
 	if api.IsRegistered() {
-		rawActivations, err := registration.FetchActivations(api.GetConnection())
-		if err != nil {
+		rawActivations, err := localFetchActivations(api.GetConnection())
+		if connection.IsUnreachable(err) {
+			// Still report the local products and the queued keepalive
+			// calls while the server is down.
+			slog.Warn("Could not reach the registration server, the activation status is unknown", "error", err)
+			unreachable = true
+		} else if err != nil {
 			return nil, err
 		}
 		for _, activation := range rawActivations {
@@ -97,9 +136,67 @@ func getStatuses(opts *Options) ([]Status, error) {
 		}
 	}
 	statuses := buildStatuses(installed, activations)
+	if unreachable {
+		for i := range statuses {
+			statuses[i].Status = serverUnreachable
+		}
+	}
+
+	cert, err := StoredOfflineCertificate(opts)
+	if err != nil {
+		return nil, err
+	}
+	if cert != nil {
+		applyOfflineCertificate(statuses, installed, cert)
+	}
+
+	backlog, err := KeepAliveBacklog(opts)
+	if err != nil {
+		slog.Debug("Could not read the keepalive queue", "error", err)
+	} else if len(backlog) > 0 {
+		applyKeepAliveBacklog(statuses, installed, backlog)
+	}
 	return statuses, nil
 }
 
+// applyKeepAliveBacklog reports the queued keepalive calls on the status of
+// the base product. Statuses and products are expected to be in the same
+// order.
+func applyKeepAliveBacklog(statuses []Status, products []registration.Product, backlog []SpooledKeepAlive) {
+	for i, product := range products {
+		if product.IsBase {
+			statuses[i].QueuedKeepAlives = len(backlog)
+			statuses[i].QueuedKeepAlivesSince = backlog[0].QueuedAt.Format("2006-01-02 15:04:05 MST")
+			return
+		}
+	}
+}
+
+// applyOfflineCertificate reports the offline registration certificate on
+// the status of the base product. Statuses and products are expected to be in
+// the same order.
+func applyOfflineCertificate(statuses []Status, products []registration.Product, cert *OfflineCertificateStatus) {
+	for i, product := range products {
+		if !product.IsBase {
+			continue
+		}
+		status := &statuses[i]
+		status.OfflineCertificate = cert.State
+		if status.Status == registered || cert.State == OfflineCertificateInvalid {
+			return
+		}
+		if cert.Valid() {
+			status.Status = registeredOffline
+		}
+		layout := "2006-01-02 15:04:05 MST"
+		status.Name = cert.Subscription
+		status.StartsAt = cert.StartsAt.Format(layout)
+		status.ExpiresAt = cert.ExpiresAt.Format(layout)
+		status.Type = cert.Kind
+		return
+	}
+}
+
 func buildStatuses(products []registration.Product, activations map[string]*registration.Activation) []Status {
 	var statuses []Status
 	for _, product := range products {
@@ -109,9 +206,15 @@ func buildStatuses(products []registration.Product, activations map[string]*regi
 			Version:    product.Version,
 			Arch:       product.Arch,
 			Status:     notRegistered,
+
+			ProductLine:  product.ProductLine,
+			ReleaseStage: product.ReleaseStage,
 		}
 		if activation, ok := activations[product.ToTriplet()]; ok {
 			status.Status = registered
+			if activation.Product != nil && activation.Product.ReleaseStage != "" {
+				status.ReleaseStage = activation.Product.ReleaseStage
+			}
 			if activation.RegistrationCode != "" {
 				status.Name = activation.Name
 				status.RegCode = activation.RegistrationCode
@@ -0,0 +1,257 @@
+package connect
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/i18n"
+	"github.com/SUSE/connect-ng/internal/util"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+var (
+	ErrSwitchSameServer    = errors.New("The system is already registered against this server")
+	ErrSwitchRegcodeNeeded = errors.New("A registration code (--regcode) is required to switch to the SUSE Customer Center")
+	ErrSwitchRolledBack    = errors.New("Switching server failed, the system is still registered against the previous server")
+
+	// test method overwrites
+	localNewServerAPI      = newUnregisteredAPI
+	localFetchActivations  = registration.FetchActivations
+	localFetchProductInfo  = registration.FetchProductInfo
+	localActivateProduct   = ActivateProduct
+	localDeregisterSystem  = registration.Deregister
+	localSaveConfiguration = (*Options).SaveAsConfiguration
+)
+
+// zypper service as captured before switching servers.
+type switchService struct {
+	Name string
+	URL  string
+}
+
+// SwitchServer moves a registered system to the registration server at
+// `newURL` (e.g. from SCC to an RMT server or back), keeping the same set of
+// activated products. The system is first registered against the new server
+// and all products activated there. Only then the zypper services are swapped,
+// the configuration is rewritten and the system is deregistered from the
+// previous server. If anything fails before the system has been deregistered
+// from the previous server, all changes are rolled back and an error wrapping
+// `ErrSwitchRolledBack` is returned.
+func SwitchServer(api WrappedAPI, opts *Options, newURL string) (*RegisterOut, error) {
+	out := newRegisterOut(OperationSwitchServer)
+
+	if !api.IsRegistered() {
+		return out, out.Finish("", ErrSystemNotRegistered)
+	}
+	if strings.TrimRight(newURL, "/") == strings.TrimRight(opts.BaseURL, "/") {
+		return out, out.Finish("", ErrSwitchSameServer)
+	}
+
+	newOpts := *opts
+	newOpts.ChangeBaseURL(newURL)
+	if newOpts.IsScc() && opts.Token == "" {
+		return out, out.Finish("", ErrSwitchRegcodeNeeded)
+	}
+
+	printInformation(i18n.Tf("Switching system from %s to %s", opts.BaseURL, newOpts.BaseURL), opts)
+
+	// Capture the current state of the system.
+	credsPath := cred.SystemCredentialsPath(opts.FsRoot)
+	oldCreds, err := cred.ReadCredentials(credsPath)
+	if err != nil {
+		return out, out.Finish("", err)
+	}
+	products, err := productsToSwitch(api.GetConnection())
+	if err != nil {
+		return out, out.Finish("", err)
+	}
+	oldServices, err := servicesOf(opts.BaseURL)
+	if err != nil {
+		return out, out.Finish("", err)
+	}
+
+	// Register against the new server. From now on the system credentials
+	// are the ones from the new server.
+	opts.Print(i18n.Tf("\nRegistering system to %s ...", newOpts.ServerName()))
+	newAPI := localNewServerAPI(&newOpts)
+	if err := newAPI.Register(&newOpts); err != nil {
+		restoreCredentials(oldCreds, credsPath)
+		return out, out.Finish("", fmt.Errorf("%w: %v", ErrSwitchRolledBack, err))
+	}
+
+	rollback := func(cause error) error {
+		opts.Print(i18n.T("\nRolling back ..."))
+		if err := localDeregisterSystem(newAPI.GetConnection()); err != nil {
+			slog.Debug("Could not deregister", "server", newOpts.BaseURL, "error", err)
+		}
+		restoreCredentials(oldCreds, credsPath)
+		return fmt.Errorf("%w: %v", ErrSwitchRolledBack, cause)
+	}
+
+	newServices := []switchService{}
+	for _, product := range products {
+		service, err := activateOnNewServer(newAPI.GetConnection(), &newOpts, product, out)
+		if err != nil {
+			return out, out.Finish("", rollback(err))
+		}
+		newServices = append(newServices, switchService{Name: service.Name, URL: service.URL})
+	}
+
+	if !opts.SkipServiceInstall {
+		opts.Print(i18n.T("\nSwapping services ..."))
+		if err := swapServices(oldServices, newServices, &newOpts); err != nil {
+			err = rollback(err)
+			restoreServices(newServices, oldServices, opts)
+			return out, out.Finish("", err)
+		}
+	}
+
+	if err := localSaveConfiguration(&newOpts); err != nil {
+		err = rollback(err)
+		if !opts.SkipServiceInstall {
+			restoreServices(newServices, oldServices, opts)
+		}
+		return out, out.Finish("", err)
+	}
+
+	// The new registration is in place, the previous one is not needed
+	// anymore. Use the previous credentials without writing them back to the
+	// credentials file, which belongs to the new registration by now.
+	opts.Print(i18n.Tf("\nDeregistering system from %s ...", opts.ServerName()))
+	oldAPI := newWrapper(opts, cred.Credentials{
+		Filename:    os.DevNull,
+		Username:    oldCreds.Username,
+		Password:    oldCreds.Password,
+		SystemToken: oldCreds.SystemToken,
+	}, true)
+	if err := localDeregisterSystem(oldAPI.GetConnection()); err != nil {
+		opts.Print(i18n.Tf("-> Could not deregister from %s, please remove this system there manually: %v", opts.ServerName(), err))
+	}
+	if err := localRemoveRegistryAuth(oldCreds.Username, oldCreds.Password); err != nil {
+		slog.Debug("Could not remove registry authentication", "error", err)
+	}
+
+	*opts = newOpts
+	if opts.OutputKind == Text {
+		util.Info.Print(util.Bold(util.GreenText("\n" + i18n.T("Successfully switched registration server"))))
+	}
+	return out, out.Finish(i18n.T("Successfully switched registration server"), nil)
+}
+
+// productsToSwitch returns the installed products which are activated on the
+// current server, with the base product first and the extensions in
+// dependency order.
+func productsToSwitch(conn connection.Connection) ([]registration.Product, error) {
+	installed, err := localInstalledProducts()
+	if err != nil {
+		return nil, err
+	}
+	activations, err := localFetchActivations(conn)
+	if err != nil {
+		return nil, err
+	}
+	activated := NewStringSet()
+	for _, a := range activations {
+		activated.Add(a.ToTriplet())
+	}
+
+	var base *registration.Product
+	for i := range installed {
+		if installed[i].IsBase {
+			base = &installed[i]
+			break
+		}
+	}
+	if base == nil {
+		return nil, zypper.ErrCannotDetectBaseProduct
+	}
+
+	products := []registration.Product{*base}
+	tree, err := localFetchProductInfo(conn, base.Identifier, base.Version, base.Arch)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range tree.ToExtensionsList() {
+		if activated.Contains(e.ToTriplet()) {
+			products = append(products, e)
+		}
+	}
+	return products, nil
+}
+
+func activateOnNewServer(conn connection.Connection, opts *Options, product registration.Product, out *RegisterOut) (service registration.Service, err error) {
+	entry := out.start(product, ActionActivate)
+	defer func() { entry.finish(err) }()
+
+	opts.Print(i18n.Tf("\nActivating %s %s %s ...\n", product.Identifier, product.Version, product.Arch))
+	service, err = localActivateProduct(conn, opts.Token, product)
+	if err != nil {
+		return service, err
+	}
+	entry.setService(service.ID, service.Name, service.URL)
+	return service, nil
+}
+
+// servicesOf returns the zypper services pointing to the given server.
+func servicesOf(baseURL string) ([]switchService, error) {
+	installed, err := localInstalledServices()
+	if err != nil {
+		return nil, err
+	}
+	services := []switchService{}
+	for _, s := range installed {
+		if strings.Contains(s.URL, baseURL) {
+			services = append(services, switchService{Name: s.Name, URL: s.URL})
+		}
+	}
+	return services, nil
+}
+
+// swapServices adds the services of the new server and then removes the ones
+// from the previous server which have not been replaced.
+func swapServices(oldServices, newServices []switchService, opts *Options) error {
+	added := NewStringSet()
+	for _, s := range newServices {
+		if err := localAddService(s.URL, s.Name, !opts.NoZypperRefresh, opts.Insecure); err != nil {
+			return err
+		}
+		added.Add(s.Name)
+	}
+	for _, s := range oldServices {
+		if added.Contains(s.Name) {
+			continue
+		}
+		if err := localRemoveService(s.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreServices brings the zypper services back to how they were before
+// switching. Note that this has to be called after restoring the credentials
+// of the previous server.
+func restoreServices(newServices, oldServices []switchService, opts *Options) {
+	for _, s := range newServices {
+		if err := localRemoveService(s.Name); err != nil {
+			slog.Debug("Could not remove service", "service", s.Name, "error", err)
+		}
+	}
+	for _, s := range oldServices {
+		if err := localAddService(s.URL, s.Name, false, opts.Insecure); err != nil {
+			opts.Print(i18n.Tf("-> Could not restore service %s: %v", s.Name, err))
+		}
+	}
+}
+
+func restoreCredentials(creds cred.Credentials, path string) {
+	if err := cred.CreateCredentials(creds.Username, creds.Password, creds.SystemToken, path); err != nil {
+		util.Info.Print(i18n.Tf("Could not restore the system credentials in %s: %v", path, err))
+	}
+}
@@ -0,0 +1,192 @@
+package connect
+
+import (
+	"errors"
+	"testing"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type switchServerMock struct {
+	added        []string
+	removed      []string
+	deregistered []string
+	configSaved  bool
+}
+
+func mockSwitchServer(t *testing.T, opts *Options, rejected string) *switchServerMock {
+	t.Helper()
+
+	m := &switchServerMock{}
+	origProducts, origServices := localInstalledProducts, localInstalledServices
+	origAdd, origRemove := localAddService, localRemoveService
+	origAPI, origActivations, origInfo := localNewServerAPI, localFetchActivations, localFetchProductInfo
+	origActivate, origDeregister := localActivateProduct, localDeregisterSystem
+	origSave, origRegistry := localSaveConfiguration, localRemoveRegistryAuth
+	t.Cleanup(func() {
+		localInstalledProducts, localInstalledServices = origProducts, origServices
+		localAddService, localRemoveService = origAdd, origRemove
+		localNewServerAPI, localFetchActivations, localFetchProductInfo = origAPI, origActivations, origInfo
+		localActivateProduct, localDeregisterSystem = origActivate, origDeregister
+		localSaveConfiguration, localRemoveRegistryAuth = origSave, origRegistry
+	})
+
+	localInstalledProducts = func() ([]registration.Product, error) {
+		return []registration.Product{
+			{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true},
+			{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"},
+			{Identifier: "sle-module-python3", Version: "15.6", Arch: "x86_64"},
+		}, nil
+	}
+	localFetchActivations = func(connection.Connection) ([]*registration.Activation, error) {
+		return []*registration.Activation{
+			{Product: &registration.Product{Identifier: "SLES", Version: "15.6", Arch: "x86_64"}},
+			{Product: &registration.Product{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64"}},
+		}, nil
+	}
+	localFetchProductInfo = func(_ connection.Connection, _, _, _ string) (*registration.Product, error) {
+		return &registration.Product{
+			Extensions: []registration.Product{
+				{Identifier: "sle-module-basesystem", Version: "15.6", Arch: "x86_64", Extensions: []registration.Product{
+					{Identifier: "sle-module-python3", Version: "15.6", Arch: "x86_64"},
+				}},
+			},
+		}, nil
+	}
+	localInstalledServices = func() ([]zypper.ZypperService, error) {
+		return []zypper.ZypperService{
+			{Name: "SUSE_Linux_Enterprise_Server_15_SP6_x86_64", URL: "https://scc.suse.com/access/services/1"},
+			{Name: "Basesystem_Module_15_SP6_x86_64", URL: "https://scc.suse.com/access/services/2"},
+			{Name: "custom", URL: "https://example.com/service"},
+		}, nil
+	}
+
+	newConn, _ := connection.NewMockConnectionWithCredentials()
+	localNewServerAPI = func(newOpts *Options) WrappedAPI {
+		api := NewMockWrappedAPI()
+		api.On("Register", mock.Anything).Return(nil).Run(func(mock.Arguments) {
+			// Registering writes the credentials of the new server.
+			cred.CreateCredentials("RMT_login", "new", "", cred.SystemCredentialsPath(opts.FsRoot))
+		})
+		api.On("GetConnection").Return(newConn)
+		return api
+	}
+	localActivateProduct = func(_ connection.Connection, _ string, product registration.Product) (registration.Service, error) {
+		if product.Identifier == rejected {
+			return registration.Service{}, errors.New("No repositories found for product")
+		}
+		return registration.Service{
+			Name: product.Identifier + "_service",
+			URL:  "https://rmt.example.com/services/" + product.Identifier,
+		}, nil
+	}
+	localDeregisterSystem = func(conn connection.Connection) error {
+		login, _, _ := conn.GetCredentials().Login()
+		m.deregistered = append(m.deregistered, login)
+		return nil
+	}
+	localAddService = func(url, name string, _ bool, _ bool) error {
+		m.added = append(m.added, name)
+		return nil
+	}
+	localRemoveService = func(name string) error {
+		m.removed = append(m.removed, name)
+		return nil
+	}
+	localSaveConfiguration = func(*Options) error {
+		m.configSaved = true
+		return nil
+	}
+	localRemoveRegistryAuth = func(string, string) error { return nil }
+	return m
+}
+
+func switchServerSetup(t *testing.T) (*Options, *MockWrappedAPI) {
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	if err := cred.CreateCredentials("SCC_login", "old", "", cred.SystemCredentialsPath(opts.FsRoot)); err != nil {
+		t.Fatalf("could not create credentials: %v", err)
+	}
+
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(true)
+	api.On("GetConnection").Return(conn)
+	return opts, api
+}
+
+func TestSwitchServer(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := switchServerSetup(t)
+	m := mockSwitchServer(t, opts, "")
+
+	out, err := SwitchServer(api, opts, "https://rmt.example.com")
+	assert.NoError(err)
+	assert.True(out.Success)
+	assert.Equal(OperationSwitchServer, out.Operation)
+
+	// Only the activated products are carried over, base product first.
+	assert.Len(out.Products, 2)
+	assert.Equal("SLES", out.Products[0].Product.Identifier)
+	assert.Equal("sle-module-basesystem", out.Products[1].Product.Identifier)
+
+	assert.Equal([]string{"SLES_service", "sle-module-basesystem_service"}, m.added)
+	assert.Equal([]string{"SUSE_Linux_Enterprise_Server_15_SP6_x86_64", "Basesystem_Module_15_SP6_x86_64"}, m.removed)
+	assert.True(m.configSaved)
+
+	// The previous registration is removed without touching the new
+	// credentials.
+	assert.Equal([]string{"SCC_login"}, m.deregistered)
+	creds, err := cred.ReadCredentials(cred.SystemCredentialsPath(opts.FsRoot))
+	assert.NoError(err)
+	assert.Equal("RMT_login", creds.Username)
+
+	assert.Equal("https://rmt.example.com", opts.BaseURL)
+	assert.False(opts.IsScc())
+}
+
+func TestSwitchServerRollback(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := switchServerSetup(t)
+	m := mockSwitchServer(t, opts, "sle-module-basesystem")
+
+	out, err := SwitchServer(api, opts, "https://rmt.example.com")
+	assert.ErrorIs(err, ErrSwitchRolledBack)
+	assert.Equal(ErrorCodeSwitchRolledBack, out.ErrorCode)
+	assert.False(out.Products[1].Success)
+
+	// Nothing changed on the system, and the new server forgot about it.
+	assert.Empty(m.added)
+	assert.Empty(m.removed)
+	assert.False(m.configSaved)
+	assert.Equal([]string{"sample-login"}, m.deregistered)
+
+	creds, err := cred.ReadCredentials(cred.SystemCredentialsPath(opts.FsRoot))
+	assert.NoError(err)
+	assert.Equal("SCC_login", creds.Username)
+	assert.Equal("https://scc.suse.com", opts.BaseURL)
+}
+
+func TestSwitchServerChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := switchServerSetup(t)
+	_, err := SwitchServer(api, opts, "https://scc.suse.com/")
+	assert.ErrorIs(err, ErrSwitchSameServer)
+
+	opts.ChangeBaseURL("https://rmt.example.com")
+	_, err = SwitchServer(api, opts, "https://scc.suse.com")
+	assert.ErrorIs(err, ErrSwitchRegcodeNeeded)
+
+	unregistered := NewMockWrappedAPI()
+	unregistered.On("IsRegistered").Return(false)
+	_, err = SwitchServer(unregistered, opts, "https://rmt2.example.com")
+	assert.ErrorIs(err, ErrSystemNotRegistered)
+}
@@ -1,16 +1,28 @@
 package connect
 
 import (
+	"log/slog"
 	"strings"
 
 	"github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/registration"
 )
 
 // Cleanup removes system credentials stored at the given `basePath`, and
-// removes installed services which are related to the given `baseURL`.
-func Cleanup(baseURL, basePath string) error {
+// removes installed services which are related to the given `baseURL`. The
+// returned result contains the services which have been removed.
+func Cleanup(baseURL, basePath string) (*RegisterOut, error) {
+	out := newRegisterOut(OperationCleanup)
+	if err := cleanup(baseURL, basePath, out); err != nil {
+		return out, out.Finish("", err)
+	}
+	return out, out.Finish(i18n.T("Successfully cleaned up system"), nil)
+}
+
+func cleanup(baseURL, basePath string, out *RegisterOut) error {
 	systemCredPath := credentials.SystemCredentialsPath(basePath)
 	err := util.RemoveFile(systemCredPath)
 	if err != nil {
@@ -28,10 +40,12 @@ func Cleanup(baseURL, basePath string) error {
 		//       on the configuration (e.g. listen on https but API
 		//       returns URL with http).
 		if !strings.Contains(service.URL, baseURL) {
-			util.Debug.Printf("%s not in %s\n", baseURL, service.URL)
+			slog.Debug("Service does not belong to the registration server", "server", baseURL, "service", service.Name, "url", service.URL)
 			continue
 		}
-		if err := zypper.RemoveService(service.Name); err != nil {
+		entry := out.start(registration.Product{}, ActionRemove)
+		entry.setService(0, service.Name, service.URL)
+		if err := entry.finish(zypper.RemoveService(service.Name)); err != nil {
 			return err
 		}
 
@@ -1,9 +1,7 @@
 package connect
 
 import (
-	"bufio"
-	"errors"
-	"os"
+	"github.com/SUSE/connect-ng/internal/uptime"
 )
 
 const (
@@ -11,7 +9,7 @@ const (
 )
 
 // readUptimeLogFile reads the system uptime log from a given file and
-// returns them as a string array. If the given file does not exist,
+// returns its entries as a string array. If the given file does not exist,
 // it will be interpreted as if the system uptime log feature is not
 // enabled. Hence an empty array will be returned.
 func readUptimeLogFile(uptimeLogFilePath string) ([]string, error) {
@@ -22,24 +20,9 @@ func readUptimeLogFile(uptimeLogFilePath string) ([]string, error) {
 	// basis. If the service is not installed or otherwise disabled, the
 	// uptime log file may not exist. In that case we assume the uptime
 	// tracking feature is disabled.
-	_, err := os.Stat(uptimeLogFilePath)
-	if errors.Is(err, os.ErrNotExist) {
-		return nil, nil
-	}
-
-	uptimeLogFile, err := os.Open(uptimeLogFilePath)
-	if err != nil {
-		return nil, err
-	}
-	defer uptimeLogFile.Close()
-	fileScanner := bufio.NewScanner(uptimeLogFile)
-	var logEntries []string
-
-	for fileScanner.Scan() {
-		logEntries = append(logEntries, fileScanner.Text())
-	}
-	if err = fileScanner.Err(); err != nil {
+	log, err := uptime.Read(uptimeLogFilePath)
+	if err != nil || log == nil {
 		return nil, err
 	}
-	return logEntries, nil
+	return log.Lines(), nil
 }
@@ -0,0 +1,292 @@
+package connect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/i18n"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/registration"
+)
+
+// DriftSeverity tells how serious a mismatch between the local system and the
+// activations on the registration server is.
+type DriftSeverity string
+
+const (
+	// The system cannot use the repositories of an activated product.
+	DriftError DriftSeverity = "error"
+	// The system works but its state is not the one expected.
+	DriftWarning DriftSeverity = "warning"
+)
+
+// Kinds of drift reported by `Verify`.
+const (
+	DriftServiceMissing      = "service_missing"
+	DriftServiceURLMismatch  = "service_url_mismatch"
+	DriftServiceOrphaned     = "service_orphaned"
+	DriftServiceOldServer    = "service_old_server"
+	DriftCredentialsMissing  = "credentials_missing"
+	DriftCredentialsInvalid  = "credentials_invalid"
+	DriftCredentialsStale    = "credentials_stale"
+	DriftCredentialsOrphaned = "credentials_orphaned"
+	DriftProductNotActivated = "product_not_activated"
+	DriftProductNotInstalled = "product_not_installed"
+)
+
+// DriftFinding is a single mismatch found by `Verify`.
+type DriftFinding struct {
+	Severity DriftSeverity `json:"severity"`
+	Kind     string        `json:"kind"`
+	Product  string        `json:"product,omitempty"`
+	Service  string        `json:"service,omitempty"`
+	Message  string        `json:"message"`
+
+	// URL of the service: the one expected by the registration server for
+	// missing services, or the installed one for services to be removed.
+	URL string `json:"url,omitempty"`
+}
+
+// VerifyResult is the outcome of `SUSEConnect --verify`.
+type VerifyResult struct {
+	Drift    bool           `json:"drift"`
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Findings []DriftFinding `json:"findings"`
+}
+
+// Text renders the result as a line for each finding followed by a summary.
+func (r *VerifyResult) Text() string {
+	if !r.Drift {
+		return i18n.T("No drift detected: services, credentials and products match the activations on the registration server")
+	}
+	var b strings.Builder
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "%-7s %s\n", strings.ToUpper(string(f.Severity)), f.Message)
+	}
+	b.WriteString("\n" + i18n.Tf("Drift detected: %d error(s), %d warning(s)", r.Errors, r.Warnings))
+	return b.String()
+}
+
+// JSON renders the result as JSON.
+func (r *VerifyResult) JSON() string {
+	out, _ := json.Marshal(r)
+	return string(out)
+}
+
+func (r *VerifyResult) add(finding DriftFinding, format string, args ...any) {
+	finding.Message = i18n.Tf(format, args...)
+	r.Findings = append(r.Findings, finding)
+}
+
+// Verify cross-checks the activations of this system on the registration
+// server with the installed products, the zypper services and their
+// credential files in `credentials.d`. Every mismatch is reported as a
+// finding, errors first. An error is only returned if some of this data
+// cannot be read.
+func Verify(api WrappedAPI, opts *Options) (*VerifyResult, error) {
+	if !api.IsRegistered() {
+		return nil, ErrSystemNotRegistered
+	}
+
+	activations, err := localFetchActivations(api.GetConnection())
+	if err != nil {
+		return nil, err
+	}
+	products, err := localInstalledProducts()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{Findings: []DriftFinding{}}
+	verifyProducts(result, activations, products)
+
+	// Services are not installed into containers and similar environments.
+	if !opts.SkipServiceInstall {
+		services, err := localInstalledServices()
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyServices(result, opts, activations, services); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(result.Findings, func(i, j int) bool {
+		return result.Findings[i].Severity == DriftError && result.Findings[j].Severity != DriftError
+	})
+	for _, f := range result.Findings {
+		if f.Severity == DriftError {
+			result.Errors++
+		} else {
+			result.Warnings++
+		}
+	}
+	result.Drift = len(result.Findings) > 0
+	return result, nil
+}
+
+// verifyProducts reports installed products without an activation, and
+// activations of products which are not installed anymore (e.g. because the
+// release package has been removed).
+func verifyProducts(result *VerifyResult, activations []*registration.Activation, products []registration.Product) {
+	activated := NewStringSet()
+	for _, a := range activations {
+		activated.Add(a.ToTriplet())
+	}
+	installed := NewStringSet()
+	for _, p := range products {
+		installed.Add(p.ToTriplet())
+	}
+
+	for _, p := range products {
+		if activated.Contains(p.ToTriplet()) {
+			continue
+		}
+		severity := DriftWarning
+		if p.IsBase {
+			severity = DriftError
+		}
+		result.add(DriftFinding{Severity: severity, Kind: DriftProductNotActivated, Product: p.ToTriplet()},
+			"%s is installed but not activated on the registration server", p.ToTriplet())
+	}
+	for _, a := range activations {
+		if installed.Contains(a.ToTriplet()) {
+			continue
+		}
+		result.add(DriftFinding{Severity: DriftWarning, Kind: DriftProductNotInstalled, Product: a.ToTriplet()},
+			"%s is activated on the registration server but not installed", a.ToTriplet())
+	}
+}
+
+// verifyServices checks that the service of every activation is installed
+// with its credentials, and that no service nor credentials are left behind
+// for products which are not activated anymore.
+func verifyServices(result *VerifyResult, opts *Options, activations []*registration.Activation, services []zypper.ZypperService) error {
+	system, err := cred.ReadCredentials(cred.SystemCredentialsPath(opts.FsRoot))
+	if err != nil {
+		return err
+	}
+
+	installed := map[string]zypper.ZypperService{}
+	for _, s := range services {
+		installed[s.Name] = s
+	}
+
+	expected := NewStringSet()
+	obsoleted := map[string]string{}
+	for _, a := range activations {
+		if a.Metadata == nil || a.Metadata.Name == "" {
+			continue
+		}
+		name, product := a.Metadata.Name, a.ToTriplet()
+		expected.Add(name)
+		if a.Metadata.ObsoletedName != "" && a.Metadata.ObsoletedName != name {
+			obsoleted[a.Metadata.ObsoletedName] = name
+		}
+
+		service, ok := installed[name]
+		if !ok {
+			result.add(DriftFinding{Severity: DriftError, Kind: DriftServiceMissing, Product: product, Service: name, URL: a.Metadata.URL},
+				"service %s of %s is not installed", name, product)
+		} else if a.Metadata.URL != "" && !sameServiceURL(service.URL, a.Metadata.URL) {
+			result.add(DriftFinding{Severity: DriftWarning, Kind: DriftServiceURLMismatch, Product: product, Service: name, URL: a.Metadata.URL},
+				"service %s of %s points to %s instead of %s", name, product, service.URL, a.Metadata.URL)
+		}
+		verifyServiceCredentials(result, opts, system, product, name)
+	}
+
+	for _, s := range services {
+		if expected.Contains(s.Name) {
+			continue
+		}
+		orphan := DriftFinding{Severity: DriftWarning, Kind: DriftServiceOrphaned, Service: s.Name, URL: s.URL}
+		if !strings.Contains(s.URL, opts.BaseURL) {
+			// Services of a previous registration server still use the
+			// login of this system.
+			creds, err := cred.ReadCredentials(cred.ServiceCredentialsPath(s.Name, opts.FsRoot))
+			if err != nil || creds.Username != system.Username {
+				continue
+			}
+			orphan.Kind = DriftServiceOldServer
+			result.add(orphan, "service %s points to %s instead of the registration server %s", s.Name, s.URL, opts.BaseURL)
+		} else if replacement, ok := obsoleted[s.Name]; ok {
+			result.add(orphan, "service %s has been replaced by %s and should be removed", s.Name, replacement)
+		} else {
+			result.add(orphan, "service %s does not belong to any activated product", s.Name)
+		}
+	}
+
+	return verifyOrphanedCredentials(result, opts, system, installed, expected)
+}
+
+func verifyServiceCredentials(result *VerifyResult, opts *Options, system cred.Credentials, product, service string) {
+	path := cred.ServiceCredentialsPath(service, opts.FsRoot)
+	creds, err := cred.ReadCredentials(path)
+	if errors.Is(err, cred.ErrMissingCredentialsFile) {
+		result.add(DriftFinding{Severity: DriftError, Kind: DriftCredentialsMissing, Product: product, Service: service},
+			"credentials of service %s are missing (%s)", service, path)
+	} else if err != nil {
+		result.add(DriftFinding{Severity: DriftError, Kind: DriftCredentialsInvalid, Product: product, Service: service},
+			"credentials of service %s cannot be read: %v", service, err)
+	} else if creds.Username != system.Username || creds.Password != system.Password {
+		result.add(DriftFinding{Severity: DriftWarning, Kind: DriftCredentialsStale, Product: product, Service: service},
+			"credentials of service %s do not match the system credentials", service)
+	}
+}
+
+// verifyOrphanedCredentials reports the credential files holding the login of
+// this system which are not used by any service. Files with other logins
+// belong to repositories not managed by SUSEConnect and are ignored.
+func verifyOrphanedCredentials(result *VerifyResult, opts *Options, system cred.Credentials, installed map[string]zypper.ZypperService, expected StringSet) error {
+	dir := filepath.Join(opts.FsRoot, cred.DefaultCredentialsDir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	systemFile := filepath.Base(cred.GlobalCredentialsFile)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == systemFile || expected.Contains(name) {
+			continue
+		}
+		if _, ok := installed[name]; ok {
+			continue
+		}
+		creds, err := cred.ReadCredentials(filepath.Join(dir, name))
+		if err != nil || creds.Username != system.Username {
+			continue
+		}
+		result.add(DriftFinding{Severity: DriftWarning, Kind: DriftCredentialsOrphaned, Service: name},
+			"credentials %s are not used by any service", filepath.Join(cred.DefaultCredentialsDir, name))
+	}
+	return nil
+}
+
+// sameServiceURL compares the URL of an installed service with the one given
+// by the registration server, ignoring the `ssl_verify` parameter added for
+// insecure connections.
+func sameServiceURL(installed, expected string) bool {
+	normalize := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return raw
+		}
+		q := u.Query()
+		q.Del("ssl_verify")
+		u.RawQuery = q.Encode()
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		return u.String()
+	}
+	return normalize(installed) == normalize(expected)
+}
@@ -0,0 +1,187 @@
+package connect
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	cred "github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/zypper"
+	"github.com/SUSE/connect-ng/pkg/connection"
+	"github.com/SUSE/connect-ng/pkg/registration"
+	"github.com/stretchr/testify/assert"
+)
+
+func verifyActivation(identifier string, base bool) *registration.Activation {
+	name := identifier + "_15.6_x86_64"
+	return &registration.Activation{
+		Product: &registration.Product{Identifier: identifier, Version: "15.6", Arch: "x86_64", IsBase: base},
+		Metadata: &registration.Metadata{
+			Name: name,
+			URL:  "https://scc.suse.com/access/services/1?credentials=" + name,
+		},
+	}
+}
+
+func mockVerify(t *testing.T, activations []*registration.Activation, products []registration.Product, services []zypper.ZypperService) (*Options, *MockWrappedAPI) {
+	t.Helper()
+
+	origActivations, origProducts, origServices := localFetchActivations, localInstalledProducts, localInstalledServices
+	t.Cleanup(func() {
+		localFetchActivations, localInstalledProducts, localInstalledServices = origActivations, origProducts, origServices
+	})
+	localFetchActivations = func(connection.Connection) ([]*registration.Activation, error) {
+		return activations, nil
+	}
+	localInstalledProducts = func() ([]registration.Product, error) {
+		return products, nil
+	}
+	localInstalledServices = func() ([]zypper.ZypperService, error) {
+		return services, nil
+	}
+
+	opts := DefaultOptions()
+	opts.FsRoot = t.TempDir()
+	cred.CreateTestCredentials("SCC_login", "secret", opts.FsRoot, t)
+
+	conn, _ := connection.NewMockConnectionWithCredentials()
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(true)
+	api.On("GetConnection").Return(conn)
+	return opts, api
+}
+
+func writeServiceCredentials(t *testing.T, opts *Options, service, login string) {
+	t.Helper()
+	if err := cred.CreateCredentials(login, "secret", "", cred.ServiceCredentialsPath(service, opts.FsRoot)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyNoDrift(t *testing.T) {
+	assert := assert.New(t)
+
+	sles := verifyActivation("SLES", true)
+	opts, api := mockVerify(t,
+		[]*registration.Activation{sles},
+		[]registration.Product{*sles.Product},
+		[]zypper.ZypperService{{Name: sles.Metadata.Name, URL: sles.Metadata.URL + "&ssl_verify=no"}})
+	writeServiceCredentials(t, opts, sles.Metadata.Name, "SCC_login")
+
+	result, err := Verify(api, opts)
+	assert.NoError(err)
+	assert.False(result.Drift)
+	assert.Empty(result.Findings)
+	assert.Contains(result.Text(), "No drift detected")
+}
+
+func TestVerifyDrift(t *testing.T) {
+	assert := assert.New(t)
+
+	sles := verifyActivation("SLES", true)
+	basesystem := verifyActivation("sle-module-basesystem", false)
+	legacy := verifyActivation("sle-module-legacy", false)
+	server := verifyActivation("sle-module-server-applications", false)
+	server.Metadata.ObsoletedName = "SLE_Module_Server_Applications"
+
+	opts, api := mockVerify(t,
+		[]*registration.Activation{sles, basesystem, legacy, server},
+		[]registration.Product{
+			*sles.Product, *basesystem.Product, *server.Product,
+			{Identifier: "sle-ha", Version: "15.6", Arch: "x86_64"},
+		},
+		[]zypper.ZypperService{
+			{Name: sles.Metadata.Name, URL: sles.Metadata.URL},
+			{Name: legacy.Metadata.Name, URL: "https://scc.suse.com/access/services/2"},
+			{Name: server.Metadata.Name, URL: server.Metadata.URL},
+			{Name: "SLE_Module_Server_Applications", URL: "https://scc.suse.com/access/services/3"},
+			{Name: "third-party", URL: "https://example.com/service"},
+			{Name: "old-rmt", URL: "https://rmt.example.com/services/4"},
+		})
+	writeServiceCredentials(t, opts, sles.Metadata.Name, "SCC_login")
+	writeServiceCredentials(t, opts, legacy.Metadata.Name, "SCC_other")
+	writeServiceCredentials(t, opts, "SLE_Module_Server_Applications", "SCC_login")
+	writeServiceCredentials(t, opts, "leftover", "SCC_login")
+	writeServiceCredentials(t, opts, "old-rmt", "SCC_login")
+	writeServiceCredentials(t, opts, "third-party-repo", "someone")
+	os.WriteFile(cred.ServiceCredentialsPath(server.Metadata.Name, opts.FsRoot), []byte("garbage"), 0600)
+
+	result, err := Verify(api, opts)
+	assert.NoError(err)
+	assert.True(result.Drift)
+
+	kinds := map[string][]DriftSeverity{}
+	for _, f := range result.Findings {
+		kinds[f.Kind+" "+f.Service+f.Product] = append(kinds[f.Kind+" "+f.Service+f.Product], f.Severity)
+	}
+	assert.Equal(map[string][]DriftSeverity{
+		"product_not_activated sle-ha/15.6/x86_64":                                                                 {DriftWarning},
+		"product_not_installed sle-module-legacy/15.6/x86_64":                                                      {DriftWarning},
+		"service_missing sle-module-basesystem_15.6_x86_64sle-module-basesystem/15.6/x86_64":                       {DriftError},
+		"credentials_missing sle-module-basesystem_15.6_x86_64sle-module-basesystem/15.6/x86_64":                   {DriftError},
+		"service_url_mismatch sle-module-legacy_15.6_x86_64sle-module-legacy/15.6/x86_64":                          {DriftWarning},
+		"credentials_stale sle-module-legacy_15.6_x86_64sle-module-legacy/15.6/x86_64":                             {DriftWarning},
+		"credentials_invalid sle-module-server-applications_15.6_x86_64sle-module-server-applications/15.6/x86_64": {DriftError},
+		"service_orphaned SLE_Module_Server_Applications":                                                          {DriftWarning},
+		"service_old_server old-rmt":                                                                               {DriftWarning},
+		"credentials_orphaned leftover":                                                                            {DriftWarning},
+	}, kinds)
+	assert.Equal(3, result.Errors)
+	assert.Equal(7, result.Warnings)
+
+	// errors come first
+	assert.Equal(DriftError, result.Findings[0].Severity)
+	assert.Equal(DriftWarning, result.Findings[len(result.Findings)-1].Severity)
+	assert.Contains(result.Text(), "Drift detected: 3 error(s), 7 warning(s)")
+	assert.Contains(result.Text(), "has been replaced by sle-module-server-applications_15.6_x86_64")
+
+	decoded := VerifyResult{}
+	assert.NoError(json.Unmarshal([]byte(result.JSON()), &decoded))
+	assert.Equal(*result, decoded)
+}
+
+func TestVerifyBaseProductNotActivated(t *testing.T) {
+	assert := assert.New(t)
+
+	opts, api := mockVerify(t, []*registration.Activation{},
+		[]registration.Product{{Identifier: "SLES", Version: "15.6", Arch: "x86_64", IsBase: true}},
+		[]zypper.ZypperService{})
+
+	result, err := Verify(api, opts)
+	assert.NoError(err)
+	assert.Len(result.Findings, 1)
+	assert.Equal(DriftError, result.Findings[0].Severity)
+	assert.Equal(DriftProductNotActivated, result.Findings[0].Kind)
+}
+
+func TestVerifySkipServiceInstall(t *testing.T) {
+	assert := assert.New(t)
+
+	sles := verifyActivation("SLES", true)
+	opts, api := mockVerify(t, []*registration.Activation{sles}, []registration.Product{*sles.Product}, nil)
+	opts.SkipServiceInstall = true
+	localInstalledServices = func() ([]zypper.ZypperService, error) {
+		t.Fatal("services should not be read")
+		return nil, nil
+	}
+
+	result, err := Verify(api, opts)
+	assert.NoError(err)
+	assert.False(result.Drift)
+}
+
+func TestVerifyNotRegistered(t *testing.T) {
+	assert := assert.New(t)
+
+	api := NewMockWrappedAPI()
+	api.On("IsRegistered").Return(false)
+	_, err := Verify(api, DefaultOptions())
+	assert.ErrorIs(err, ErrSystemNotRegistered)
+}
+
+func TestSameServiceURL(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(sameServiceURL("https://scc.suse.com/access/services/1/?credentials=a&ssl_verify=no", "https://scc.suse.com/access/services/1?credentials=a"))
+	assert.False(sameServiceURL("https://scc.suse.com/access/services/2?credentials=a", "https://scc.suse.com/access/services/1?credentials=a"))
+}
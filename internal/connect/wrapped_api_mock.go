@@ -31,23 +31,29 @@ func NewMockWrappedAPI() *MockWrappedAPI {
 // INFO: For information how to handle arguments in the mocked interface implementation
 // check: https://pkg.go.dev/github.com/stretchr/testify/mock
 
-func (m *MockWrappedAPI) KeepAlive() error {
-	args := m.Called()
+func (m *MockWrappedAPI) KeepAlive(uptimeTracking bool) error {
+	args := m.Called(uptimeTracking)
 
 	return args.Error(0)
 }
 
-func (m *MockWrappedAPI) Register(regcode string) error {
-	args := m.Called(regcode)
+func (m *MockWrappedAPI) Register(opts *Options) error {
+	args := m.Called(opts)
 
 	return args.Error(0)
 }
-func (m *MockWrappedAPI) RegisterOrKeepAlive(regcode string) error {
-	args := m.Called(regcode)
+func (m *MockWrappedAPI) RegisterOrKeepAlive(opts *Options) error {
+	args := m.Called(opts)
 
 	return args.Error(0)
 }
 
+func (m *MockWrappedAPI) IsRegistered() bool {
+	args := m.Called()
+
+	return args.Bool(0)
+}
+
 func (m *MockWrappedAPI) AssignLabels(assigned []string) ([]labels.Label, error) {
 	args := m.Called(assigned)
 
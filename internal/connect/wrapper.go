@@ -2,6 +2,7 @@ package connect
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/SUSE/connect-ng/internal/collectors"
 	"github.com/SUSE/connect-ng/internal/credentials"
+	"github.com/SUSE/connect-ng/internal/i18n"
 	"github.com/SUSE/connect-ng/internal/util"
 	collectorsconfig "github.com/SUSE/connect-ng/pkg/collectors"
 	"github.com/SUSE/connect-ng/pkg/connection"
@@ -88,7 +90,7 @@ func buildCollectorOptions(rawConfig map[string]map[string]string) collectorscon
 		// Warn on unknown configuration keys
 		for key := range entry {
 			if key != "state" {
-				util.Info.Printf("Warning: Unknown configuration key '%s' for collector '%s'\n", key, collectorName)
+				util.Info.Print(i18n.Tf("Warning: Unknown configuration key '%s' for collector '%s'\n", key, collectorName))
 			}
 		}
 
@@ -108,16 +110,6 @@ func NewWrappedAPI(opts *Options) WrappedAPI {
 		opts.Collectors = buildCollectorOptions(opts.CollectorsRaw)
 	}
 
-	connectionOpts := connection.Options{
-		URL:              opts.BaseURL,
-		Secure:           !opts.Insecure,
-		AppName:          "SUSEConnect",
-		Version:          GetShortenedVersion(),
-		PreferedLanguage: opts.Language,
-		Timeout:          connection.DefaultTimeout,
-		Proxy:            proxyWithAuth,
-	}
-
 	credentialsPath := credentials.SystemCredentialsPath(opts.FsRoot)
 	creds, err := credentials.ReadCredentials(credentialsPath)
 	registered := false
@@ -129,6 +121,27 @@ func NewWrappedAPI(opts *Options) WrappedAPI {
 		// future writes don't fail and can create a new credentials file.
 		creds.Filename = credentialsPath
 	}
+	return newWrapper(opts, creds, registered)
+}
+
+// Returns a new Wrapper object for the given Options which is not registered,
+// regardless of the credentials available on the system. Registering through
+// it overwrites the system credentials.
+func newUnregisteredAPI(opts *Options) WrappedAPI {
+	creds := credentials.Credentials{Filename: credentials.SystemCredentialsPath(opts.FsRoot)}
+	return newWrapper(opts, creds, false)
+}
+
+func newWrapper(opts *Options, creds credentials.Credentials, registered bool) WrappedAPI {
+	connectionOpts := connection.Options{
+		URL:              opts.BaseURL,
+		Secure:           !opts.Insecure,
+		AppName:          "SUSEConnect",
+		Version:          GetShortenedVersion(),
+		PreferedLanguage: opts.Language,
+		Timeout:          connection.DefaultTimeout,
+		Proxy:            proxyWithAuth,
+	}
 
 	return &Wrapper{
 		Connection: &connection.ApiConnection{
@@ -153,13 +166,14 @@ func (w Wrapper) KeepAlive(uptimeTracking bool) error {
 
 	// If the uptime tracking log is requested via the configuration, attach it
 	// now.
-	extraData := registration.NoExtraData
+	extraData := registration.ExtraData{}
+	var onlineAt []string
 	if uptimeTracking {
-		data, err := readUptimeLogFile(UptimeLogFilePath)
+		onlineAt, err = readUptimeLogFile(UptimeLogFilePath)
 		if err != nil {
 			return err
 		}
-		extraData["online_at"] = data
+		extraData["online_at"] = onlineAt
 	}
 
 	profileData, err := FetchSystemProfiles(arch, true, w.options.Collectors)
@@ -168,12 +182,33 @@ func (w Wrapper) KeepAlive(uptimeTracking bool) error {
 		return fmt.Errorf("could not fetch system's profiles: %v", err)
 	}
 
-	code, err := registration.Status(w.Connection, hostname, hwinfo, profileData, extraData)
+	// The system information rarely changes, only send it when it did or
+	// when it has not been sent for a while.
+	sysInfo := hwinfo
+	changed, err := profiles.SystemInfoChanged(true, hwinfo)
+	if err != nil {
+		slog.Debug("Could not update the system information cache", "error", err)
+	} else if !changed {
+		slog.Debug("System information unchanged since the last keepalive, not sending it")
+		sysInfo = nil
+	}
+
+	// Calls queued while the server was unreachable go first.
+	code := registration.Unknown
+	err = replayKeepAliveSpool(w.Connection, w.options)
+	if err == nil {
+		code, err = registration.Status(w.Connection, hostname, sysInfo, profileData, extraData)
+	}
 	if code == registration.Unregistered {
 		profiles.DeleteProfileCache("*-profile-id")
 		return ErrPingFromUnregistered
 	} else if err != nil {
 		profiles.DeleteProfileCache("*-profile-id")
+		if connection.IsUnreachable(err) {
+			if spoolErr := spoolKeepAlive(w.options, hostname, onlineAt); spoolErr != nil {
+				slog.Warn("Could not queue keepalive", "path", KeepAliveSpoolDir, "error", spoolErr)
+			}
+		}
 	}
 
 	return err
@@ -238,7 +273,7 @@ func (w Wrapper) AssignLabels(assigned []string) ([]labels.Label, error) {
 		name = strings.TrimSpace(name)
 		collection = append(collection, labels.Label{Name: name})
 	}
-	util.Debug.Printf(util.Bold("Setting Labels %s"), strings.Join(assigned, ","))
+	slog.Debug("Setting labels", "labels", strings.Join(assigned, ","))
 
 	return labels.AssignLabels(w.Connection, collection)
 }
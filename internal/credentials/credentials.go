@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -52,12 +53,12 @@ func (c *Credentials) Token() (string, error) {
 func (c *Credentials) UpdateToken(token string) error {
 	token = strings.TrimSpace(token)
 	if token == "" {
-		util.Debug.Printf("Skipping null token\n")
+		slog.Debug("Skipping null token")
 		return nil
 	}
 	c.SystemToken = token
 
-	util.Debug.Printf("Token has been updated to `%s`\n", c.Username)
+	slog.Debug("System token has been updated", "username", c.Username)
 	return c.write()
 }
 
@@ -74,10 +75,15 @@ func (c *Credentials) SetLogin(username, password string) error {
 	c.Username = username
 	c.Password = password
 
-	util.Debug.Printf("Login has been set for system login `%s`\n", c.Username)
+	slog.Debug("Login has been set", "username", c.Username)
 	return c.write()
 }
 
+// LogValue leaves the password and the system token out of log records.
+func (c Credentials) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("file", c.Filename), slog.String("username", c.Username))
+}
+
 func (c *Credentials) String() string {
 	return fmt.Sprintf("file: %s, username: %s, password: REDACTED, system_token: %s",
 		c.Filename, c.Username, c.SystemToken)
@@ -97,7 +103,7 @@ func CurlrcCredentialsPath() string {
 
 // ReadCredentials returns the credentials from path
 func ReadCredentials(path string) (Credentials, error) {
-	util.Debug.Print("Reading credentials: ", path)
+	slog.Debug("Reading credentials", "path", path)
 	if !util.FileExists(path) {
 		return Credentials{}, ErrMissingCredentialsFile
 	}
@@ -111,7 +117,7 @@ func ReadCredentials(path string) (Credentials, error) {
 		return Credentials{}, err
 	}
 	creds.Filename = path
-	util.Debug.Print("Credentials read: ", creds)
+	slog.Debug("Credentials read", "credentials", creds)
 	return creds, nil
 }
 
@@ -135,7 +141,7 @@ func parseCredentials(r io.Reader) (Credentials, error) {
 }
 
 func (c Credentials) write() error {
-	util.Debug.Print("Writing credentials: ", c)
+	slog.Debug("Writing credentials", "credentials", c)
 	path := c.Filename
 	dir := filepath.Dir(path)
 	if !util.FileExists(dir) {
@@ -164,7 +170,7 @@ func CreateCredentials(login, password, systemToken, path string) error {
 }
 
 func readCurlrcCredentials(path string) (Credentials, error) {
-	util.Debug.Print("Reading proxy credentials: ", path)
+	slog.Debug("Reading proxy credentials", "path", path)
 	f, err := os.Open(path)
 	if err != nil {
 		return Credentials{}, err
@@ -175,7 +181,7 @@ func readCurlrcCredentials(path string) (Credentials, error) {
 		return Credentials{}, err
 	}
 	creds.Filename = path
-	util.Debug.Print("Proxy credentials read: ", creds)
+	slog.Debug("Proxy credentials read", "credentials", creds)
 	return creds, nil
 }
 
@@ -0,0 +1,113 @@
+// Package i18n translates the messages shown to the user by the command line
+// tools. Translations are gettext catalogs (po/<language>.po) embedded into
+// the binaries, the template of all messages is po/suseconnect.pot. The
+// language is taken from the environment (LC_ALL, LC_MESSAGES and LANG, in
+// this order). Messages without translation are shown in English.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed po/*.po
+var catalogs embed.FS
+
+var (
+	mu       sync.RWMutex
+	loaded   bool
+	language string
+	messages map[string]string
+)
+
+// T returns the translation of the given message into the language of the
+// user, or the message itself if there is none.
+func T(msgid string) string {
+	ensureLoaded()
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if msgstr, ok := messages[msgid]; ok {
+		return msgstr
+	}
+	return msgid
+}
+
+// Tf translates the given format and formats it with the given arguments as
+// fmt.Sprintf does.
+func Tf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Language returns the language of the catalog in use, or an empty string if
+// messages are shown in English.
+func Language() string {
+	ensureLoaded()
+
+	mu.RLock()
+	defer mu.RUnlock()
+	return language
+}
+
+// SetLanguage selects the catalog for the given locale (e.g. "de_DE.UTF-8").
+// The catalog of the territory is preferred ("pt_BR"), then the one of the
+// language ("pt"). Unknown locales, "C" and "POSIX" select English.
+func SetLanguage(locale string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	loaded = true
+	language, messages = "", nil
+	for _, candidate := range localeCandidates(locale) {
+		data, err := catalogs.ReadFile("po/" + candidate + ".po")
+		if err != nil {
+			continue
+		}
+		catalog, err := parsePO(string(data))
+		if err != nil {
+			slog.Debug("Could not load translations", "language", candidate, "error", err)
+			return
+		}
+		language, messages = candidate, catalog
+		return
+	}
+}
+
+func ensureLoaded() {
+	mu.RLock()
+	done := loaded
+	mu.RUnlock()
+	if !done {
+		SetLanguage(environmentLocale())
+	}
+}
+
+// environmentLocale returns the locale of the messages as configured in the
+// environment.
+func environmentLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// localeCandidates returns the catalogs to try for the given locale, e.g.
+// "de_DE" and "de" for "de_DE.UTF-8@euro".
+func localeCandidates(locale string) []string {
+	locale, _, _ = strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, ".")
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+	candidates := []string{locale}
+	if lang, _, ok := strings.Cut(locale, "_"); ok {
+		candidates = append(candidates, lang)
+	}
+	return candidates
+}
@@ -0,0 +1,351 @@
+package i18n
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "regenerate po/suseconnect.pot from the sources")
+
+// Directories, relative to the root of the repository, holding messages to
+// be translated.
+var sourceDirs = []string{
+	"cmd/suseconnect",
+	"cmd/zypper-migration",
+	"cmd/zypper-search-packages",
+	"internal/connect",
+}
+
+// Calls whose argument at the given position is a message to be translated,
+// like the keywords of xgettext.
+var keywords = map[string]int{
+	"i18n.T":     0,
+	"i18n.Tf":    0,
+	"result.add": 1,
+}
+
+const potHeader = `# Messages of SUSEConnect, zypper-migration and zypper-search-packages.
+# Generated by "go test ./internal/i18n -update", do not edit.
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+`
+
+// extractMessages returns the messages to be translated found in the sources,
+// with the files they are used in.
+func extractMessages(t *testing.T) map[string][]string {
+	root := filepath.Join("..", "..")
+	found := map[string]map[string]bool{}
+
+	for _, dir := range sourceDirs {
+		fset := token.NewFileSet()
+		pkgs, err := parser.ParseDir(fset, filepath.Join(root, dir), func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, parser.ParseComments)
+		if err != nil {
+			t.Fatalf("Could not parse %s: %v", dir, err)
+		}
+
+		for _, pkg := range pkgs {
+			constants := packageStrings(t, filepath.Join(root, dir), pkg)
+			for path, file := range pkg.Files {
+				name := filepath.ToSlash(filepath.Join(dir, filepath.Base(path)))
+				ast.Inspect(file, func(n ast.Node) bool {
+					call, ok := n.(*ast.CallExpr)
+					if !ok {
+						return true
+					}
+					sel, ok := call.Fun.(*ast.SelectorExpr)
+					if !ok {
+						return true
+					}
+					x, ok := sel.X.(*ast.Ident)
+					if !ok {
+						return true
+					}
+					pos, ok := keywords[x.Name+"."+sel.Sel.Name]
+					if !ok || pos >= len(call.Args) {
+						return true
+					}
+					// Formats passed through variables are extracted where
+					// they are defined.
+					if msgid, ok := stringValue(call.Args[pos], constants); ok {
+						if found[msgid] == nil {
+							found[msgid] = map[string]bool{}
+						}
+						found[msgid][name] = true
+					}
+					return true
+				})
+			}
+		}
+	}
+
+	messages := map[string][]string{}
+	for msgid, files := range found {
+		for file := range files {
+			messages[msgid] = append(messages[msgid], file)
+		}
+		sort.Strings(messages[msgid])
+	}
+	return messages
+}
+
+// packageStrings returns the string constants of the package, and the
+// strings embedded from files (e.g. the usage texts).
+func packageStrings(t *testing.T, dir string, pkg *ast.Package) map[string]string {
+	values := map[string]string{}
+	for _, file := range pkg.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || (gen.Tok != token.CONST && gen.Tok != token.VAR) {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if vs.Doc != nil {
+					for _, c := range vs.Doc.List {
+						if embed, ok := strings.CutPrefix(c.Text, "//go:embed "); ok {
+							data, err := os.ReadFile(filepath.Join(dir, strings.TrimSpace(embed)))
+							if err != nil {
+								t.Fatalf("Could not read embedded file: %v", err)
+							}
+							values[vs.Names[0].Name] = string(data)
+						}
+					}
+				}
+				if gen.Tok != token.CONST {
+					continue
+				}
+				for i, name := range vs.Names {
+					if i < len(vs.Values) {
+						if value, ok := stringValue(vs.Values[i], nil); ok {
+							values[name.Name] = value
+						}
+					}
+				}
+			}
+		}
+	}
+	return values
+}
+
+func stringValue(expr ast.Expr, constants map[string]string) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		value, err := strconv.Unquote(e.Value)
+		return value, err == nil
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		left, ok := stringValue(e.X, constants)
+		if !ok {
+			return "", false
+		}
+		right, ok := stringValue(e.Y, constants)
+		return left + right, ok
+	case *ast.ParenExpr:
+		return stringValue(e.X, constants)
+	case *ast.Ident:
+		value, ok := constants[e.Name]
+		return value, ok
+	}
+	return "", false
+}
+
+func renderPOT(messages map[string][]string) string {
+	msgids := make([]string, 0, len(messages))
+	for msgid := range messages {
+		msgids = append(msgids, msgid)
+	}
+	sort.Strings(msgids)
+
+	var b strings.Builder
+	b.WriteString(potHeader)
+	for _, msgid := range msgids {
+		b.WriteString("\n")
+		for _, file := range messages[msgid] {
+			b.WriteString("#: " + file + "\n")
+		}
+		if verbs.MatchString(msgid) {
+			b.WriteString("#, c-format\n")
+		}
+		b.WriteString(formatPO("msgid", msgid))
+		b.WriteString("msgstr \"\"\n")
+	}
+	return b.String()
+}
+
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func formatVerbs(s string) []string {
+	found := []string{}
+	for _, verb := range verbs.FindAllString(s, -1) {
+		if verb != "%%" {
+			found = append(found, verb)
+		}
+	}
+	return found
+}
+
+func TestTemplateUpToDate(t *testing.T) {
+	pot := renderPOT(extractMessages(t))
+	path := filepath.Join("po", "suseconnect.pot")
+	if *update {
+		if err := os.WriteFile(path, []byte(pot), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != pot {
+		t.Errorf("%s is out of date, run: go test ./internal/i18n -update", path)
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	assert := assert.New(t)
+
+	template, err := os.ReadFile(filepath.Join("po", "suseconnect.pot"))
+	assert.NoError(err)
+	entries, err := parsePOEntries(string(template))
+	assert.NoError(err)
+	known := map[string]bool{}
+	for _, entry := range entries {
+		known[entry.msgid] = true
+	}
+
+	files, err := filepath.Glob(filepath.Join("po", "*.po"))
+	assert.NoError(err)
+	assert.NotEmpty(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		assert.NoError(err)
+		entries, err := parsePOEntries(string(data))
+		if !assert.NoError(err, file) {
+			continue
+		}
+		for _, entry := range entries {
+			assert.True(known[entry.msgid], "%s: obsolete message %q", file, entry.msgid)
+			if entry.msgstr == "" || entry.fuzzy {
+				continue
+			}
+			assert.Equal(formatVerbs(entry.msgid), formatVerbs(entry.msgstr), "%s: verbs of %q", file, entry.msgid)
+		}
+	}
+}
+
+func TestLocaleCandidates(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{"de_DE", "de"}, localeCandidates("de_DE.UTF-8@euro"))
+	assert.Equal([]string{"de_DE", "de"}, localeCandidates("de_DE"))
+	assert.Equal([]string{"de"}, localeCandidates("de"))
+	assert.Empty(localeCandidates("C.UTF-8"))
+	assert.Empty(localeCandidates("POSIX"))
+	assert.Empty(localeCandidates(""))
+}
+
+func TestEnvironmentLocale(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "de_DE.UTF-8")
+	assert.Equal("de_DE.UTF-8", environmentLocale())
+
+	t.Setenv("LC_MESSAGES", "C")
+	assert.Equal("C", environmentLocale())
+
+	t.Setenv("LC_ALL", "fr_FR")
+	assert.Equal("fr_FR", environmentLocale())
+}
+
+func TestTranslate(t *testing.T) {
+	assert := assert.New(t)
+	defer SetLanguage("C")
+
+	SetLanguage("de_AT.UTF-8")
+	assert.Equal("de", Language())
+	assert.Equal("System erfolgreich registriert", T("Successfully registered system"))
+	assert.Equal("Not a message of the catalog", T("Not a message of the catalog"))
+	assert.Equal("System wird bei SCC registriert", Tf("Registering system to %s", "SCC"))
+
+	SetLanguage("xx_XX.UTF-8")
+	assert.Equal("", Language())
+	assert.Equal("Successfully registered system", T("Successfully registered system"))
+
+	SetLanguage("C")
+	assert.Equal("", Language())
+	assert.Equal("Registering system to SCC", Tf("Registering system to %s", "SCC"))
+}
+
+func TestParsePO(t *testing.T) {
+	assert := assert.New(t)
+
+	catalog, err := parsePO(`# comment
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+
+#: cmd/suseconnect/suseconnect.go
+msgid "one"
+msgstr "eins"
+
+msgid ""
+"multi\n"
+"line"
+msgstr ""
+"mehr\n"
+"zeilig"
+
+#, fuzzy
+msgid "fuzzy"
+msgstr "unscharf"
+
+msgid "untranslated"
+msgstr ""
+
+msgid "escaped \"quotes\"\t"
+msgstr "\"Anführungszeichen\"\t"
+`)
+	assert.NoError(err)
+	assert.Equal(map[string]string{
+		"one":                  "eins",
+		"multi\nline":          "mehr\nzeilig",
+		"escaped \"quotes\"\t": "\"Anführungszeichen\"\t",
+	}, catalog)
+
+	_, err = parsePO("msgid \"one\"\nmsgctxt \"menu\"\n")
+	assert.ErrorContains(err, "line 2: unsupported keyword")
+
+	_, err = parsePO("msgid \"unterminated\n")
+	assert.ErrorContains(err, "line 1: invalid string")
+}
+
+func TestFormatPO(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("msgid \"one\"\n", formatPO("msgid", "one"))
+	assert.Equal("msgid \"one\\n\"\n", formatPO("msgid", "one\n"))
+	assert.Equal("msgid \"\"\n\"one\\n\"\n\"two\"\n", formatPO("msgid", "one\ntwo"))
+	assert.Equal("msgstr \"\\\"quoted\\\"\"\n", formatPO("msgstr", "\"quoted\""))
+}
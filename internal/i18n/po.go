@@ -0,0 +1,118 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// poEntry is a message of a gettext catalog.
+type poEntry struct {
+	msgid  string
+	msgstr string
+	fuzzy  bool
+}
+
+// parsePO parses a gettext catalog and returns its translated messages.
+// Untranslated and fuzzy messages, as well as the header, are left out.
+func parsePO(data string) (map[string]string, error) {
+	entries, err := parsePOEntries(data)
+	if err != nil {
+		return nil, err
+	}
+	messages := map[string]string{}
+	for _, entry := range entries {
+		if entry.msgid == "" || entry.msgstr == "" || entry.fuzzy {
+			continue
+		}
+		messages[entry.msgid] = entry.msgstr
+	}
+	return messages, nil
+}
+
+// parsePOEntries parses all the entries of a gettext catalog, including the
+// header. Plural forms and contexts are not supported.
+func parsePOEntries(data string) ([]poEntry, error) {
+	entries := []poEntry{}
+	var current *poEntry
+	var target *string
+	fuzzy := false
+
+	flush := func() {
+		if current != nil {
+			entries = append(entries, *current)
+		}
+		current, target = nil, nil
+	}
+
+	for n, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			flush()
+			fuzzy = false
+		case strings.HasPrefix(line, "#,"):
+			fuzzy = fuzzy || strings.Contains(line, "fuzzy")
+		case strings.HasPrefix(line, "#"):
+			// comments
+		case strings.HasPrefix(line, "msgid "):
+			flush()
+			current = &poEntry{fuzzy: fuzzy}
+			fuzzy = false
+			target = &current.msgid
+			if err := appendString(target, strings.TrimPrefix(line, "msgid ")); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+		case strings.HasPrefix(line, "msgstr "):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: msgstr without msgid", n+1)
+			}
+			target = &current.msgstr
+			if err := appendString(target, strings.TrimPrefix(line, "msgstr ")); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+		case strings.HasPrefix(line, `"`):
+			if target == nil {
+				return nil, fmt.Errorf("line %d: string outside of an entry", n+1)
+			}
+			if err := appendString(target, line); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: unsupported keyword: %s", n+1, line)
+		}
+	}
+	flush()
+	return entries, nil
+}
+
+func appendString(target *string, quoted string) error {
+	value, err := strconv.Unquote(strings.TrimSpace(quoted))
+	if err != nil {
+		return fmt.Errorf("invalid string %s", quoted)
+	}
+	*target += value
+	return nil
+}
+
+// formatPO renders a string as it is written in a gettext catalog, splitting
+// it at the newlines.
+func formatPO(keyword, value string) string {
+	lines := strings.SplitAfter(value, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s %s\n", keyword, quotePO(value))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%s\n", quotePO(line))
+	}
+	return b.String()
+}
+
+func quotePO(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(value) + `"`
+}
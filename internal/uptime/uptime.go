@@ -0,0 +1,230 @@
+// Package uptime reads and writes the uptime log kept by suse-uptime-tracker
+// and sent to the registration server by `SUSEConnect --keepalive`.
+//
+// The log records for each day (UTC) the hours during which the system was
+// running as a string of 24 "0" or "1" characters:
+//
+//	# suse-uptime-log v2 sha256:<checksum of the following lines>
+//	2025-06-01:000000001111000000000000
+//	2025-06-02:000000000000000000000011
+//
+// Files written before the header was introduced (version 1) are read as
+// well and upgraded on the next write. The file is always replaced
+// atomically, and readers and writers synchronize through a lock file next
+// to it.
+package uptime
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// FormatVersion is the version of the format written by this package.
+	FormatVersion = 2
+
+	// DateFormat is the format of the days in the log.
+	DateFormat = "2006-01-02"
+
+	// NoUptime is the entry of a day without any uptime.
+	NoUptime = "000000000000000000000000"
+
+	headerPrefix = "# suse-uptime-log"
+)
+
+// ErrUnsupportedVersion is returned for logs written by a newer version of
+// the tracker, which are left untouched.
+var ErrUnsupportedVersion = errors.New("unsupported uptime log version")
+
+// Log maps days to the hours of uptime on that day.
+type Log map[string]string
+
+// Lines returns the entries of the log as "day:hours", oldest first.
+func (l Log) Lines() []string {
+	days := make([]string, 0, len(l))
+	for day := range l {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	lines := make([]string, 0, len(days))
+	for _, day := range days {
+		lines = append(lines, day+":"+l[day])
+	}
+	return lines
+}
+
+// Read returns the uptime log stored at path, or nil if there is none.
+// Malformed lines are skipped.
+func Read(path string) (Log, error) {
+	// Do not leave a lock file behind if the tracker is not used.
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	unlock := lock(path, syscall.LOCK_SH)
+	defer unlock()
+	return read(path)
+}
+
+// Update reads the uptime log stored at path, passes it to the given
+// function and atomically replaces the file with the returned log. Other
+// readers and writers are locked out in the meantime.
+func Update(path string, update func(Log) (Log, error)) error {
+	unlock := lock(path, syscall.LOCK_EX)
+	defer unlock()
+
+	log, err := read(path)
+	if err != nil {
+		return err
+	}
+	if log == nil {
+		log = Log{}
+	}
+	log, err = update(log)
+	if err != nil {
+		return err
+	}
+	return write(path, log)
+}
+
+func read(path string) (Log, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses the content of an uptime log of any supported version.
+// Malformed lines and checksum mismatches are logged and the valid entries
+// are returned. Entries of the same day are merged.
+func Parse(data []byte) (Log, error) {
+	body := data
+	checksum := ""
+	if bytes.HasPrefix(data, []byte(headerPrefix)) {
+		header, rest, _ := bytes.Cut(data, []byte("\n"))
+		body = rest
+
+		var version int
+		if _, err := fmt.Sscanf(string(header), headerPrefix+" v%d sha256:%s", &version, &checksum); err != nil {
+			slog.Warn("Malformed uptime log header, recovering the entries", "header", string(header))
+		} else if version > FormatVersion {
+			return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+		}
+	}
+	if checksum != "" && checksum != sum(body) {
+		slog.Warn("Uptime log checksum mismatch, recovering the valid entries")
+	}
+
+	log := Log{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		day, hours, ok := parseEntry(line)
+		if !ok {
+			slog.Warn("Skipping malformed uptime log entry", "entry", line)
+			continue
+		}
+		if previous, ok := log[day]; ok {
+			hours = merge(previous, hours)
+		}
+		log[day] = hours
+	}
+	return log, scanner.Err()
+}
+
+// Format returns the content of the file storing the given log.
+func Format(log Log) []byte {
+	var body bytes.Buffer
+	for _, line := range log.Lines() {
+		body.WriteString(line + "\n")
+	}
+	header := fmt.Sprintf("%s v%d sha256:%s\n", headerPrefix, FormatVersion, sum(body.Bytes()))
+	return append([]byte(header), body.Bytes()...)
+}
+
+func parseEntry(line string) (string, string, bool) {
+	day, hours, found := strings.Cut(line, ":")
+	if !found || len(hours) != len(NoUptime) || strings.Trim(hours, "01") != "" {
+		return "", "", false
+	}
+	if _, err := time.Parse(DateFormat, day); err != nil {
+		return "", "", false
+	}
+	return day, hours, true
+}
+
+func merge(a, b string) string {
+	merged := []byte(a)
+	for i := range merged {
+		if b[i] == '1' {
+			merged[i] = '1'
+		}
+	}
+	return string(merged)
+}
+
+func sum(body []byte) string {
+	checksum := sha256.Sum256(body)
+	return hex.EncodeToString(checksum[:])
+}
+
+func write(path string, log Log) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(Format(log)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// lock takes a lock on the lock file of the log at path and returns the
+// function releasing it. The log is read without a lock if the lock file
+// cannot be opened, e.g. on a read-only file system, since it is always
+// replaced atomically.
+func lock(path string, how int) func() {
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		slog.Debug("Could not open the uptime log lock", "path", path+".lock", "error", err)
+		return func() {}
+	}
+	if err := syscall.Flock(int(file.Fd()), how); err != nil {
+		slog.Debug("Could not lock the uptime log", "path", path+".lock", "error", err)
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}
+}
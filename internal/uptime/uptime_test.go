@@ -0,0 +1,85 @@
+package uptime
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMissing(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "suse-uptime.log")
+	log, err := Read(path)
+	assert.NoError(err)
+	assert.Nil(log)
+	assert.NoFileExists(path + ".lock")
+}
+
+func TestMigrateVersion1(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "suse-uptime.log")
+	assert.NoError(os.WriteFile(path, []byte("2024-01-18:000000000000001000110000\n2024-01-13:000000000000000000010000\n"), 0644))
+
+	log, err := Read(path)
+	assert.NoError(err)
+	assert.Equal([]string{"2024-01-13:000000000000000000010000", "2024-01-18:000000000000001000110000"}, log.Lines())
+
+	assert.NoError(Update(path, func(log Log) (Log, error) { return log, nil }))
+	data, _ := os.ReadFile(path)
+	assert.True(strings.HasPrefix(string(data), "# suse-uptime-log v2 sha256:"))
+
+	migrated, err := Read(path)
+	assert.NoError(err)
+	assert.Equal(log, migrated)
+}
+
+func TestParseRecoversEntries(t *testing.T) {
+	assert := assert.New(t)
+
+	data := Format(Log{"2024-01-18": "000000000000001000110000"})
+	// Appended by hand, the checksum does not match anymore.
+	data = append(data, []byte("2024-01-13000000000000000000010000\n2024-13-01:000000000000000000010000\n2024-01-19:0000\n2024-01-18:100000000000000000000000\n")...)
+
+	log, err := Parse(data)
+	assert.NoError(err)
+	assert.Equal(Log{"2024-01-18": "100000000000001000110000"}, log)
+}
+
+func TestParseUnsupportedVersion(t *testing.T) {
+	_, err := Parse([]byte("# suse-uptime-log v3 sha256:abc\n"))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "suse-uptime.log")
+	var wg sync.WaitGroup
+	for hour := 0; hour < 24; hour++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(Update(path, func(log Log) (Log, error) {
+				hours, ok := log["2024-01-18"]
+				if !ok {
+					hours = NoUptime
+				}
+				updated := []byte(hours)
+				updated[hour] = '1'
+				log["2024-01-18"] = string(updated)
+				return log, nil
+			}))
+		}()
+	}
+	wg.Wait()
+
+	log, err := Read(path)
+	assert.NoError(err)
+	assert.Equal(Log{"2024-01-18": "111111111111111111111111"}, log)
+}
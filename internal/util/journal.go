@@ -0,0 +1,78 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Priorities of journal entries, as defined by syslog(3).
+const (
+	JournalErr     = 3
+	JournalWarning = 4
+	JournalNotice  = 5
+	JournalInfo    = 6
+	JournalDebug   = 7
+)
+
+var (
+	// test method overwrites
+	journalSocket = "/run/systemd/journal/socket"
+)
+
+// JournalSend sends a structured entry to the systemd journal using its
+// native protocol. Field names must be upper case and may only contain
+// letters, digits and underscores. Nothing is sent if journald is not
+// running. It does not log anything itself, as it is used by the journal
+// handler of the default logger.
+func JournalSend(message string, priority int, fields map[string]string) error {
+	if _, err := os.Stat(journalSocket); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(journalEntry(message, priority, fields))
+	return err
+}
+
+// journalEntry encodes the entry as expected by journald: a KEY=value line
+// for each field, or the binary form for values spanning several lines.
+func journalEntry(message string, priority int, fields map[string]string) []byte {
+	all := map[string]string{
+		"MESSAGE":           message,
+		"PRIORITY":          strconv.Itoa(priority),
+		"SYSLOG_IDENTIFIER": "SUSEConnect",
+	}
+	for key, value := range fields {
+		all[key] = value
+	}
+
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, key := range keys {
+		value := all[key]
+		if !strings.Contains(value, "\n") {
+			b.WriteString(key + "=" + value + "\n")
+			continue
+		}
+		b.WriteString(key + "\n")
+		binary.Write(&b, binary.LittleEndian, uint64(len(value)))
+		b.WriteString(value + "\n")
+	}
+	return b.Bytes()
+}
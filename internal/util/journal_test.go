@@ -0,0 +1,49 @@
+package util
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalEntry(t *testing.T) {
+	assert := assert.New(t)
+
+	entry := journalEntry("registered", JournalInfo, map[string]string{
+		"SUSECONNECT_OPERATION": "register",
+		"SUSECONNECT_ERROR":     "one\ntwo",
+	})
+	expected := "MESSAGE=registered\n" +
+		"PRIORITY=6\n" +
+		"SUSECONNECT_ERROR\n\x07\x00\x00\x00\x00\x00\x00\x00one\ntwo\n" +
+		"SUSECONNECT_OPERATION=register\n" +
+		"SYSLOG_IDENTIFIER=SUSEConnect\n"
+	assert.Equal(expected, string(entry))
+}
+
+func TestJournalSend(t *testing.T) {
+	assert := assert.New(t)
+
+	socket := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	assert.NoError(err)
+	defer conn.Close()
+
+	defer func(path string) { journalSocket = path }(journalSocket)
+	journalSocket = socket
+
+	assert.NoError(JournalSend("registered", JournalNotice, nil))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	assert.NoError(err)
+	assert.Equal("MESSAGE=registered\nPRIORITY=5\nSYSLOG_IDENTIFIER=SUSEConnect\n", string(buf[:n]))
+}
+
+func TestJournalSendWithoutJournald(t *testing.T) {
+	defer func(path string) { journalSocket = path }(journalSocket)
+	journalSocket = filepath.Join(t.TempDir(), "missing")
+
+	assert.NoError(t, JournalSend("registered", JournalInfo, nil))
+}
@@ -1,28 +1,349 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"maps"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 )
 
+// Levels of the log records. LevelVerbose sits between the debugging output
+// and the regular progress messages, and is enabled by `--verbose`.
+const (
+	LevelDebug   = slog.LevelDebug
+	LevelVerbose = slog.Level(-2)
+	LevelInfo    = slog.LevelInfo
+	LevelWarn    = slog.LevelWarn
+	LevelError   = slog.LevelError
+)
+
+// LogFormat is the format of the log records written to the terminal.
+type LogFormat string
+
+const (
+	// Plain messages as printed before the switch to structured logging:
+	// progress messages go to the standard output, everything else to the
+	// standard error. Attributes are only shown in the debugging output.
+	LogConsole LogFormat = "console"
+	// key=value pairs, see slog.TextHandler.
+	LogText LogFormat = "text"
+	// A JSON document per record, see slog.JSONHandler.
+	LogJSON LogFormat = "json"
+)
+
+// redactedValue replaces the values of secret attributes.
+const redactedValue = "********"
+
 var (
-	// Debug logger for debugging output
-	Debug = log.New(io.Discard, "", 0)
-	// Info logger for standard info output
+	// Info prints the outcome of commands (e.g. "Successfully registered
+	// system"). It is not part of the leveled logging and is printed
+	// regardless of the log level.
 	Info = log.New(os.Stdout, "", 0)
-	// QuietOut is used to simplify --quiet option
-	QuietOut = log.New(io.Discard, "", 0)
+
+	logLevel = new(slog.LevelVar)
+
+	// Attributes holding secrets, compared in lower case.
+	secretKeys = []string{"password", "passwd", "regcode", "token", "system_token", "secret", "authorization", "cookie"}
+
+	// test method overwrites
+	logStdout   io.Writer = os.Stdout
+	logStderr   io.Writer = os.Stderr
+	journalSend           = JournalSend
 )
 
-// EnableDebug turns on debugging output
-func EnableDebug() {
-	Debug.SetOutput(os.Stderr)
+// LogOptions configures the default logger, see SetupLogging.
+type LogOptions struct {
+	Level  slog.Level
+	Format LogFormat
+
+	// Also send the log records to the systemd journal.
+	Journal bool
+}
+
+// ParseLogFormat parses the value given to `--log-format`.
+func ParseLogFormat(value string) (LogFormat, error) {
+	switch format := LogFormat(strings.ToLower(value)); format {
+	case LogConsole, LogText, LogJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown log format %q, use one of: console, text or json", value)
+}
+
+// SetupLogging replaces the default slog logger with one writing records of
+// the given level and above in the given format, and optionally to the
+// systemd journal. Secrets are redacted from the attributes of every record
+// (see RedactAttr).
+func SetupLogging(opts LogOptions) {
+	logLevel.Set(opts.Level)
+
+	var handler slog.Handler
+	handlerOpts := &slog.HandlerOptions{Level: logLevel, ReplaceAttr: RedactAttr}
+	switch opts.Format {
+	case LogText:
+		handler = slog.NewTextHandler(logStderr, handlerOpts)
+	case LogJSON:
+		handler = slog.NewJSONHandler(logStderr, handlerOpts)
+	default:
+		handler = &consoleHandler{level: logLevel, mu: &sync.Mutex{}}
+	}
+	if opts.Journal {
+		handler = multiHandler{handler, &journalHandler{level: logLevel}}
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// Verbose logs the given message at LevelVerbose on the default logger.
+func Verbose(msg string, args ...any) {
+	slog.Log(context.Background(), LevelVerbose, msg, args...)
+}
+
+// RedactAttr masks the values of attributes holding secrets (passwords,
+// registration codes, tokens, ...) and the passwords of URLs. It trims the
+// surrounding white space of messages, which is only meaningful on the
+// console. It is meant to be used as slog.HandlerOptions.ReplaceAttr.
+func RedactAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.MessageKey {
+		return slog.String(a.Key, strings.TrimSpace(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		return a
+	case slog.KindAny:
+		if u, ok := a.Value.Any().(*url.URL); ok && u != nil {
+			return slog.String(a.Key, u.Redacted())
+		}
+	}
+
+	if slices.Contains(secretKeys, strings.ToLower(a.Key)) {
+		if a.Value.Kind() == slog.KindString && a.Value.String() == "" {
+			return a
+		}
+		return slog.String(a.Key, redactedValue)
+	}
+	if a.Value.Kind() == slog.KindString {
+		return slog.String(a.Key, redactURL(a.Value.String()))
+	}
+	return a
+}
+
+// redactURL masks the password of the given value if it is a URL.
+func redactURL(value string) string {
+	if !strings.Contains(value, "@") || !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+	return u.Redacted()
+}
+
+// consoleHandler prints the plain messages of the records.
+type consoleHandler struct {
+	level  slog.Leveler
+	attrs  string
+	prefix string
+
+	mu *sync.Mutex
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+
+	// Keep the regular output as it is, attributes are only useful to debug.
+	if r.Level < LevelVerbose {
+		b.WriteString(h.attrs)
+		r.Attrs(func(a slog.Attr) bool {
+			writeConsoleAttr(&b, h.prefix, a)
+			return true
+		})
+	}
+	if !strings.HasSuffix(b.String(), "\n") {
+		b.WriteString("\n")
+	}
+
+	out := logStderr
+	if r.Level >= LevelVerbose && r.Level < LevelWarn {
+		out = logStdout
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		writeConsoleAttr(&b, h.prefix, a)
+	}
+	clone := *h
+	clone.attrs = b.String()
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+func writeConsoleAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		for _, member := range a.Value.Group() {
+			writeConsoleAttr(b, prefix+a.Key+".", member)
+		}
+		return
+	}
+	a = RedactAttr(nil, a)
+	if a.Key == "" {
+		return
+	}
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, value)
+}
+
+// journalHandler sends the records to the systemd journal. Attributes are
+// sent as SUSECONNECT_<KEY> fields.
+type journalHandler struct {
+	level  slog.Leveler
+	fields map[string]string
+	prefix string
+}
+
+func (h *journalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := maps.Clone(h.fields)
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addJournalField(fields, h.prefix, a)
+		return true
+	})
+	return journalSend(strings.TrimSpace(r.Message), journalPriority(r.Level), fields)
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.fields = maps.Clone(h.fields)
+	if clone.fields == nil {
+		clone.fields = map[string]string{}
+	}
+	for _, a := range attrs {
+		addJournalField(clone.fields, h.prefix, a)
+	}
+	return &clone
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "_"
+	return &clone
+}
+
+func addJournalField(fields map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		for _, member := range a.Value.Group() {
+			addJournalField(fields, prefix+a.Key+"_", member)
+		}
+		return
+	}
+	a = RedactAttr(nil, a)
+	if a.Key == "" {
+		return
+	}
+	fields[journalFieldName(prefix+a.Key)] = a.Value.String()
+}
+
+// journalFieldName converts an attribute key into a valid journal field
+// name: upper case letters, digits and underscores.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	return "SUSECONNECT_" + name
+}
+
+func journalPriority(level slog.Level) int {
+	switch {
+	case level >= LevelError:
+		return JournalErr
+	case level >= LevelWarn:
+		return JournalWarning
+	case level >= LevelInfo:
+		return JournalInfo
+	}
+	return JournalDebug
+}
+
+// multiHandler passes the records to several handlers.
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var first error
+	for _, h := range m {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
 }
 
-func IsLoggerEnabled(l *log.Logger) bool {
-	return l.Writer() != io.Discard
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
 }
 
 func GreenText(text string) string {
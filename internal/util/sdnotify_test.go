@@ -0,0 +1,48 @@
+package util
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSdNotify(t *testing.T) {
+	assert := assert.New(t)
+
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	assert.NoError(err)
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", socket)
+
+	assert.NoError(SdNotify(SdNotifyReady + "\nSTATUS=Waiting"))
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	assert.NoError(err)
+	assert.Equal("READY=1\nSTATUS=Waiting", string(buf[:n]))
+}
+
+func TestSdNotifyWithoutSystemd(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	assert.NoError(t, SdNotify(SdNotifyReady))
+}
+
+func TestSdWatchdogInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("WATCHDOG_USEC", "")
+	assert.Zero(SdWatchdogInterval())
+
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	assert.Equal(30*time.Second, SdWatchdogInterval())
+
+	// Meant for another process.
+	t.Setenv("WATCHDOG_PID", "1")
+	assert.Zero(SdWatchdogInterval())
+}
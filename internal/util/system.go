@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"os/user"
@@ -45,7 +46,7 @@ type ExecuteFunc func(cmd []string, validExitCodes []int) ([]byte, error)
 
 // Assign function for running external commands to a variable so it can be mocked by tests.
 var Execute ExecuteFunc = func(cmd []string, validExitCodes []int) ([]byte, error) {
-	Debug.Print("Executing: ", cmd)
+	slog.Debug("Executing", "command", strings.Join(cmd, " "))
 	var stderr, stdout bytes.Buffer
 	comm := exec.Command(cmd[0], cmd[1:]...)
 	if systemEcho {
@@ -58,13 +59,7 @@ var Execute ExecuteFunc = func(cmd []string, validExitCodes []int) ([]byte, erro
 	comm.Env = append(os.Environ(), "LC_ALL=C")
 	err := comm.Run()
 	exitCode := comm.ProcessState.ExitCode()
-	Debug.Printf("Return code: %d\n", exitCode)
-	if stdout.Len() > 0 {
-		Debug.Print("Output: ", stdout.String())
-	}
-	if stderr.Len() > 0 {
-		Debug.Print("Error: ", stderr.String())
-	}
+	slog.Debug("Command finished", "command", cmd[0], "exit_code", exitCode, "stdout", stdout.String(), "stderr", stderr.String())
 	// TODO Ruby version also checks stderr for "ABORT request"
 	if err != nil && !slices.Contains(validExitCodes, exitCode) {
 		output := stderr.Bytes()
@@ -110,7 +105,7 @@ var ExecutableExists = func(path string) bool {
 }
 
 func RemoveFile(path string) error {
-	Debug.Print("Removing file: ", path)
+	slog.Debug("Removing file", "path", path)
 	if !FileExists(path) {
 		return nil
 	}
@@ -160,7 +155,8 @@ func ReadOnlyFilesystem(root string) error {
 var CurrentUser = func() string {
 	u, err := user.Current()
 	if err != nil {
-		Debug.Fatal("cannot determine the current user")
+		slog.Error("cannot determine the current user", "error", err)
+		os.Exit(1)
 		return ""
 	}
 	return u.Username
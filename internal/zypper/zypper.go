@@ -3,6 +3,7 @@ package zypper
 import (
 	"encoding/xml"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -64,7 +65,7 @@ func zypperRun(args []string, validExitCodes []int) ([]byte, error) {
 		cmd = append(cmd, "--root", zypperFilesystemRoot)
 	}
 	cmd = append(cmd, args...)
-	util.QuietOut.Printf("\nExecuting '%s'\n\n", strings.Join(cmd, " "))
+	slog.Info("Executing", "command", strings.Join(cmd, " "))
 	output, err := util.Execute(cmd, validExitCodes)
 	if err != nil {
 		if ee, ok := err.(util.ExecuteError); ok {
@@ -203,7 +204,7 @@ func AddService(serviceURL, serviceName string, refresh bool, insecure bool) err
 }
 
 func RemoveService(serviceName string) error {
-	util.Debug.Println("Removing service: ", serviceName)
+	slog.Debug("Removing service", "service", serviceName)
 
 	args := []string{"--non-interactive", "removeservice", serviceName}
 	_, err := zypperRun(args, []int{zypperOK})
@@ -2,7 +2,9 @@ package connection
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 )
 
@@ -38,3 +40,15 @@ func ErrorFromResponse(resp *http.Response) *ApiError {
 	}
 	return ae
 }
+
+// IsUnreachable returns true if the given error means that the registration
+// server could not be reached or is not available at the moment (network
+// errors and 5xx responses), as opposed to a request it rejected.
+func IsUnreachable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var apiErr *ApiError
+	return errors.As(err, &apiErr) && apiErr.Code >= http.StatusInternalServerError
+}
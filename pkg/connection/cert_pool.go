@@ -6,17 +6,16 @@ package connection
 import (
 	"crypto/x509"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
-
-	"github.com/SUSE/connect-ng/internal/util"
 )
 
 func systemRootsPool() *x509.CertPool {
 	systemRoots, systemRootsErr := _loadSystemRoots()
 	if systemRootsErr != nil {
-		util.Debug.Printf("Failed to load system roots: %s", systemRootsErr.Error())
+		slog.Debug("Failed to load system roots", "error", systemRootsErr)
 		return nil
 	}
 	return systemRoots
@@ -7,10 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
-
-	"github.com/SUSE/connect-ng/internal/util"
 )
 
 const (
@@ -173,7 +172,7 @@ func (conn ApiConnection) setupHTTPClient() *http.Client {
 		if err == nil {
 			pool = systemPool.Clone()
 		} else {
-			util.Debug.Printf("Failed to retrieve x509.SystemCertPool(): %s", err.Error())
+			slog.Debug("Failed to retrieve x509.SystemCertPool()", "error", err)
 			pool = x509.NewCertPool()
 		}
 	}
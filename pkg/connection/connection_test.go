@@ -197,6 +197,32 @@ func TestConnectionDoErrorTranslation(t *testing.T) {
 	assert.ErrorContains(doErr, expected)
 }
 
+func TestConnectionDoUnreachable(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := func(response http.ResponseWriter) {
+		response.WriteHeader(http.StatusServiceUnavailable)
+	}
+	server := NewTestServerSetupWith(t, "GET", "/test/api", handler)
+
+	opts := DefaultOptions("testApp", "1.0", "en_US")
+	opts.URL = server.URL
+	conn := New(opts, NoCredentials{})
+
+	request, _ := conn.BuildRequest("GET", "/test/api", "")
+	_, doErr := conn.Do(request)
+	assert.True(IsUnreachable(doErr))
+
+	// Nothing is listening anymore.
+	server.Close()
+	request, _ = conn.BuildRequest("GET", "/test/api", "")
+	_, doErr = conn.Do(request)
+	assert.True(IsUnreachable(doErr))
+
+	assert.False(IsUnreachable(&ApiError{Code: http.StatusNotFound}))
+	assert.False(IsUnreachable(nil))
+}
+
 func TestConnectionUpdateToken(t *testing.T) {
 	assert := assert.New(t)
 
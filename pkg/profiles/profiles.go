@@ -5,9 +5,11 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/SUSE/connect-ng/internal/util"
 )
@@ -17,6 +19,19 @@ type Result = map[string]interface{}
 const clearCacheCount = "clear-cache-count"
 const clearCacheCountLimit = 5
 
+// The checksum of the system information is removed along with the ones of
+// the profiles, e.g. when the server asks for it with the `clear-cache`
+// profiles action.
+const systemInfoCacheFile = "hwinfo-profile-id"
+const systemInfoSentAtFile = "hwinfo-sent-at"
+
+// SystemInfoRefreshInterval is how long unchanged system information may be
+// left out of keepalive calls before it is sent again in full.
+const SystemInfoRefreshInterval = 7 * 24 * time.Hour
+
+// test method overwrites
+var localNow = time.Now
+
 // WrappedProfile interface
 type WrappedProfile interface {
 	DeleteProfileCache(nameFilter string)
@@ -69,7 +84,7 @@ func (cache *ProfileCache) PutCacheValue(name string, value string) error {
 
 	filePath := filepath.Join(GetProfileFilePath(), name)
 	perms = os.FileMode(0644)
-	util.Debug.Println("PutCacheValue file, value: ", filePath, []byte(value))
+	slog.Debug("Updating profile cache", "path", filePath, "size", len(value))
 	err = os.WriteFile(filePath, []byte(value), perms)
 	return err
 }
@@ -132,8 +147,7 @@ func BuildProfile(updateCache bool, tag string, cacheFile string, dataBlock any)
 	// Marshal to Json for ID calculation
 	jsonBytes, err := json.Marshal(dataBlock)
 	if err != nil {
-		util.Debug.Printf("dataBlockType, : %T\n", dataBlock)
-		util.Debug.Println("dataBlock, :", dataBlock)
+		slog.Debug("Unexpected profile data", "type", fmt.Sprintf("%T", dataBlock))
 		fmt.Printf("Failed: BuildProfile: %s, %v\n", "bad input:", err)
 		return Result{}, err
 	}
@@ -147,7 +161,7 @@ func BuildProfile(updateCache bool, tag string, cacheFile string, dataBlock any)
 	if savedCacheId != cacheId {
 		profile.Data = dataBlock
 		if updateCache {
-			util.Debug.Print("updating: ", cacheFile)
+			slog.Debug("Updating profile cache", "path", cacheFile)
 			err := profileCache.PutCacheValue(cacheFile, cacheId)
 			if err != nil {
 				fmt.Printf("Failed: Profile Cache Update: %s, %v\n", cacheFile, err)
@@ -159,9 +173,38 @@ func BuildProfile(updateCache bool, tag string, cacheFile string, dataBlock any)
 	return Result{tag: profile}, nil
 }
 
+// SystemInfoChanged tells whether the given system information has to be
+// sent to the server: it differs from the one sent last, or that was sent
+// longer than SystemInfoRefreshInterval ago. If updateCache is set and the
+// information has to be sent, its checksum is stored as the last sent one.
+func SystemInfoChanged(updateCache bool, systemInfo any) (bool, error) {
+	jsonBytes, err := json.Marshal(systemInfo)
+	if err != nil {
+		return true, err
+	}
+	cacheId := calcSha256(string(jsonBytes))
+
+	sentAt, _ := strconv.ParseInt(profileCache.GetCacheValue(systemInfoSentAtFile), 10, 64)
+	stale := localNow().Sub(time.Unix(sentAt, 0)) >= SystemInfoRefreshInterval
+	if profileCache.GetCacheValue(systemInfoCacheFile) == cacheId && !stale {
+		return false, nil
+	}
+
+	if updateCache {
+		slog.Debug("Updating system information cache", "path", systemInfoCacheFile)
+		if err := profileCache.PutCacheValue(systemInfoCacheFile, cacheId); err != nil {
+			return true, err
+		}
+		if err := profileCache.PutCacheValue(systemInfoSentAtFile, strconv.FormatInt(localNow().Unix(), 10)); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
 // DeleteProfileCache simply calls the interface method
 func DeleteProfileCache(filter string) {
-	util.Debug.Print("clearing ProfileCache with filter: ", filter)
+	slog.Debug("Clearing profile cache", "filter", filter)
 	profileCache.DeleteProfileCache(filter)
 }
 
@@ -175,17 +218,24 @@ func GetProfileFilePath() string {
 }
 
 func ResetFailedProfileUpdate() {
-	util.Debug.Print("reset clearCacheCount: ")
+	slog.Debug("Resetting clear-cache-count")
 	profileCache.PutCacheValue(clearCacheCount, "0")
 }
 
 func IncFailedProfileUpdate() {
 	cnt, _ := strconv.Atoi(profileCache.GetCacheValue(clearCacheCount))
 	cnt++
-	util.Debug.Print("clearCacheCount set to: ", cnt)
+	slog.Debug("Setting clear-cache-count", "count", cnt)
 	profileCache.PutCacheValue(clearCacheCount, strconv.Itoa(cnt))
 }
 
+// FailedProfileUpdates returns how many times in a row sending the profiles
+// has failed. Profiles are not sent anymore once this exceeds a limit.
+func FailedProfileUpdates() int {
+	cnt, _ := strconv.Atoi(profileCache.GetCacheValue(clearCacheCount))
+	return cnt
+}
+
 // SetProfileCache is now correct as profileCache is of type WrappedProfile
 func SetProfileCache(newCache WrappedProfile) {
 	profileCache = newCache
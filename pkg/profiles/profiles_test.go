@@ -2,7 +2,9 @@ package profiles
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/SUSE/connect-ng/internal/util"
 	"github.com/stretchr/testify/assert"
@@ -103,3 +105,62 @@ func TestInterfaceHandling(t *testing.T) {
 	assert.Equal("works3", cacheVal)
 	assert.Equal("dummy", saveCacheVal.Data)
 }
+
+type mapProfileCache map[string]string
+
+func (cache mapProfileCache) DeleteProfileCache(fileFilter string) {
+	for name := range cache {
+		if matched, _ := filepath.Match(fileFilter, name); matched {
+			delete(cache, name)
+		}
+	}
+}
+
+func (cache mapProfileCache) PutCacheValue(name string, value string) error {
+	cache[name] = value
+	return nil
+}
+
+func (cache mapProfileCache) GetCacheValue(name string) string {
+	return cache[name]
+}
+
+func TestSystemInfoChanged(t *testing.T) {
+	assert := assert.New(t)
+
+	prevCache, prevNow := profileCache, localNow
+	t.Cleanup(func() { profileCache, localNow = prevCache, prevNow })
+	cache := mapProfileCache{}
+	SetProfileCache(cache)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	localNow = func() time.Time { return now }
+
+	info := map[string]any{"cpus": 2, "hostname": "test"}
+
+	// Dry runs do not update the cache.
+	changed, err := SystemInfoChanged(false, info)
+	assert.NoError(err)
+	assert.True(changed)
+	assert.Empty(cache)
+
+	changed, _ = SystemInfoChanged(true, info)
+	assert.True(changed)
+	changed, _ = SystemInfoChanged(true, info)
+	assert.False(changed)
+
+	info["cpus"] = 4
+	changed, _ = SystemInfoChanged(true, info)
+	assert.True(changed)
+
+	// Sent again once the refresh interval has passed.
+	now = now.Add(SystemInfoRefreshInterval)
+	changed, _ = SystemInfoChanged(true, info)
+	assert.True(changed)
+	changed, _ = SystemInfoChanged(true, info)
+	assert.False(changed)
+
+	// The server asked to clear the cache.
+	DeleteProfileCache("*-profile-id")
+	changed, _ = SystemInfoChanged(true, info)
+	assert.True(changed)
+}
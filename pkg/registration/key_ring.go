@@ -0,0 +1,219 @@
+package registration
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"embed"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// Public keys used by SCC to sign offline registration certificates. When
+	// SUSE rotates the signing key, the new key is added here while keeping
+	// the previous ones so already issued certificates stay valid.
+	//
+	//go:embed keys/*.pem
+	embeddedKeys embed.FS
+
+	// ErrUnsupportedCertificate is returned when an offline registration
+	// certificate declares a version, cipher and hash combination which is not
+	// known to this library.
+	ErrUnsupportedCertificate = errors.New("unsupported offline certificate")
+
+	// ErrUnknownKey is returned when an offline registration certificate
+	// references a key which is not part of the key ring.
+	ErrUnknownKey = errors.New("unknown offline certificate key")
+)
+
+// A key trusted to sign offline registration certificates.
+type TrustedKey struct {
+	// Identifier of the key. Certificates can reference the key which signed
+	// them through this identifier. See [KeyID].
+	ID string
+
+	Key *rsa.PublicKey
+}
+
+// A set of keys trusted to sign offline registration certificates. Use
+// [DefaultKeyRing] to get a key ring with the keys used by SCC, and add
+// further keys (e.g. the ones from a registration proxy) to it.
+type KeyRing struct {
+	keys map[string]TrustedKey
+}
+
+// The signature scheme of an offline registration certificate as declared by
+// its version, cipher and hash fields.
+type certificateScheme struct {
+	Version string
+	Cipher  string
+	Hash    string
+}
+
+// Verifies the signature of the payload with the given key.
+type signatureVerifier func(key *rsa.PublicKey, payload, signature []byte) error
+
+// All the supported signature schemes. Certificates declaring any other
+// combination are rejected.
+var certificateSchemes = map[certificateScheme]signatureVerifier{
+	{Version: "1", Cipher: "RSA", Hash: "SHA256"}: verifyRSAPSSSHA256,
+}
+
+func verifyRSAPSSSHA256(key *rsa.PublicKey, payload, signature []byte) error {
+	digest := sha256.Sum256(payload)
+	options := &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+		Hash:       crypto.SHA256,
+	}
+	return rsa.VerifyPSS(key, crypto.SHA256, digest[:], signature, options)
+}
+
+// Returns the verifier for the scheme declared by the given certificate.
+func schemeVerifier(cert *OfflineCertificate) (signatureVerifier, error) {
+	scheme := certificateScheme{
+		Version: strings.TrimSpace(cert.Version),
+		Cipher:  strings.ToUpper(strings.TrimSpace(cert.Cipher)),
+		Hash:    strings.ToUpper(strings.TrimSpace(cert.Hash)),
+	}
+	verifier, ok := certificateSchemes[scheme]
+	if !ok {
+		return nil, fmt.Errorf("%w: version %q, cipher %q, hash %q",
+			ErrUnsupportedCertificate, cert.Version, cert.Cipher, cert.Hash)
+	}
+	return verifier, nil
+}
+
+// Returns the identifier of the given key: the first 16 hexadecimal digits of
+// the SHA256 sum of its DER encoding.
+func KeyID(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("public key: %s", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])[:16], nil
+}
+
+// Creates a key ring with the given keys.
+func NewKeyRing(keys ...TrustedKey) *KeyRing {
+	ring := &KeyRing{keys: map[string]TrustedKey{}}
+	for _, key := range keys {
+		ring.Add(key)
+	}
+	return ring
+}
+
+// Returns a new key ring containing the keys used by SCC to sign offline
+// registration certificates.
+func DefaultKeyRing() (*KeyRing, error) {
+	ring := NewKeyRing()
+
+	files, err := embeddedKeys.ReadDir("keys")
+	if err != nil {
+		return nil, fmt.Errorf("public key: %s", err)
+	}
+	for _, file := range files {
+		data, err := embeddedKeys.ReadFile("keys/" + file.Name())
+		if err != nil {
+			return nil, fmt.Errorf("public key: %s", err)
+		}
+		if _, err := ring.AddPEM(data); err != nil {
+			return nil, fmt.Errorf("public key %s: %s. This is a bug", file.Name(), err)
+		}
+	}
+	return ring, nil
+}
+
+// Adds the given key to the key ring. If the key has no identifier, it is
+// computed with [KeyID].
+func (ring *KeyRing) Add(key TrustedKey) error {
+	if key.ID == "" {
+		id, err := KeyID(key.Key)
+		if err != nil {
+			return err
+		}
+		key.ID = id
+	}
+	ring.keys[key.ID] = key
+	return nil
+}
+
+// Adds the PEM encoded (PKIX) RSA public key to the key ring. Returns the
+// identifier of the added key.
+func (ring *KeyRing) AddPEM(data []byte) (string, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("public key: no PEM data found")
+	}
+
+	// PKCS#8 compatible
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("public key: %s", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("public key: only RSA keys are supported")
+	}
+
+	id, err := KeyID(key)
+	if err != nil {
+		return "", err
+	}
+	return id, ring.Add(TrustedKey{ID: id, Key: key})
+}
+
+// Adds all the PEM encoded keys (files ending in ".pem") from the given
+// directory. A missing directory is not considered an error.
+func (ring *KeyRing) AddFromDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := ring.AddPEM(data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Returns the identifiers of all the keys in the key ring, sorted.
+func (ring *KeyRing) IDs() []string {
+	ids := make([]string, 0, len(ring.keys))
+	for id := range ring.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Returns the keys to be tried for the given certificate: the key referenced
+// by the certificate or, if it does not reference any, all of them.
+func (ring *KeyRing) candidates(cert *OfflineCertificate) ([]TrustedKey, error) {
+	if cert.KeyID != "" {
+		key, ok := ring.keys[cert.KeyID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKey, cert.KeyID)
+		}
+		return []TrustedKey{key}, nil
+	}
+
+	keys := make([]TrustedKey, 0, len(ring.keys))
+	for _, id := range ring.IDs() {
+		keys = append(keys, ring.keys[id])
+	}
+	return keys, nil
+}
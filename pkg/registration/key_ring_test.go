@@ -0,0 +1,136 @@
+package registration
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func generatePEMKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %s", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("could not marshal key: %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func validCertificate(t *testing.T) *OfflineCertificate {
+	cert, err := OfflineCertificateFrom(bytes.NewReader(fixture(t, "pkg/registration/offline_certificate/valid.cert")), true)
+	if err != nil {
+		t.Fatalf("could not read certificate: %s", err)
+	}
+	return cert
+}
+
+func TestDefaultKeyRing(t *testing.T) {
+	assert := assert.New(t)
+
+	ring, err := DefaultKeyRing()
+	assert.NoError(err)
+	assert.Len(ring.IDs(), 1)
+	assert.Len(ring.IDs()[0], 16)
+}
+
+func TestKeyRingReferencedKey(t *testing.T) {
+	assert := assert.New(t)
+
+	ring, _ := DefaultKeyRing()
+	cert := validCertificate(t)
+
+	cert.KeyID = ring.IDs()[0]
+	valid, err := cert.IsValidWith(ring)
+	assert.NoError(err)
+	assert.True(valid)
+
+	cert.KeyID = "0123456789abcdef"
+	_, err = cert.IsValidWith(ring)
+	assert.ErrorIs(err, ErrUnknownKey)
+}
+
+func TestKeyRingOtherKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	ring := NewKeyRing()
+	id, err := ring.AddPEM(generatePEMKey(t))
+	assert.NoError(err)
+	assert.Equal([]string{id}, ring.IDs())
+
+	valid, err := validCertificate(t).IsValidWith(ring)
+	assert.NoError(err)
+	assert.False(valid)
+
+	// Rotated keys are tried next to the previous ones.
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "scc.pem"), embeddedKey(t), 0644))
+	assert.NoError(ring.AddFromDir(dir))
+	assert.Len(ring.IDs(), 2)
+
+	valid, err = validCertificate(t).IsValidWith(ring)
+	assert.NoError(err)
+	assert.True(valid)
+}
+
+func TestKeyRingAddFromDir(t *testing.T) {
+	assert := assert.New(t)
+
+	ring := NewKeyRing()
+	assert.NoError(ring.AddFromDir(filepath.Join(t.TempDir(), "missing")))
+	assert.Empty(ring.IDs())
+
+	dir := t.TempDir()
+	assert.NoError(os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0644))
+	assert.ErrorContains(ring.AddFromDir(dir), "broken.pem")
+}
+
+func TestIsValidUnsupportedScheme(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct{ version, cipher, hash string }{
+		{"2", "RSA", "SHA256"},
+		{"1", "ECDSA", "SHA256"},
+		{"1", "RSA", "SHA1"},
+	}
+	for _, test := range tests {
+		cert := validCertificate(t)
+		cert.Version, cert.Cipher, cert.Hash = test.version, test.cipher, test.hash
+
+		valid, err := cert.IsValid()
+		assert.ErrorIs(err, ErrUnsupportedCertificate)
+		assert.False(valid)
+	}
+
+	cert := validCertificate(t)
+	cert.Cipher, cert.Hash = "rsa", "sha256"
+	valid, err := cert.IsValid()
+	assert.NoError(err)
+	assert.True(valid)
+}
+
+func TestValidateAt(t *testing.T) {
+	assert := assert.New(t)
+	cert := validCertificate(t)
+
+	assert.ErrorIs(cert.ValidateAt(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)), ErrCertificateNotYetValid)
+	assert.NoError(cert.ValidateAt(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.ErrorIs(cert.ValidateAt(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)), ErrCertificateExpired)
+}
+
+func embeddedKey(t *testing.T) []byte {
+	data, err := embeddedKeys.ReadFile("keys/scc_public_key.pem")
+	if err != nil {
+		t.Fatalf("could not read embedded key: %s", err)
+	}
+	return data
+}
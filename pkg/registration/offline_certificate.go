@@ -1,16 +1,24 @@
 package registration
 
 import (
-	"crypto"
-	"crypto/rsa"
-	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"time"
 )
 
+var (
+	// ErrCertificateNotYetValid is returned when the subscription of an
+	// offline registration certificate has not started yet.
+	ErrCertificateNotYetValid = errors.New("offline certificate not yet valid")
+
+	// ErrCertificateExpired is returned when the subscription of an offline
+	// registration certificate has expired.
+	ErrCertificateExpired = errors.New("offline certificate expired")
+)
+
 // The information extracted from an offline registration certificate
 // To further inspect payload and signature check [IsValid] and [ExtractPayload].
 type OfflineCertificate struct {
@@ -22,6 +30,10 @@ type OfflineCertificate struct {
 	SystemID         int    `json:"system_id"`
 	ProductName      string `json:"product_name"`
 
+	// Identifier of the key which signed this certificate (see [KeyID]).
+	// Optional, if empty all the keys of the key ring are tried.
+	KeyID string `json:"key_id,omitempty"`
+
 	*OfflinePayload
 }
 
@@ -66,29 +78,63 @@ func OfflineCertificateFrom(reader io.Reader, decodeData bool) (*OfflineCertific
 }
 
 // Checks if the provided offline registration certificate is valid using
-// the public RSA key to validate the included signature
+// the keys used by SCC to validate the included signature. See [IsValidWith]
+// to validate with other keys.
 func (cert *OfflineCertificate) IsValid() (bool, error) {
-	key, keyErr := sccPublicKey()
+	ring, ringErr := DefaultKeyRing()
+	if ringErr != nil {
+		return false, ringErr
+	}
+	return cert.IsValidWith(ring)
+}
 
-	if keyErr != nil {
-		return false, keyErr
+// Checks if the provided offline registration certificate has been signed by
+// one of the keys of the given key ring. The signature is verified according
+// to the version, cipher and hash declared by the certificate. An error
+// wrapping [ErrUnsupportedCertificate] is returned if this combination is not
+// supported, and one wrapping [ErrUnknownKey] if the certificate references a
+// key which is not in the key ring.
+func (cert *OfflineCertificate) IsValidWith(ring *KeyRing) (bool, error) {
+	verify, schemeErr := schemeVerifier(cert)
+	if schemeErr != nil {
+		return false, schemeErr
 	}
 
-	digest := sha256.Sum256([]byte(cert.EncodedPayload))
 	signature, sigErr := cert.Signature()
-
 	if sigErr != nil {
 		return false, sigErr
 	}
 
-	options := &rsa.PSSOptions{
-		SaltLength: rsa.PSSSaltLengthEqualsHash,
-		Hash:       crypto.SHA256,
+	keys, keyErr := ring.candidates(cert)
+	if keyErr != nil {
+		return false, keyErr
+	}
+
+	for _, key := range keys {
+		if verify(key.Key, []byte(cert.EncodedPayload), signature) == nil {
+			return true, nil
+		}
 	}
+	return false, nil
+}
 
-	verifyErr := rsa.VerifyPSS(key, crypto.SHA256, digest[:], signature, options)
+// Checks that the subscription included in the certificate is active at the
+// given time. Returns [ErrCertificateNotYetValid] or [ErrCertificateExpired]
+// otherwise. Note that this does not verify the signature, see [IsValid].
+func (cert *OfflineCertificate) ValidateAt(at time.Time) error {
+	payload, extractErr := cert.ExtractPayload()
+	if extractErr != nil {
+		return extractErr
+	}
 
-	return verifyErr == nil, nil
+	info := payload.SubscriptionInfo
+	if !info.StartsAt.IsZero() && at.Before(info.StartsAt) {
+		return fmt.Errorf("%w: starts at %s", ErrCertificateNotYetValid, info.StartsAt.Format(time.RFC3339))
+	}
+	if !at.Before(info.ExpiresAt) {
+		return fmt.Errorf("%w: expired at %s", ErrCertificateExpired, info.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
 }
 
 // Extracts the payload from an offline registration certificate
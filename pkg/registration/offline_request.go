@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"strings"
 
@@ -56,6 +57,29 @@ func (req *OfflineRequest) Base64Encoded() (io.Reader, error) {
 	return reader, nil
 }
 
+// Reads an offline registration request from the given reader object. Set
+// `decodeData` to true if the data is expected to be base64-encoded.
+func OfflineRequestFrom(reader io.Reader, decodeData bool) (*OfflineRequest, error) {
+	request := &OfflineRequest{}
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read request error: %s", err)
+	}
+
+	if decodeData {
+		raw, err = decodeBase64(bytes.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("decode request error: %s", err)
+		}
+	}
+
+	if err = json.Unmarshal(raw, request); err != nil {
+		return nil, fmt.Errorf("json error: %s", err)
+	}
+	return request, nil
+}
+
 // Builds an offline registration request with it respective required attributes.
 // See [OfflineRequest.SetCredentials] for optional attributes.
 func BuildOfflineRequest(identifier, version, arch string, systemInformation SystemInformation) *OfflineRequest {
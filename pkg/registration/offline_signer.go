@@ -0,0 +1,171 @@
+package registration
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Issues offline registration certificates. This allows registration proxies
+// in air-gapped networks to hand out offline registration certificates to
+// the systems behind them. Systems validating these certificates need to
+// trust the public key of the signer, see [KeyRing].
+type OfflineSigner struct {
+	key   *rsa.PrivateKey
+	keyID string
+}
+
+// Creates a signer which signs offline registration certificates with the
+// given key.
+func NewOfflineSigner(key *rsa.PrivateKey) (*OfflineSigner, error) {
+	id, err := KeyID(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &OfflineSigner{key: key, keyID: id}, nil
+}
+
+// Creates a signer from a PEM encoded RSA private key (PKCS#1 or PKCS#8).
+func NewOfflineSignerFromPEM(data []byte) (*OfflineSigner, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return NewOfflineSigner(key)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %s", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key: only RSA keys are supported")
+	}
+	return NewOfflineSigner(key)
+}
+
+// The identifier of the signing key, which is referenced by the issued
+// certificates.
+func (signer *OfflineSigner) KeyID() string {
+	return signer.keyID
+}
+
+// The public key to be added to the key ring of the systems validating the
+// issued certificates.
+func (signer *OfflineSigner) PublicKey() *rsa.PublicKey {
+	return &signer.key.PublicKey
+}
+
+// Issues an offline registration certificate for the system which created the
+// given offline request. The certificate proves the knowledge of the
+// registration code and is bound to the UUID included in the system
+// information of the request. If the request carries no credentials, new ones
+// are generated for the system.
+func (signer *OfflineSigner) Sign(request *OfflineRequest, regcode string, subscription SubscriptionInfo) (*OfflineCertificate, error) {
+	uuid, _ := request.SystemInformation["uuid"].(string)
+	if uuid == "" {
+		return nil, fmt.Errorf("offline request: no system uuid included")
+	}
+	if regcode == "" {
+		return nil, fmt.Errorf("offline request: no registration code given")
+	}
+
+	payload := &OfflinePayload{
+		Login:            request.Login,
+		Password:         request.Password,
+		SubscriptionInfo: subscription,
+		HashedRegcode:    calcSHA256From(regcode),
+		HashedUUID:       calcSHA256From(uuid),
+		Information:      request.SystemInformation,
+	}
+	if payload.Login == "" {
+		login, password, err := generateCredentials()
+		if err != nil {
+			return nil, err
+		}
+		payload.Login, payload.Password = login, password
+	}
+
+	data, jsonErr := json.Marshal(payload)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	encodedPayload := base64.StdEncoding.EncodeToString(data)
+
+	digest := sha256.Sum256([]byte(encodedPayload))
+	options := &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+		Hash:       crypto.SHA256,
+	}
+	signature, signErr := rsa.SignPSS(rand.Reader, signer.key, crypto.SHA256, digest[:], options)
+	if signErr != nil {
+		return nil, fmt.Errorf("sign: %s", signErr)
+	}
+
+	return &OfflineCertificate{
+		Version:          "1",
+		Cipher:           "RSA",
+		Hash:             "SHA256",
+		EncodedPayload:   encodedPayload,
+		EncodedSignature: base64.StdEncoding.EncodeToString(signature),
+		ProductName:      request.Product.Identifier,
+		KeyID:            signer.keyID,
+		OfflinePayload:   payload,
+	}, nil
+}
+
+// signedCertificate is the wire format of an issued certificate, as read by
+// [OfflineCertificateFrom]. The payload is only carried in its signed,
+// encoded form.
+type signedCertificate struct {
+	Version          string `json:"version"`
+	Cipher           string `json:"cipher"`
+	Hash             string `json:"hash"`
+	EncodedPayload   string `json:"payload"`
+	EncodedSignature string `json:"signature"`
+	SystemID         int    `json:"system_id"`
+	ProductName      string `json:"product_name"`
+	KeyID            string `json:"key_id,omitempty"`
+}
+
+// Marshal and encode the offline registration certificate into its base64
+// representation, as read by [OfflineCertificateFrom].
+func (cert *OfflineCertificate) Base64Encoded() (io.Reader, error) {
+	data, jsonErr := json.Marshal(signedCertificate{
+		Version:          cert.Version,
+		Cipher:           cert.Cipher,
+		Hash:             cert.Hash,
+		EncodedPayload:   cert.EncodedPayload,
+		EncodedSignature: cert.EncodedSignature,
+		SystemID:         cert.SystemID,
+		ProductName:      cert.ProductName,
+		KeyID:            cert.KeyID,
+	})
+
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+	blob := base64.StdEncoding.EncodeToString(data)
+
+	return strings.NewReader(blob), nil
+}
+
+// Generates credentials in the same format SCC uses for systems.
+func generateCredentials() (string, string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generate credentials: %s", err)
+	}
+	return "SCC_" + hex.EncodeToString(buf[:16]), hex.EncodeToString(buf[16:]), nil
+}
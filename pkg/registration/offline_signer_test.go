@@ -0,0 +1,132 @@
+package registration
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSigner(t *testing.T) *OfflineSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate key: %s", err)
+	}
+	signer, err := NewOfflineSigner(key)
+	if err != nil {
+		t.Fatalf("could not create signer: %s", err)
+	}
+	return signer
+}
+
+func testSubscription(t *testing.T) SubscriptionInfo {
+	info := SubscriptionInfo{}
+	if err := json.Unmarshal(fixture(t, "pkg/registration/subscription_info.json"), &info); err != nil {
+		t.Fatalf("could not parse subscription: %s", err)
+	}
+	return info
+}
+
+func TestOfflineSignerRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	signer := testSigner(t)
+	request := BuildOfflineRequest("rancher", "2.9.4", "x86_64", SystemInformation{"uuid": "some-uuid", "cpus": 4})
+	encodedRequest, err := request.Base64Encoded()
+	assert.NoError(err)
+	request, err = OfflineRequestFrom(encodedRequest, true)
+	assert.NoError(err)
+
+	cert, err := signer.Sign(request, "some-regcode", testSubscription(t))
+	assert.NoError(err)
+
+	// The issued certificate only carries the payload in its signed form.
+	blob, err := cert.Base64Encoded()
+	assert.NoError(err)
+	raw, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, blob))
+	assert.NoError(err)
+	assert.NotContains(string(raw), "hashed_regcode")
+
+	blob, err = cert.Base64Encoded()
+	assert.NoError(err)
+	cert, err = OfflineCertificateFrom(blob, true)
+	assert.NoError(err)
+	assert.Equal(signer.KeyID(), cert.KeyID)
+	assert.Equal("rancher", cert.ProductName)
+
+	valid, err := cert.IsValidWith(NewKeyRing(TrustedKey{Key: signer.PublicKey()}))
+	assert.NoError(err)
+	assert.True(valid)
+
+	// Only trusted signers are accepted.
+	_, err = cert.IsValid()
+	assert.ErrorIs(err, ErrUnknownKey)
+
+	matches, err := cert.RegcodeMatches("some-regcode")
+	assert.NoError(err)
+	assert.True(matches)
+	matches, err = cert.UUIDMatches("some-uuid")
+	assert.NoError(err)
+	assert.True(matches)
+
+	payload, err := cert.ExtractPayload()
+	assert.NoError(err)
+	assert.Regexp("^SCC_[0-9a-f]{32}$", payload.Login)
+	assert.Len(payload.Password, 16)
+	assert.Equal(float64(4), payload.Information["cpus"])
+	assert.NoError(cert.ValidateAt(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestOfflineSignerKeepsCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	request := BuildOfflineRequest("rancher", "2.9.4", "x86_64", SystemInformation{"uuid": "some-uuid"})
+	request.Login, request.Password = "login", "password"
+
+	cert, err := testSigner(t).Sign(request, "some-regcode", testSubscription(t))
+	assert.NoError(err)
+	assert.Equal("login", cert.Login)
+	assert.Equal("password", cert.Password)
+}
+
+func TestOfflineSignerMissingData(t *testing.T) {
+	assert := assert.New(t)
+	signer := testSigner(t)
+
+	request := BuildOfflineRequest("rancher", "2.9.4", "x86_64", NoSystemInformation)
+	_, err := signer.Sign(request, "some-regcode", testSubscription(t))
+	assert.ErrorContains(err, "uuid")
+
+	request = BuildOfflineRequest("rancher", "2.9.4", "x86_64", SystemInformation{"uuid": "some-uuid"})
+	_, err = signer.Sign(request, "", testSubscription(t))
+	assert.ErrorContains(err, "registration code")
+}
+
+func TestNewOfflineSignerFromPEM(t *testing.T) {
+	assert := assert.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	signer, err := NewOfflineSignerFromPEM(pkcs1)
+	assert.NoError(err)
+	expected, _ := KeyID(&key.PublicKey)
+	assert.Equal(expected, signer.KeyID())
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(err)
+	signer, err = NewOfflineSignerFromPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NoError(err)
+	assert.Equal(expected, signer.KeyID())
+
+	_, err = NewOfflineSignerFromPEM([]byte("not a key"))
+	assert.Error(err)
+}
@@ -1,7 +1,8 @@
 package registration
 
 import (
-	"github.com/SUSE/connect-ng/internal/util"
+	"log/slog"
+
 	"github.com/SUSE/connect-ng/pkg/connection"
 )
 
@@ -27,7 +28,10 @@ type statusRequest struct {
 }
 
 // Returns the registration status for the system pointed by the authorized
-// connection.
+// connection. Pass a nil systemInformation to keep the one stored on the
+// server, e.g. when it did not change since the last call. If the server
+// could not be reached (see `connection.IsUnreachable`), Unknown is returned
+// along with the error.
 func Status(conn connection.Connection, hostname string, systemInformation SystemInformation, profiles DataProfiles, extraData ExtraData) (StatusCode, error) {
 	payload := statusRequest{
 		Hostname: hostname,
@@ -52,11 +56,13 @@ func Status(conn connection.Connection, hostname string, systemInformation Syste
 
 	connection.AddSystemAuth(request, login, password)
 
-	util.Debug.Println("registration payload : ", payload)
-	util.Debug.Println("registration request : ", request)
+	// The request carries the credentials of the system, do not log it.
+	slog.Debug("Checking registration status", "method", request.Method, "url", request.URL)
 	_, doErr := conn.Do(request)
-	if doErr != nil {
-		util.Debug.Println("registration.Status doErr: ", doErr)
+	if connection.IsUnreachable(doErr) {
+		return Unknown, doErr
+	} else if doErr != nil {
+		slog.Debug("Registration status request failed", "error", doErr)
 		return Unregistered, nil
 	}
 
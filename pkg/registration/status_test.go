@@ -42,6 +42,19 @@ func TestStatusUnregistered(t *testing.T) {
 	assert.Equal(Unregistered, status)
 }
 
+func TestStatusUnreachable(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, _ := connection.NewMockConnectionWithCredentials()
+
+	// 503 Service Unavailable
+	conn.On("Do", mock.Anything).Return([]byte(""), &connection.ApiError{Code: 503})
+
+	status, err := Status(conn, hostname, NoSystemInformation, noProfileData, NoExtraData)
+	assert.Error(err)
+	assert.Equal(Unknown, status)
+}
+
 func TestStatusWithSystemInformation(t *testing.T) {
 	assert := assert.New(t)
 
@@ -60,6 +73,19 @@ func TestStatusWithSystemInformation(t *testing.T) {
 	assert.Equal(Registered, status)
 }
 
+func TestStatusWithoutSystemInformation(t *testing.T) {
+	assert := assert.New(t)
+
+	conn, _ := connection.NewMockConnectionWithCredentials()
+
+	// 204 No Content
+	conn.On("Do", mock.AnythingOfType("*http.Request")).Return([]byte(""), nil).Run(matchBody(t, `{"hostname":"test-hostname"}`))
+
+	status, err := Status(conn, hostname, nil, noProfileData, NoExtraData)
+	assert.NoError(err)
+	assert.Equal(Registered, status)
+}
+
 func TestStatusWithExtraData(t *testing.T) {
 	assert := assert.New(t)
 
@@ -35,7 +35,7 @@ var NoExtraData = map[string]any{}
 type DataProfiles = map[string]any
 
 type requestWithInformation struct {
-	SystemInformation any          `json:"hwinfo"`
+	SystemInformation any          `json:"hwinfo,omitempty"`
 	InstanceData      string       `json:"instance_data,omitempty"`
 	Namespace         string       `json:"namespace,omitempty"`
 	OnlineAt          []string     `json:"online_at,omitempty"`
@@ -43,7 +43,10 @@ type requestWithInformation struct {
 }
 
 func enrichWithSystemInformation(payload *requestWithInformation, info SystemInformation) {
-	payload.SystemInformation = info
+	// A nil map leaves the information known by the server untouched.
+	if info != nil {
+		payload.SystemInformation = info
+	}
 }
 
 func enrichWithExtraData(payload *requestWithInformation, extraData ExtraData) error {
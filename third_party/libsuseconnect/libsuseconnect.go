@@ -6,13 +6,17 @@ package main
 import "C"
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net"
 	"net/url"
+	"os"
 	"path/filepath"
 	"slices"
 	"strconv"
@@ -48,15 +52,71 @@ func (w callbackWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-// function pointer to log callback passed from ruby
-var logFun C.logLineFunc
+// callbackHandler forwards the log records to the log callback with the
+// matching log level, as key=value pairs.
+type callbackHandler map[int]slog.Handler
+
+func newCallbackHandler() callbackHandler {
+	h := callbackHandler{}
+	for _, level := range []int{llDebug, llInfo, llWarning, llError} {
+		h[level] = slog.NewTextHandler(callbackWriter{level}, &slog.HandlerOptions{Level: logLevel, ReplaceAttr: util.RedactAttr})
+	}
+	return h
+}
+
+func (h callbackHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= logLevel.Level()
+}
+
+func (h callbackHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := llDebug
+	switch {
+	case r.Level >= slog.LevelError:
+		level = llError
+	case r.Level >= slog.LevelWarn:
+		level = llWarning
+	case r.Level >= slog.LevelInfo:
+		level = llInfo
+	}
+	return h[level].Handle(ctx, r)
+}
+
+func (h callbackHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := callbackHandler{}
+	for level, handler := range h {
+		clone[level] = handler.WithAttrs(attrs)
+	}
+	return clone
+}
+
+func (h callbackHandler) WithGroup(name string) slog.Handler {
+	clone := callbackHandler{}
+	for level, handler := range h {
+		clone[level] = handler.WithGroup(name)
+	}
+	return clone
+}
+
+var (
+	// function pointer to log callback passed from ruby
+	logFun C.logLineFunc
+
+	// Only warnings and errors are logged unless "debug" is set in the
+	// client parameters.
+	logLevel = new(slog.LevelVar)
+)
+
+func init() {
+	// Until a log callback is set, log into the standard error.
+	logLevel.Set(slog.LevelWarn)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel, ReplaceAttr: util.RedactAttr})))
+}
 
 //export set_log_callback
 func set_log_callback(logCallback C.logLineFunc) {
 	logFun = logCallback
-	// NOTE: Debug is not redirected here as it is disabled by default
 	util.Info.SetOutput(callbackWriter{llInfo})
-	// TODO: add other levels?
+	slog.SetDefault(slog.New(newCallbackHandler()))
 }
 
 //export free_string
@@ -105,7 +165,7 @@ func deactivate_system(clientParams *C.char) *C.char {
 	opts := loadConfig(C.GoString(clientParams))
 	api := connect.NewWrappedAPI(opts)
 
-	err := connect.Deregister(api, opts)
+	_, err := connect.Deregister(api, opts)
 	if err != nil {
 		return C.CString(errorToJSON(err))
 	}
@@ -264,7 +324,7 @@ func loadConfig(clientParams string) *connect.Options {
 	json.Unmarshal([]byte(clientParams), &extConfig)
 	// enable debug output if "debug" was set in json
 	if v, _ := strconv.ParseBool(extConfig.Debug); v {
-		util.Debug.SetOutput(callbackWriter{llDebug})
+		logLevel.Set(slog.LevelDebug)
 	}
 
 	// Read the options from the default configuration path and merge the
@@ -346,7 +406,7 @@ func errorToJSON(err error) string {
 			s.ErrType = "NetError"
 			s.Message = ierr.Error()
 		} else {
-			util.Debug.Printf("url.Error: %T: %v", ierr, err)
+			slog.Debug("Unknown url.Error", "type", fmt.Sprintf("%T", ierr), "error", err)
 			s.Message = err.Error()
 		}
 	} else if je, ok := err.(connect.JSONError); ok {
@@ -359,7 +419,7 @@ func errorToJSON(err error) string {
 		case cred.ErrMissingCredentialsFile:
 			s.ErrType = "MissingCredentialsFile"
 		}
-		util.Debug.Printf("Error: %T: %v", err, err)
+		slog.Debug("Unknown error", "type", fmt.Sprintf("%T", err), "error", err)
 		s.Message = err.Error()
 	}
 